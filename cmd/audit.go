@@ -0,0 +1,214 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model"
+)
+
+type AppAudit struct {
+	*App
+}
+
+func (a *App) AppAudit() Cmder {
+	return &AppAudit{App: a}
+}
+
+func (a *AppAudit) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "audit operation",
+		Long:  "Options for querying the operation audit trail of the commands that change the cluster state, e.g. kill, runaway create / delete and sql bind create / delete",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	return cmd
+}
+
+type AppAuditList struct {
+	*AppAudit
+	command   string
+	startTime string
+	endTime   string
+	limit     int
+}
+
+func (a *AppAudit) AppAuditList() Cmder {
+	return &AppAuditList{AppAudit: a}
+}
+
+func (a *AppAuditList) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the operation audit records",
+		Long:  "List the operation audit records in descending order of the start time, filtered by the cluster name -c, the command and the start time range",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			audits, err := model.ListAudit(context.Background(), a.clusterName, a.command, a.startTime, a.endTime, a.limit)
+			if err != nil {
+				return err
+			}
+			if len(audits) == 0 {
+				a.output.Notice("the operation audit records not found, please ignore and skip")
+				return nil
+			}
+			columns := []string{"id", "os_user", "cluster_name", "command", "status", "start_time", "elapsed", "statements", "objects", "error"}
+			var rows [][]interface{}
+			for _, r := range audits {
+				rows = append(rows, []interface{}{
+					r.ID,
+					r.OsUser,
+					r.ClusterName,
+					r.Command,
+					r.Status,
+					r.StartTime.Format("2006-01-02 15:04:05"),
+					auditElapsed(r),
+					auditLines(r.Statements),
+					auditLines(r.Objects),
+					r.Error,
+				})
+			}
+			if err := a.output.Table("operation audit content", columns, rows); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().StringVar(&a.command, "command", "", "filter the audit records by the command, the command matches its subcommands, e.g. kill matches kill sql and kill user")
+	cmd.Flags().StringVar(&a.startTime, "start", "", "filter the audit records started after the time, format: 2006-01-02 15:04:05")
+	cmd.Flags().StringVar(&a.endTime, "end", "", "filter the audit records started before the time, format: 2006-01-02 15:04:05")
+	cmd.Flags().IntVar(&a.limit, "limit", 50, "the maximum number of the audit records, 0 means unlimited")
+	return cmd
+}
+
+type AppAuditShow struct {
+	*AppAudit
+	id uint64
+}
+
+func (a *AppAudit) AppAuditShow() Cmder {
+	return &AppAuditShow{AppAudit: a}
+}
+
+func (a *AppAuditShow) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "show the operation audit record detail",
+		Long:  "Show the operation audit record detail, including the command line, the statements issued and the objects affected",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.id == 0 {
+				return fmt.Errorf(`the audit id cannot be empty, required flag(s) --id {auditID} not set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := model.GetAudit(context.Background(), a.id)
+			if err != nil {
+				return err
+			}
+			var endTime string
+			if r.EndTime != nil {
+				endTime = r.EndTime.Format("2006-01-02 15:04:05")
+			}
+			columns := []string{"name", "value"}
+			rows := [][]interface{}{
+				{"id", r.ID},
+				{"os_user", r.OsUser},
+				{"cluster_name", r.ClusterName},
+				{"command", r.Command},
+				{"command_line", r.CommandLine},
+				{"status", r.Status},
+				{"start_time", r.StartTime.Format("2006-01-02 15:04:05")},
+				{"end_time", endTime},
+				{"elapsed", auditElapsed(r)},
+				{"error", r.Error},
+			}
+			if err := a.output.Table("operation audit content", columns, rows); err != nil {
+				return err
+			}
+			a.output.Value("operation audit statements", auditValues(r.Statements))
+			a.output.Value("operation audit affected objects", auditValues(r.Objects))
+			a.output.Printf("operation audit statements:\n%s\n\n", r.Statements)
+			a.output.Printf("operation audit affected objects:\n%s\n\n", r.Objects)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().Uint64Var(&a.id, "id", 0, "the audit record id, see by [audit list]")
+	return cmd
+}
+
+// runAudited records the execution of the command that changes the cluster state in the operation audit trail
+func runAudited(cmd *cobra.Command, args []string, clusterName string, fn func() error) error {
+	ctx := context.Background()
+	commandLine := strings.Join(append([]string{cmd.Root().Name()}, commandArgs(cmd, args)...), " ")
+	auditor, err := model.AuditStart(ctx, clusterName, strings.Join(strings.Fields(cmd.CommandPath())[1:], " "), commandLine)
+	if err != nil {
+		return err
+	}
+	return finishAudited(ctx, auditor, fn())
+}
+
+// finishAudited records the outcome of the audited execution, the failure of the audit record is appended to the returned error
+func finishAudited(ctx context.Context, auditor *model.Auditor, err error) error {
+	if ferr := auditor.Finish(ctx, err); ferr != nil {
+		if err != nil {
+			return fmt.Errorf("%v, and record the audit failed: %v", err, ferr)
+		}
+		return fmt.Errorf("the command executed successfully, but record the audit failed: %v", ferr)
+	}
+	return err
+}
+
+func auditElapsed(r *sqlite.Audit) string {
+	if r.EndTime == nil {
+		return ""
+	}
+	return r.EndTime.Sub(r.StartTime).Round(time.Millisecond).String()
+}
+
+// auditValues returns the newline separated statements or objects
+func auditValues(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// auditLines returns the number of the newline separated statements or objects
+func auditLines(s string) int {
+	return len(auditValues(s))
+}
@@ -0,0 +1,341 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wentaojin/tidba/model"
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+// clientCommand is the setting command of the interactive command line starting with the backslash, e.g. \timeout 30s,
+// it is handled by the command line itself instead of the cobra command and the sql lexer
+type clientCommand struct {
+	usage string
+	// raw passes the text after the command name as the only argument, the command parses the quoted values itself
+	raw bool
+	run func(l *CommandLine, args []string) error
+}
+
+var clientCommands = map[string]*clientCommand{
+	`\timeout`: {
+		usage: `\timeout [{duration} | off], show or set the timeout of the sql statement, e.g. \timeout 30s, the number without unit means seconds`,
+		run:   (*CommandLine).runTimeoutCommand,
+	},
+	`\o`: {
+		usage: `\o [{file}], write the query results to the file, the file is truncated, \o without the file restores the terminal output`,
+		run:   (*CommandLine).runOutputCommand,
+	},
+	`\format`: {
+		usage: `\format [table | vertical | csv | tsv | json | markdown], show or set the query result format, the statement terminated by \G is always vertical`,
+		run:   (*CommandLine).runFormatCommand,
+	},
+	`\pager`: {
+		usage: `\pager [{command} | off], show or set the pager the query results are piped through, e.g. \pager less -S`,
+		run:   (*CommandLine).runPagerCommand,
+	},
+	`\watch`: {
+		usage: `\watch {seconds} [--log {file}] {statement | command}, execute the read sql statement or the tidba command on the interval and refresh in place, the changed cells are highlighted, Ctrl+C to stop`,
+		run:   (*CommandLine).runWatchCommand,
+	},
+	`\width`: {
+		usage: `\width [{width} | off], show or set the maximum column width of the table, vertical and markdown format, the value is wrapped in the table format`,
+		run:   (*CommandLine).runWidthCommand,
+	},
+	`\history`: {
+		usage: `\history [--cluster {clusterName} | --all] [--grep {text}] [--limit {n}], show the history of the logged in cluster, the latest 20 records by default`,
+		run:   (*CommandLine).runHistoryCommand,
+	},
+	`\rerun`: {
+		usage: `\rerun {id}, execute the sql statement or the tidba command of the history again, the id is shown by \history`,
+		run:   (*CommandLine).runRerunCommand,
+	},
+	`\run`: {
+		usage: `\run {snippetName} [{key}={value} ...], execute the sql statements of the snippet with the params, the snippets are shown by [snippet list]`,
+		raw:   true,
+		run:   (*CommandLine).runSnippetCommand,
+	},
+	`\truncate`: {
+		usage: `\truncate [on | off], show or set whether the value exceeding the maximum column width is truncated instead of wrapped`,
+		run:   (*CommandLine).runTruncateCommand,
+	},
+}
+
+// resultSettings is the output settings of the query results changed by the client commands, the settings are kept
+// until the interactive command line exits
+type resultSettings struct {
+	format   string
+	maxWidth int
+	truncate bool
+	pager    string
+	file     *os.File
+}
+
+// isClientCommand returns whether the input line is the client command, the \G line terminates the buffered statement
+func isClientCommand(line string) bool {
+	return strings.HasPrefix(line, `\`) && !strings.HasPrefix(line, VerticalSQLDelimiter)
+}
+
+// clientCommandNames returns the sorted client command names
+func clientCommandNames() []string {
+	var names []string
+	for name := range clientCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l *CommandLine) runClientCommand(line string) error {
+	fields := strings.Fields(line)
+	c, ok := clientCommands[fields[0]]
+	if !ok {
+		var usages []string
+		for _, name := range clientCommandNames() {
+			usages = append(usages, clientCommands[name].usage)
+		}
+		return fmt.Errorf("unknown command [%s], options:\n%s", fields[0], strings.Join(usages, "\n"))
+	}
+	if c.raw {
+		if text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0])); text != "" {
+			return c.run(l, []string{text})
+		}
+		return c.run(l, nil)
+	}
+	return c.run(l, fields[1:])
+}
+
+func (l *CommandLine) runTimeoutCommand(args []string) error {
+	if len(args) == 0 {
+		if timeout := l.getQueryTimeout(); timeout > 0 {
+			fmt.Printf("the sql statement timeout: %v\n\n", timeout)
+		} else {
+			fmt.Printf("the sql statement timeout: off\n\n")
+		}
+		return nil
+	}
+
+	var timeout time.Duration
+	switch v := args[0]; {
+	case strings.EqualFold(v, "off") || v == "0":
+	default:
+		if secs, err := strconv.ParseUint(v, 10, 64); err == nil {
+			timeout = time.Duration(secs) * time.Second
+			break
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid timeout [%s], the format is the duration e.g. 30s / 5m, the number of seconds or off", v)
+		}
+		timeout = d
+	}
+
+	l.mutex.Lock()
+	l.queryTimeout = timeout
+	l.mutex.Unlock()
+	if timeout > 0 {
+		fmt.Printf("✅ the sql statement timeout is set to %v\n\n", timeout)
+	} else {
+		fmt.Printf("✅ the sql statement timeout is off\n\n")
+	}
+	return nil
+}
+
+func (l *CommandLine) runOutputCommand(args []string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.result.file != nil {
+		if err := l.result.file.Close(); err != nil {
+			return fmt.Errorf("close the output file [%s] failed: %v", l.result.file.Name(), err)
+		}
+		fmt.Printf("✅ the query results of the output file [%s] are closed\n\n", l.result.file.Name())
+		l.result.file = nil
+	}
+	if len(args) == 0 {
+		fmt.Printf("✅ the query results are written to the terminal\n\n")
+		return nil
+	}
+	f, err := os.Create(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("create the output file failed: %v", err)
+	}
+	l.result.file = f
+	fmt.Printf("✅ the query results are written to the file [%s]\n\n", f.Name())
+	return nil
+}
+
+func (l *CommandLine) runFormatCommand(args []string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(args) == 0 {
+		fmt.Printf("the query result format: %s\n\n", l.resultFormat())
+		return nil
+	}
+	format := strings.ToLower(args[0])
+	if !stringutil.IsContainString(format, model.QueryResultFormats) {
+		return fmt.Errorf("invalid format [%s], options: %s", args[0], strings.Join(model.QueryResultFormats, " / "))
+	}
+	l.result.format = format
+	fmt.Printf("✅ the query result format is set to %s\n\n", format)
+	return nil
+}
+
+func (l *CommandLine) runPagerCommand(args []string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	switch {
+	case len(args) == 0:
+		if l.result.pager == "" {
+			fmt.Printf("the pager: off\n\n")
+		} else {
+			fmt.Printf("the pager: %s\n\n", l.result.pager)
+		}
+	case len(args) == 1 && strings.EqualFold(args[0], "off"):
+		l.result.pager = ""
+		fmt.Printf("✅ the pager is off\n\n")
+	default:
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return fmt.Errorf("the pager command [%s] not found: %v", args[0], err)
+		}
+		l.result.pager = strings.Join(args, " ")
+		fmt.Printf("✅ the pager is set to %s\n\n", l.result.pager)
+	}
+	return nil
+}
+
+func (l *CommandLine) runWidthCommand(args []string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(args) == 0 {
+		if l.result.maxWidth > 0 {
+			fmt.Printf("the maximum column width: %d\n\n", l.result.maxWidth)
+		} else {
+			fmt.Printf("the maximum column width: off\n\n")
+		}
+		return nil
+	}
+	width := 0
+	if !strings.EqualFold(args[0], "off") {
+		w, err := strconv.Atoi(args[0])
+		if err != nil || w < 0 {
+			return fmt.Errorf("invalid width [%s], the width is the positive number or off", args[0])
+		}
+		width = w
+	}
+	l.result.maxWidth = width
+	if width > 0 {
+		fmt.Printf("✅ the maximum column width is set to %d\n\n", width)
+	} else {
+		fmt.Printf("✅ the maximum column width is off\n\n")
+	}
+	return nil
+}
+
+func (l *CommandLine) runTruncateCommand(args []string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			l.result.truncate = true
+		case "off":
+			l.result.truncate = false
+		default:
+			return fmt.Errorf("invalid value [%s], options: on / off", args[0])
+		}
+	}
+	state := "off"
+	if l.result.truncate {
+		state = "on"
+	}
+	if l.result.maxWidth == 0 {
+		fmt.Printf("the truncate: %s, it takes effect after the maximum column width is set by \\width\n\n", state)
+	} else {
+		fmt.Printf("the truncate: %s\n\n", state)
+	}
+	return nil
+}
+
+// resultFormat returns the query result format, it is called with the mutex held
+func (l *CommandLine) resultFormat() string {
+	if l.result.format == "" {
+		return model.QueryResultFormatTable
+	}
+	return l.result.format
+}
+
+// writeResult renders the query result with the result settings, and writes it to the output file, the pager or the terminal
+func (l *CommandLine) writeResult(columns []string, results []map[string]string, delimiter string, elapsed float64) error {
+	l.mutex.RLock()
+	opts := &model.QueryResultOptions{
+		Format:   l.resultFormat(),
+		MaxWidth: l.result.maxWidth,
+		Truncate: l.result.truncate,
+		Elapsed:  elapsed,
+	}
+	file, pager := l.result.file, l.result.pager
+	l.mutex.RUnlock()
+	if delimiter == VerticalSQLDelimiter {
+		opts.Format = model.QueryResultFormatVertical
+	}
+
+	var buf bytes.Buffer
+	if err := model.WriteQueryResult(&buf, columns, results, opts); err != nil {
+		return err
+	}
+	switch {
+	case file != nil:
+		if _, err := file.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("write the output file [%s] failed: %v", file.Name(), err)
+		}
+		fmt.Printf("%d rows written to the file [%s] (%.2f sec)\n\n", len(results), file.Name(), elapsed)
+	case pager != "":
+		if err := runPager(pager, &buf); err != nil {
+			return fmt.Errorf("run the pager [%s] failed: %v", pager, err)
+		}
+	default:
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	// the exported data formats have no caption, the summary is shown on the terminal
+	if file == nil && opts.Format != model.QueryResultFormatTable && opts.Format != model.QueryResultFormatVertical {
+		fmt.Printf("%d rows in set (%.2f sec)\n\n", len(results), elapsed)
+	}
+	return nil
+}
+
+// runPager pipes the output through the pager command, Ctrl+C is handled by the pager instead of exiting the command line
+func runPager(pager string, output *bytes.Buffer) error {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	c := exec.Command("sh", "-c", pager)
+	c.Stdin = output
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
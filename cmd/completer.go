@@ -0,0 +1,333 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/mysql"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+// completionQueryTimeout bounds the name query of the tab completion, the terminal is blocked while completing
+const completionQueryTimeout = 3 * time.Second
+
+// Completer implements the readline auto completer, the command names and flags are completed from the cobra command tree,
+// the cluster names are completed from the metadata, and the database, table and index names are queried lazily
+// from the logged in cluster and cached until the cluster is logged in again or the database is changed by USE
+type Completer struct {
+	cli     *CommandLine
+	rootCmd *cobra.Command
+
+	mutex     sync.Mutex
+	cluster   string
+	databases []string
+	tables    map[string][]string // key: database
+	indexes   map[string][]string // key: database.table
+}
+
+func NewCompleter(cli *CommandLine, rootCmd *cobra.Command) *Completer {
+	return &Completer{cli: cli, rootCmd: rootCmd}
+}
+
+// Invalidate clears the cached names, the names are queried again on the next completion
+func (c *Completer) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cluster = ""
+	c.databases = nil
+	c.tables = nil
+	c.indexes = nil
+}
+
+// Do returns the candidate suffixes of the word before the cursor and the length of the word, see readline.AutoCompleter
+func (c *Completer) Do(line []rune, pos int) ([][]rune, int) {
+	fields := strings.Fields(string(line[:pos]))
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(string(line[:pos]), " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	var candidates []string
+	if len(fields) > 0 && isQueryInput(fields[0], c.rootCmd) {
+		candidates, word = c.completeQuery(fields, word)
+	} else {
+		candidates, word = c.completeCommand(fields, word)
+	}
+
+	var suffixes [][]rune
+	for _, name := range candidates {
+		if strings.HasPrefix(name, word) {
+			suffixes = append(suffixes, []rune(name[len(word):]+" "))
+		}
+	}
+	return suffixes, len([]rune(word))
+}
+
+// completeCommand completes the tidba command, the returned word is the part of the current word to be completed
+func (c *Completer) completeCommand(fields []string, word string) ([]string, string) {
+	if len(fields) == 0 {
+		var names []string
+		for _, sub := range c.rootCmd.Commands() {
+			names = append(names, sub.Name())
+		}
+		// the sql keyword is completed in the case of the entered word
+		for _, q := range sqlStatementKeywords() {
+			if word != "" && word == strings.ToLower(word) {
+				q = strings.ToLower(q)
+			}
+			names = append(names, q)
+		}
+		return append(names, clientCommandNames()...), word
+	}
+
+	cmd := c.rootCmd
+	for _, f := range fields {
+		if strings.HasPrefix(f, "-") {
+			continue
+		}
+		for _, sub := range cmd.Commands() {
+			if sub.Name() == f {
+				cmd = sub
+				break
+			}
+		}
+	}
+
+	// the flag value, in the form of --flag value or --flag=value
+	flagName, prefix := "", ""
+	if strings.HasPrefix(word, "-") && strings.Contains(word, "=") {
+		idx := strings.Index(word, "=")
+		flagName, prefix, word = word[:idx], word[:idx+1], word[idx+1:]
+	} else if last := fields[len(fields)-1]; strings.HasPrefix(last, "-") && !strings.Contains(last, "=") {
+		if f := lookupFlag(cmd, last); f != nil && f.Value.Type() != "bool" {
+			flagName = last
+		}
+	}
+	if flagName != "" {
+		// the slice flag value is separated by comma, only the last element is completed
+		if idx := strings.LastIndex(word, ","); idx >= 0 {
+			prefix, word = prefix+word[:idx+1], word[idx+1:]
+		}
+		var names []string
+		for _, n := range c.completeFlagValue(cmd, flagName, fields) {
+			names = append(names, prefix+n)
+		}
+		return names, prefix + word
+	}
+
+	var names []string
+	if strings.HasPrefix(word, "-") {
+		// the inherited flags are merged into the command flags after the first lookup, avoid the duplicated names
+		visit := func(f *pflag.Flag) {
+			if !f.Hidden && !stringutil.IsContainString("--"+f.Name, names) {
+				names = append(names, "--"+f.Name)
+			}
+		}
+		cmd.Flags().VisitAll(visit)
+		cmd.PersistentFlags().VisitAll(visit)
+		cmd.InheritedFlags().VisitAll(visit)
+		return names, word
+	}
+	for _, sub := range cmd.Commands() {
+		names = append(names, sub.Name())
+	}
+	return names, word
+}
+
+func (c *Completer) completeFlagValue(cmd *cobra.Command, flagName string, fields []string) []string {
+	f := lookupFlag(cmd, flagName)
+	if f == nil {
+		return nil
+	}
+	switch f.Name {
+	case "cluster", "clusters":
+		return c.clusterNames()
+	case "database":
+		return c.databaseNames()
+	case "tables":
+		return c.tableNames(c.flagValue(fields, "database"))
+	case "indexes", "index":
+		return c.indexNames(c.flagValue(fields, "database"), strings.Split(c.flagValue(fields, "tables"), ","))
+	default:
+		return nil
+	}
+}
+
+// completeQuery completes the sql statement, the database names after USE and the table names after FROM / JOIN,
+// the table name qualified by the database name {database}.{table} is completed with the tables of the database
+func (c *Completer) completeQuery(fields []string, word string) ([]string, string) {
+	switch strings.ToUpper(fields[len(fields)-1]) {
+	case "USE":
+		return c.databaseNames(), word
+	case "FROM", "JOIN":
+		if idx := strings.Index(word, "."); idx >= 0 {
+			var names []string
+			for _, t := range c.tableNames(word[:idx]) {
+				names = append(names, word[:idx+1]+t)
+			}
+			return names, word
+		}
+		return append(c.tableNames(""), c.databaseNames()...), word
+	default:
+		return nil, word
+	}
+}
+
+// flagValue returns the value of the flag entered before the current word, used to narrow the completed names
+func (c *Completer) flagValue(fields []string, name string) string {
+	for i, f := range fields {
+		if f == "--"+name && i+1 < len(fields) {
+			return fields[i+1]
+		}
+		if strings.HasPrefix(f, "--"+name+"=") {
+			return strings.TrimPrefix(f, "--"+name+"=")
+		}
+	}
+	return ""
+}
+
+func (c *Completer) clusterNames() []string {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil
+	}
+	clusters, err := db.(*sqlite.Database).ListCluster(context.Background(), 0, 0)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, cl := range clusters {
+		names = append(names, cl.ClusterName)
+	}
+	return names
+}
+
+func (c *Completer) databaseNames() []string {
+	conn := c.clusterConn()
+	if conn == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.databases == nil {
+		c.databases = queryCompletionNames(conn, `SHOW DATABASES`)
+	}
+	return c.databases
+}
+
+// tableNames returns the table names of the database, the active database of USE is used when the database is empty
+func (c *Completer) tableNames(dbName string) []string {
+	if dbName == "" {
+		dbName = c.cli.GetDatabaseName()
+	}
+	conn := c.clusterConn()
+	if conn == nil || dbName == "" {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.tables[dbName]; !ok {
+		c.tables[dbName] = queryCompletionNames(conn, `SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?`, dbName)
+	}
+	return c.tables[dbName]
+}
+
+func (c *Completer) indexNames(dbName string, tables []string) []string {
+	if dbName == "" {
+		dbName = c.cli.GetDatabaseName()
+	}
+	conn := c.clusterConn()
+	if conn == nil || dbName == "" {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var names []string
+	for _, t := range tables {
+		if t == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s.%s", dbName, t)
+		if _, ok := c.indexes[key]; !ok {
+			c.indexes[key] = queryCompletionNames(conn, `SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, dbName, t)
+		}
+		names = append(names, c.indexes[key]...)
+	}
+	return names
+}
+
+// clusterConn returns the connection of the logged in cluster, the cache is reset when the logged in cluster is changed
+func (c *Completer) clusterConn() *mysql.Database {
+	clusterName := c.cli.GetClusterName()
+	if clusterName == "" {
+		return nil
+	}
+	conn := c.cli.getActiveClusterConn()
+	if conn == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.cluster != clusterName {
+		c.cluster = clusterName
+		c.databases = nil
+		c.tables = make(map[string][]string)
+		c.indexes = make(map[string][]string)
+	}
+	return conn
+}
+
+func queryCompletionNames(conn *mysql.Database, query string, args ...any) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), completionQueryTimeout)
+	defer cancel()
+	cols, res, err := conn.GeneralQuery(ctx, query, args...)
+	if err != nil || len(cols) == 0 {
+		// the failed query is cached as empty, avoid blocking the terminal on every completion
+		return []string{}
+	}
+	names := []string{}
+	for _, r := range res {
+		names = append(names, r[cols[0]])
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupFlag(cmd *cobra.Command, name string) *pflag.Flag {
+	for _, fs := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags(), cmd.InheritedFlags()} {
+		var f *pflag.Flag
+		if strings.HasPrefix(name, "--") {
+			f = fs.Lookup(strings.TrimPrefix(name, "--"))
+		} else if len(name) == 2 {
+			f = fs.ShorthandLookup(name[1:])
+		}
+		if f != nil {
+			return f
+		}
+	}
+	return nil
+}
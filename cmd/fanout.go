@@ -0,0 +1,230 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model"
+	"github.com/wentaojin/tidba/utils/secret"
+	"golang.org/x/sync/errgroup"
+)
+
+// fanoutUnsupportedCommands depend on the interactive terminal or only operate the metadata, cannot be executed on multiple clusters
+var fanoutUnsupportedCommands = []string{"meta", "audit", "snippet", "license", "login", "logout", "clear", "source", "inspect create", "inspect update", "inspect history", "inspect diff"}
+
+// clusterSelectCommands operate the metadata of the clusters selected by the flag --clusters instead of the fan-out execution
+var clusterSelectCommands = []string{"meta export", "meta import", "meta sync"}
+
+// fanoutFlags are the cluster selector flags
+var fanoutFlags = []string{"cluster", "endpoint", "group", "clusters", "fanout-concurrency"}
+
+// outputFlags are the root persistent flags of the command results output, the results of the cluster execution are collected
+// by the output of the caller
+var outputFlags = []string{"output-format", "output-file"}
+
+type fanoutResult struct {
+	clusterName string
+	output      *model.Output
+	elapsed     time.Duration
+	err         error
+}
+
+// headlessProgram disables the terminal ui of the command, it is set when the output format is not the table, the stdout is not
+// the terminal, the command is executed by the script or the command is executed on multiple clusters concurrently
+var headlessProgram bool
+
+// newProgram creates the terminal ui program, the headless command has no terminal, so the ui input and rendering are disabled
+// and only the final result of the model is collected, the models submit the data when they start
+func newProgram(m tea.Model, opts ...tea.ProgramOption) *tea.Program {
+	if !headlessProgram {
+		return tea.NewProgram(m, opts...)
+	}
+	return tea.NewProgram(m, append(opts, tea.WithInput(nil), tea.WithOutput(io.Discard))...)
+}
+
+// prepareFanout replaces the subcommand execution with the fan-out execution on the selected clusters
+func (a *App) prepareFanout(cmd *cobra.Command) error {
+	path := strings.Join(strings.Fields(cmd.CommandPath())[1:], " ")
+	if path == "" {
+		return fmt.Errorf("the flag --group or --clusters requires a subcommand, for example: [tidba --group {groupName} topsql elapsed]")
+	}
+	for _, c := range fanoutUnsupportedCommands {
+		if path == c || strings.HasPrefix(path, c+" ") {
+			return fmt.Errorf("the command [%s] does not support the flag --group or --clusters", path)
+		}
+	}
+	if a.fanoutConcurrency <= 0 {
+		return fmt.Errorf("the flag --fanout-concurrency [%d] must be greater than 0", a.fanoutConcurrency)
+	}
+	cmd.PreRun = nil
+	cmd.PreRunE = nil
+	cmd.Run = nil
+	cmd.RunE = a.runFanout
+	return nil
+}
+
+// isClusterSelectCommand returns whether the command takes the clusters selected by the flag --clusters as its own argument
+func isClusterSelectCommand(cmd *cobra.Command) bool {
+	path := strings.Join(strings.Fields(cmd.CommandPath())[1:], " ")
+	for _, c := range clusterSelectCommands {
+		if path == c {
+			return true
+		}
+	}
+	return false
+}
+
+// runFanout executes the subcommand concurrently on each selected cluster in the current process, the failed cluster does not abort the others
+func (a *App) runFanout(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	clusters, err := model.MetadataSelectClusters(ctx, a.group, a.clusters)
+	if err != nil {
+		return err
+	}
+	if err := prepareClustersMasterKey(clusters); err != nil {
+		return err
+	}
+
+	// the cluster selector flags and the output flags are not passed to the cluster execution, the results of each cluster
+	// are collected by the forked output and merged by the current output
+	argv := commandArgs(cmd, args, append(append([]string{}, fanoutFlags...), outputFlags...)...)
+	headlessProgram = true
+
+	var (
+		mutex   sync.Mutex
+		results = make([]*fanoutResult, len(clusters))
+	)
+	g := &errgroup.Group{}
+	g.SetLimit(a.fanoutConcurrency)
+	for i, c := range clusters {
+		i, clusterName := i, c.ClusterName
+		g.Go(func() error {
+			startTime := time.Now()
+			r := &fanoutResult{
+				clusterName: clusterName,
+				output:      a.output.Fork(clusterName),
+			}
+			r.err = runClusterCommand(ctx, clusterName, argv, r.output)
+			r.elapsed = time.Since(startTime)
+			results[i] = r
+
+			if !a.output.IsTable() {
+				return nil
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			text := r.output.Buffered()
+			if r.err != nil {
+				text = strings.TrimSpace(fmt.Sprintf("%s\n\nError: %v", text, r.err))
+			}
+			a.output.Printf("==================== cluster [%s] ====================\n%s\n\n", r.clusterName, text)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var (
+		failed []string
+		rows   [][]interface{}
+	)
+	for _, r := range results {
+		if r.err == nil {
+			a.output.Value(fmt.Sprintf("cluster [%s] content", r.clusterName), r.output.Document())
+			rows = append(rows, []interface{}{r.clusterName, "success", r.elapsed.Round(time.Millisecond).String(), ""})
+			continue
+		}
+		failed = append(failed, r.clusterName)
+		rows = append(rows, []interface{}{r.clusterName, "failed", r.elapsed.Round(time.Millisecond).String(), r.err.Error()})
+	}
+	if err := a.output.Table("cluster fan-out execution content", []string{"cluster_name", "status", "elapsed", "error"}, rows); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		// the post run hook is skipped when the command fails, the results of the succeeded clusters are still written
+		if err := a.output.Flush(); err != nil {
+			return err
+		}
+		return fmt.Errorf("the command failed on the clusters [%s], succeeded [%d] / total [%d]", strings.Join(failed, ","), len(results)-len(failed), len(results))
+	}
+	return nil
+}
+
+// runClusterCommand executes the subcommand on the cluster in the current process. The command tree is rebuilt with a new
+// application so that the flags of the concurrent executions are not shared, and the root pre-run hook is replaced since the
+// metadata database, the license and the master key are already prepared by the caller
+func runClusterCommand(ctx context.Context, clusterName string, argv []string, output *model.Output) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the command panic: %v", r)
+		}
+	}()
+	app := &App{}
+	root := Cmd(app)
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		app.clusterName = clusterName
+		app.output = output
+		return nil
+	}
+	root.PersistentPostRunE = nil
+	root.SetArgs(argv)
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	_, err = root.ExecuteContextC(ctx)
+	return err
+}
+
+// prepareClustersMasterKey resolves the master key once before the clusters are executed concurrently if any cluster password
+// is encrypted, the concurrent executions cannot prompt the passphrase at the same time
+func prepareClustersMasterKey(clusters []*sqlite.Cluster) error {
+	for _, c := range clusters {
+		if c.DbPasswordProvider == "" && secret.IsEncrypted(c.DbPassword) {
+			_, err := secret.DefaultKeyring.MasterKey()
+			return err
+		}
+	}
+	return nil
+}
+
+// commandArgs rebuilds the subcommand arguments from the command path and the changed flags except the excluded root persistent
+// flags, the subcommand flag with the same name as the excluded flag is kept
+func commandArgs(cmd *cobra.Command, args []string, excludes ...string) []string {
+	argv := strings.Fields(cmd.CommandPath())[1:]
+	persistent := cmd.Root().PersistentFlags()
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		for _, name := range excludes {
+			if f.Name == name && persistent.Lookup(name) == f {
+				return
+			}
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			argv = append(argv, fmt.Sprintf("--%s=%s", f.Name, strings.Join(sv.GetSlice(), ",")))
+			return
+		}
+		argv = append(argv, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return append(argv, args...)
+}
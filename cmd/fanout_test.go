@@ -0,0 +1,73 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCommandArgs checks that only the root persistent flags are excluded, the subcommand flag of the same name is kept
+func TestCommandArgs(t *testing.T) {
+	// the output is the directory flag of inspect start and split, it is not the root persistent flag and must be kept
+	excludes := append(append(append([]string{}, fanoutFlags...), outputFlags...), "output")
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"--clusters", "a,b", "-F", "json", "topsql", "elapsed", "--nearly", "10"},
+			want: []string{"topsql", "elapsed", "--nearly=10"},
+		},
+		{
+			args: []string{"--clusters", "a,b", "inspect", "start", "--output", "/data/reports"},
+			want: []string{"inspect", "start", "--output=/data/reports"},
+		},
+		{
+			args: []string{"--group", "prod", "--output-file", "r.json", "split", "range", "--output", "/data/split", "--database", "db"},
+			want: []string{"split", "range", "--database=db", "--output=/data/split"},
+		},
+	}
+	for _, c := range cases {
+		if got := parsedCommandArgs(t, c.args, excludes); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("commandArgs(%v) = %v, want %v", c.args, got, c.want)
+		}
+	}
+}
+
+// TestWatchCommandArgs checks the command arguments executed by each watch sample, the watch, output and cluster selector
+// flags are excluded
+func TestWatchCommandArgs(t *testing.T) {
+	excludes := append(append(append([]string{}, fanoutFlags...), watchFlags...), outputFlags...)
+	args := []string{"-c", "c1", "--watch", "5", "--watch-log", "w.jsonl", "region", "hotspot", "--database", "db", "--top", "10"}
+	want := []string{"region", "hotspot", "--database=db", "--top=10"}
+	if got := parsedCommandArgs(t, args, excludes); !reflect.DeepEqual(got, want) {
+		t.Errorf("commandArgs(%v) = %v, want %v", args, got, want)
+	}
+}
+
+func parsedCommandArgs(t *testing.T, args, excludes []string) []string {
+	rootCmd := Cmd(&App{})
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil {
+		t.Fatalf("find the command %v failed: %v", args, err)
+	}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("parse the command %v flags failed: %v", args, err)
+	}
+	return commandArgs(cmd, nil, excludes...)
+}
@@ -0,0 +1,273 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model"
+)
+
+const (
+	// historyLoadLimit is the number of the latest history records loaded into the readline history for the reverse search
+	historyLoadLimit = 1000
+	// historyDefaultLimit is the number of the history records shown by \history by default
+	historyDefaultLimit = 20
+	// historyRetentionLimit is the number of the latest history records kept in the metadata, the older records are deleted
+	// when the interactive command line starts
+	historyRetentionLimit = 10000
+	// historyRedacted replaces the password of the recorded input
+	historyRedacted = "<redacted>"
+)
+
+var (
+	// historySecretFlagRegexp matches the tidba flag names whose values are the password or the key
+	historySecretFlagRegexp = regexp.MustCompile(`(?i)(password|passwd|passphrase|token|^key$)`)
+	// historyURLPasswordRegexp matches the password of the url, e.g. mysql://{user}:{password}@{host}:{port}/{schema}
+	historyURLPasswordRegexp = regexp.MustCompile(`(://[^:/@\s]*:)[^@\s]*(@)`)
+	// historySQLPasswordRegexps match the string literal of the password in the sql statement, e.g.
+	// CREATE USER / ALTER USER ... IDENTIFIED [WITH plugin] BY / AS '...', SET PASSWORD [FOR user] = '...' and PASSWORD('...')
+	historySQLPasswordRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)(\bIDENTIFIED(?:\s+WITH\s+\S+)?\s+(?:BY|AS)\s+(?:PASSWORD\s+)?)('(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*")`),
+		regexp.MustCompile(`(?is)(\bSET\s+PASSWORD\b(?:\s+FOR\s+\S+)?\s*=\s*(?:PASSWORD\s*\(\s*)?)('(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*")`),
+		regexp.MustCompile(`(?is)(\bPASSWORD\s*\(\s*)('(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*")`),
+	}
+)
+
+// loadHistory loads the latest history records of all clusters into the readline history, so that the up arrow and the reverse search
+// Ctrl+R find the input executed by the previous command lines
+func (l *CommandLine) loadHistory() error {
+	if err := model.PruneHistory(context.Background(), historyRetentionLimit); err != nil {
+		return err
+	}
+	records, err := model.ListHistory(context.Background(), "", "", historyLoadLimit)
+	if err != nil {
+		return err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if err := l.readliner.SaveHistory(historyInput(records[i])); err != nil {
+			return fmt.Errorf("load history error: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveHistory records the executed sql statement or tidba command, the password of the input is redacted before it is recorded,
+// the failure of the recording only prints the warning and does not fail the executed input
+func (l *CommandLine) saveHistory(h *sqlite.History, elapsed time.Duration, rows int, err error) {
+	if !l.recordHistory {
+		return
+	}
+	if h.Delimiter == "" {
+		h.Statement = redactCommand(Cmd(&App{}), h.Statement)
+	} else {
+		h.Statement = redactStatement(h.Statement)
+	}
+	h.Elapsed = elapsed.Seconds()
+	h.RowCount = int64(rows)
+	if err != nil {
+		h.Error = err.Error()
+	}
+	if err := model.CreateHistory(context.Background(), h); err != nil {
+		fmt.Printf("\n⚠️  save the history failed: %v\n", err)
+	}
+}
+
+func (l *CommandLine) runHistoryCommand(args []string) error {
+	var (
+		clusterName string
+		grep        string
+		limit       int
+		all         bool
+	)
+	fs := pflag.NewFlagSet(`\history`, pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVarP(&clusterName, "cluster", "c", "", "")
+	fs.StringVarP(&grep, "grep", "g", "", "")
+	fs.IntVarP(&limit, "limit", "n", historyDefaultLimit, "")
+	fs.BoolVar(&all, "all", false, "")
+	// the grep text may contain the spaces, the quoted argument is kept as one argument
+	words, err := shellwords.Parse(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("parse command err: %v", err)
+	}
+	if err := fs.Parse(words); err != nil {
+		return fmt.Errorf("%v, options: --cluster {clusterName} / --all / --grep {text} / --limit {n}", err)
+	}
+	// the history of the logged in cluster is shown by default
+	if clusterName == "" && !all {
+		clusterName = l.GetClusterName()
+	}
+
+	stime := time.Now()
+	records, err := model.ListHistory(context.Background(), clusterName, grep, limit)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Printf("the history records not found, please ignore and skip\n\n")
+		return nil
+	}
+
+	columns := []string{"ID", "CLUSTER_NAME", "DATABASE", "STATEMENT", "ELAPSED", "ROWS", "ERROR", "CREATED_AT"}
+	var results []map[string]string
+	// the oldest record is shown first, the same as the readline history
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		results = append(results, map[string]string{
+			"ID":           strconv.FormatUint(r.ID, 10),
+			"CLUSTER_NAME": r.ClusterName,
+			"DATABASE":     r.DbName,
+			"STATEMENT":    historyInput(r),
+			"ELAPSED":      fmt.Sprintf("%.2fs", r.Elapsed),
+			"ROWS":         strconv.FormatInt(r.RowCount, 10),
+			"ERROR":        r.Error,
+			"CREATED_AT":   r.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return l.writeResult(columns, results, "", time.Since(stime).Seconds())
+}
+
+// runRerunCommand executes the history record again, the record is executed on the logged in cluster only if the record was executed
+// on the same cluster, the database changed since the record was executed is reported
+func (l *CommandLine) runRerunCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("the history id is required, e.g. \\rerun 10, the id is shown by \\history")
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid history id [%s], the id is shown by \\history", args[0])
+	}
+	h, err := model.GetHistory(context.Background(), id)
+	if err != nil {
+		return err
+	}
+
+	clusterName := l.GetClusterName()
+	if h.ClusterName != "" && h.ClusterName != clusterName {
+		return fmt.Errorf("the history [%d] was executed on the cluster [%s], current logged in cluster [%s], please run [login -c %s] in advance", h.ID, h.ClusterName, clusterName, h.ClusterName)
+	}
+	// the redacted password is not the origin password, the input is executed again by typing it
+	if strings.Contains(h.Statement, historyRedacted) {
+		return fmt.Errorf("the history [%d] contains the redacted password and cannot be executed again, please input the statement or command manually", h.ID)
+	}
+	if h.Delimiter != "" && h.DbName != "" && h.DbName != l.GetDatabaseName() {
+		fmt.Printf("⚠️  the history [%d] was executed on the database [%s], current database [%s]\n", h.ID, h.DbName, l.GetDatabaseName())
+	}
+
+	fmt.Println(l.promptColor.Sprintf("» [rerun:%d] %s", h.ID, historyInput(h)))
+	if l.readliner != nil {
+		if err := l.readliner.SaveHistory(historyInput(h)); err != nil {
+			return fmt.Errorf("save history error: %v", err)
+		}
+	}
+	// the error is printed where it occurs
+	if h.Delimiter == "" {
+		_ = l.executeCommand(l.newRootCmd(), h.Statement)
+		return nil
+	}
+	_ = l.executeStatements([]Statement{{Text: h.Statement, Delimiter: h.Delimiter}}, false)
+	return nil
+}
+
+// historyInput returns the input of the history record, the sql statement is terminated by the delimiter
+func historyInput(h *sqlite.History) string {
+	if h.Delimiter == "" {
+		return h.Statement
+	}
+	return h.Statement + h.Delimiter
+}
+
+// redactStatement replaces the password string literals of the sql statement, the other text of the statement is kept as it is
+func redactStatement(stmt string) string {
+	for _, re := range historySQLPasswordRegexps {
+		stmt = re.ReplaceAllString(stmt, "${1}'"+historyRedacted+"'")
+	}
+	return stmt
+}
+
+// redactCommand replaces the values of the password and key flags and the password of the url of the tidba command, the command
+// is rebuilt from the parsed arguments only if something is redacted, otherwise the input is kept as it is
+func redactCommand(rootCmd *cobra.Command, line string) string {
+	words, err := shellwords.Parse(line)
+	if err != nil || len(words) == 0 {
+		return historyURLPasswordRegexp.ReplaceAllString(line, "${1}"+historyRedacted+"${2}")
+	}
+	cmd, _, err := rootCmd.Find(words)
+	if err != nil {
+		cmd = rootCmd
+	}
+	lookup := func(name string, shorthand bool) *pflag.Flag {
+		for _, fs := range []*pflag.FlagSet{cmd.Flags(), cmd.InheritedFlags()} {
+			if shorthand {
+				if f := fs.ShorthandLookup(name); f != nil {
+					return f
+				}
+			} else if f := fs.Lookup(name); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+
+	redacted := false
+	for i := 0; i < len(words); i++ {
+		if w := historyURLPasswordRegexp.ReplaceAllString(words[i], "${1}"+historyRedacted+"${2}"); w != words[i] {
+			words[i], redacted = w, true
+		}
+		if !strings.HasPrefix(words[i], "-") || words[i] == "-" || words[i] == "--" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(words[i], "-"), "=")
+		shorthand := !strings.HasPrefix(words[i], "--")
+		if shorthand && !hasValue && len(name) > 1 {
+			// the shorthand value may follow the shorthand directly, e.g. -p{password}
+			name, value, hasValue = name[:1], name[1:], true
+		}
+		f := lookup(name, shorthand)
+		if f == nil || f.Value.Type() == "bool" || !historySecretFlagRegexp.MatchString(f.Name) {
+			continue
+		}
+		switch {
+		case hasValue && value != "":
+			words[i] = strings.TrimSuffix(words[i], value) + historyRedacted
+			redacted = true
+		case !hasValue && i+1 < len(words):
+			i++
+			words[i] = historyRedacted
+			redacted = true
+		}
+	}
+	if !redacted {
+		return line
+	}
+	for i, w := range words {
+		if w == "" || strings.ContainsAny(w, " \t\n'\"\\$`") {
+			words[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/model/inspect"
 	"github.com/wentaojin/tidba/utils/cluster/executor"
 	"github.com/wentaojin/tidba/utils/cluster/operator"
@@ -93,11 +95,10 @@ func (a *AppClusterInspectCreate) Cmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				fmt.Printf("cluster ispection config content:\n%s", content)
-				return nil
+				return outputInspectConfig(a.output, content)
 			}
 
-			p := tea.NewProgram(inspect.NewInspectCreateModel(a.clusterName), tea.WithAltScreen())
+			p := newProgram(inspect.NewInspectCreateModel(a.clusterName), tea.WithAltScreen())
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -108,7 +109,7 @@ func (a *AppClusterInspectCreate) Cmd() *cobra.Command {
 			lModel := teaModel.(inspect.InspectCreateModel)
 
 			if lModel.Error == nil && lModel.Msg != "" {
-				fmt.Printf("cluster ispection config content:\n%s", lModel.Msg)
+				return outputInspectConfig(a.output, lModel.Msg)
 			}
 			return nil
 		},
@@ -120,6 +121,21 @@ func (a *AppClusterInspectCreate) Cmd() *cobra.Command {
 	return cmd
 }
 
+// outputInspectConfig writes the yaml text of the inspection config in the table format, the other formats collect the
+// decoded config so that the json and yaml documents hold the config fields instead of the yaml text
+func outputInspectConfig(output *model.Output, content string) error {
+	if output.IsTable() {
+		output.Printf("cluster ispection config content:\n%s", content)
+		return nil
+	}
+	cfg, err := inspect.ParseInspectConfig(content)
+	if err != nil {
+		return err
+	}
+	output.Value("cluster inspection config content", cfg)
+	return nil
+}
+
 type AppClusterInspectDelete struct {
 	*AppInspect
 	force bool
@@ -155,7 +171,7 @@ func (a *AppClusterInspectDelete) Cmd() *cobra.Command {
 					}
 				}
 			}
-			p := tea.NewProgram(inspect.NewInspectDeleteModel(a.clusterName))
+			p := newProgram(inspect.NewInspectDeleteModel(a.clusterName))
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -167,7 +183,7 @@ func (a *AppClusterInspectDelete) Cmd() *cobra.Command {
 			}
 
 			if lModel.Msg != "" {
-				fmt.Printf("cluster ispection config content:\n%s", lModel.Msg)
+				return outputInspectConfig(a.output, lModel.Msg)
 			}
 			return nil
 		},
@@ -209,11 +225,10 @@ func (a *AppClusterInspectUpdate) Cmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				fmt.Printf("cluster ispection config content:\n%s", content)
-				return nil
+				return outputInspectConfig(a.output, content)
 			}
 
-			p := tea.NewProgram(inspect.NewInspectUpdateModel(a.clusterName), tea.WithAltScreen())
+			p := newProgram(inspect.NewInspectUpdateModel(a.clusterName), tea.WithAltScreen())
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -223,7 +238,7 @@ func (a *AppClusterInspectUpdate) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if lModel.Msg != nil {
-				fmt.Printf("cluster ispection config content:\n%s", lModel.Msg.String())
+				return outputInspectConfig(a.output, lModel.Msg.String())
 			}
 			return nil
 		},
@@ -259,7 +274,7 @@ func (a *AppClusterInspectQuery) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(inspect.NewInspectQueryModel(a.clusterName))
+			p := newProgram(inspect.NewInspectQueryModel(a.clusterName))
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -269,7 +284,57 @@ func (a *AppClusterInspectQuery) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if lModel.Msg != nil {
-				fmt.Printf("cluster ispection config content:\n%s", lModel.Msg.String())
+				return outputInspectConfig(a.output, lModel.Msg.String())
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	return cmd
+}
+
+type AppClusterInspectChecks struct {
+	*AppInspect
+}
+
+func (a *AppInspect) AppClusterInspectChecks() Cmder {
+	return &AppClusterInspectChecks{AppInspect: a}
+}
+
+func (a *AppClusterInspectChecks) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checks",
+		Short: "list the cluster inspction sql checks",
+		Long:  "List the built-in and custom sql checks of the development and statistics best practices, the id is used by custom_checks and disabled_checks of the inspection config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg *inspect.InspectConfig
+			if a.clusterName != "" {
+				c, err := inspect.QueryInspectConfig(context.Background(), a.clusterName)
+				if err != nil {
+					return err
+				}
+				cfg = c
+			}
+			columns := []string{"id", "module", "source", "category", "check_item", "severity", "min_version", "max_version", "threshold", "enabled"}
+			var rows [][]interface{}
+			for _, c := range inspect.ListInspectChecks(cfg) {
+				rows = append(rows, []interface{}{
+					c.ID,
+					c.Module,
+					c.Source,
+					c.Category,
+					c.CheckItem,
+					c.Severity,
+					c.MinVersion,
+					c.MaxVersion,
+					c.Threshold,
+					c.Enabled,
+				})
+			}
+			if err := a.output.Table("cluster inspection check content", columns, rows); err != nil {
+				return err
 			}
 			return nil
 		},
@@ -291,6 +356,11 @@ type AppClusterInspectStart struct {
 	concurrency  int
 	output       string
 	sshPort      int
+	nearly       int
+	startTime    string
+	endTime      string
+	modules      []string
+	skipModules  []string
 }
 
 func (a *AppInspect) AppClusterInspectStart() Cmder {
@@ -306,6 +376,23 @@ func (a *AppClusterInspectStart) Cmd() *cobra.Command {
 			if a.clusterName == "" {
 				return fmt.Errorf(`the cluster_name cannot be empty, required flag(s) -c {clusterName} not set`)
 			}
+			switch {
+			case a.nearly < 0:
+				return fmt.Errorf("the flag [--nearly] cannot be less than 0")
+			case a.startTime != "" && a.endTime == "":
+				return fmt.Errorf("the inspection time range requires both the flag [--start] and flag [--end]")
+			case a.startTime == "" && a.endTime != "":
+				return fmt.Errorf("the inspection time range requires both the flag [--start] and flag [--end]")
+			case a.startTime != "" && a.endTime != "":
+				// reset nearly options, --start and --end have higher priority
+				a.nearly = 0
+			}
+			if len(a.modules) > 0 && len(a.skipModules) > 0 {
+				return fmt.Errorf("the flag [--modules] and flag [--skip-modules] cannot be set at the same time")
+			}
+			if err := inspect.ValidateInspectModules(append(a.modules, a.skipModules...)); err != nil {
+				return err
+			}
 			_, err := database.Connector.GetDatabase(a.clusterName)
 			if err != nil {
 				return err
@@ -365,6 +452,11 @@ func (a *AppClusterInspectStart) Cmd() *cobra.Command {
 			insp, err := inspect.StartClusterInspect(
 				context.Background(),
 				a.clusterName,
+				a.nearly,
+				a.startTime,
+				a.endTime,
+				a.modules,
+				a.skipModules,
 				l,
 				sshConnProps,
 				sshProxyProps,
@@ -394,11 +486,22 @@ func (a *AppClusterInspectStart) Cmd() *cobra.Command {
 				return err
 			}
 
+			if !isAbnormal {
+				abnormalFile = ""
+			}
+			// the inspection has finished and the report is exported, the failure of saving the run is only warned
+			run, saveErr := inspect.SaveInspectRun(context.Background(), a.clusterName, insp, fileName, abnormalFile)
+
 			l.Infof("+ Success inspect %v cluster", color.RedString("[%v]", a.clusterName))
 			l.Infof("  - Inspect report exported to %s, please download and view", color.GreenString("[%v]", fileName))
 			if isAbnormal {
 				l.Infof("  - Dev-Practices and DB-Statistics abnormal report exported to %s, please download and view", color.RedString("[%v]", abnormalFile))
 			}
+			if saveErr != nil {
+				l.Warnf("  - Inspect run save failed: %v", saveErr)
+			} else {
+				l.Infof("  - Inspect run saved as %s, compare with the other run by [inspect diff {runID} %d]", color.GreenString("[%d]", run.ID), run.ID)
+			}
 			return nil
 		},
 		TraverseChildren: true,
@@ -415,6 +518,258 @@ func (a *AppClusterInspectStart) Cmd() *cobra.Command {
 	cmd.Flags().StringVarP(&a.output, "output", "o", "/tmp", "Configure the inspection report output directory")
 	cmd.Flags().IntVar(&a.concurrency, "concurrency", 5, "max number of parallel tasks to run")
 	cmd.Flags().IntVar(&a.sshPort, "port", 22, "SSH port to use for the connection (default: 22)")
+	cmd.Flags().IntVar(&a.nearly, "nearly", 0, "configure the inspection time window ending now, size: minutes, overrides the window_minutes of the inspection config")
+	cmd.Flags().StringVar(&a.startTime, "start", "", "configure the inspection time range with start time, e.g. '2006-01-02 15:04:05', overrides the flag --nearly")
+	cmd.Flags().StringVar(&a.endTime, "end", "", "configure the inspection time range with end time, e.g. '2006-01-02 15:04:05', overrides the flag --nearly")
+	cmd.Flags().StringSliceVar(&a.modules, "modules", nil, "configure the inspection modules to run, e.g. check_dmesg_logs, overrides the modules of the inspection config")
+	cmd.Flags().StringSliceVar(&a.skipModules, "skip-modules", nil, "configure the inspection modules to skip, e.g. check_dmesg_logs")
+
+	return cmd
+}
+
+type AppInspectHistory struct {
+	*AppInspect
+}
+
+func (a *AppInspect) AppInspectHistory() Cmder {
+	return &AppInspectHistory{AppInspect: a}
+}
+
+func (a *AppInspectHistory) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "history of the cluster inspction runs",
+		Long:  "Options for querying the inspection runs saved by [inspect start], the runs are compared by [inspect diff]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	return cmd
+}
+
+type AppInspectHistoryList struct {
+	*AppInspectHistory
+	limit int
+}
+
+func (a *AppInspectHistory) AppInspectHistoryList() Cmder {
+	return &AppInspectHistoryList{AppInspectHistory: a}
+}
+
+func (a *AppInspectHistoryList) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the cluster inspction runs",
+		Long:  "List the inspection runs in descending order of the run id, filtered by the cluster name -c",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs, err := inspect.ListInspectRun(context.Background(), a.clusterName, a.limit)
+			if err != nil {
+				return err
+			}
+			if len(runs) == 0 {
+				a.output.Notice("the inspection runs not found, please run [inspect start -c {clusterName}] to inspect the cluster")
+				return nil
+			}
+			columns := []string{"id", "cluster_name", "inspection_time", "inspection_window", "abnormal_count", "report_file", "abnormal_file"}
+			var rows [][]interface{}
+			for _, r := range runs {
+				rows = append(rows, []interface{}{
+					r.ID,
+					r.ClusterName,
+					r.InspectionTime,
+					r.InspectionWindow,
+					r.AbnormalCount,
+					r.ReportFile,
+					r.AbnormalFile,
+				})
+			}
+			if err := a.output.Table("cluster inspection run content", columns, rows); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().IntVar(&a.limit, "limit", 20, "the maximum number of the inspection runs, 0 means unlimited")
+	return cmd
+}
+
+type AppInspectHistoryShow struct {
+	*AppInspectHistory
+	id         uint64
+	reportFile string
+}
+
+func (a *AppInspectHistory) AppInspectHistoryShow() Cmder {
+	return &AppInspectHistoryShow{AppInspectHistory: a}
+}
 
+func (a *AppInspectHistoryShow) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "show the cluster inspction run detail",
+		Long:  "Show the inspection run detail and its summary, the html report of the run can be generated again by the flag --report-file",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.id == 0 {
+				return fmt.Errorf(`the inspection run id cannot be empty, required flag(s) --id {runID} not set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run, rep, err := inspect.GetInspectRun(context.Background(), a.id)
+			if err != nil {
+				return err
+			}
+			if a.reportFile != "" {
+				file, err := os.OpenFile(a.reportFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				if err := inspect.GenClusterInspectReport(rep, file); err != nil {
+					return err
+				}
+			}
+
+			columns := []string{"name", "value"}
+			rows := [][]interface{}{
+				{"id", run.ID},
+				{"cluster_name", run.ClusterName},
+				{"cluster_version", rep.ClusterVersion},
+				{"inspection_time", run.InspectionTime},
+				{"inspection_window", run.InspectionWindow},
+				{"abnormal_count", run.AbnormalCount},
+				{"report_file", run.ReportFile},
+				{"abnormal_file", run.AbnormalFile},
+			}
+			if err := a.output.Table("cluster inspection run content", columns, rows); err != nil {
+				return err
+			}
+
+			var summaries [][]interface{}
+			for _, s := range rep.InspectSummary {
+				summaries = append(summaries, []interface{}{s.SummaryName, s.SummaryResult})
+			}
+			if err := a.output.Table("cluster inspection summary content", []string{"summary_name", "summary_result"}, summaries); err != nil {
+				return err
+			}
+			if a.reportFile != "" {
+				a.output.Notice("the inspection report of the run [%d] exported to [%s]", run.ID, a.reportFile)
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().Uint64Var(&a.id, "id", 0, "the inspection run id, see by [inspect history list]")
+	cmd.Flags().StringVar(&a.reportFile, "report-file", "", "generate the html inspection report of the run to the file")
+	return cmd
+}
+
+type AppInspectDiff struct {
+	*AppInspect
+	tolerance float64
+	htmlFile  string
+}
+
+func (a *AppInspect) AppInspectDiff() Cmder {
+	return &AppInspectDiff{AppInspect: a}
+}
+
+func (a *AppInspectDiff) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff {runA} {runB}",
+		Short: "diff the cluster inspction runs",
+		Long:  "Diff two inspection runs, the checks newly failed or recovered, the changed variables and configs, and the metrics changed beyond the tolerance are shown, the run A is the baseline",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("the inspection run ids are required, e.g. [inspect diff {runA} {runB}], the run ids are shown by [inspect history list]")
+			}
+			for _, arg := range args {
+				if _, err := strconv.ParseUint(arg, 10, 64); err != nil {
+					return fmt.Errorf("invalid inspection run id [%s], the run id is the number", arg)
+				}
+			}
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.tolerance < 0 {
+				return fmt.Errorf("the flag [--tolerance] cannot be less than 0")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			beforeID, _ := strconv.ParseUint(args[0], 10, 64)
+			afterID, _ := strconv.ParseUint(args[1], 10, 64)
+			beforeRun, before, err := inspect.GetInspectRun(ctx, beforeID)
+			if err != nil {
+				return err
+			}
+			afterRun, after, err := inspect.GetInspectRun(ctx, afterID)
+			if err != nil {
+				return err
+			}
+
+			d := &inspect.InspectDiff{
+				Before:    &inspect.InspectDiffRun{ID: beforeRun.ID, ClusterName: beforeRun.ClusterName, InspectionTime: beforeRun.InspectionTime, InspectionWindow: beforeRun.InspectionWindow},
+				After:     &inspect.InspectDiffRun{ID: afterRun.ID, ClusterName: afterRun.ClusterName, InspectionTime: afterRun.InspectionTime, InspectionWindow: afterRun.InspectionWindow},
+				Tolerance: a.tolerance,
+				Items:     inspect.DiffInspectReport(before, after, a.tolerance),
+			}
+			if a.htmlFile != "" {
+				file, err := os.OpenFile(a.htmlFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				if err := inspect.GenInspectDiffReport(d, file); err != nil {
+					return err
+				}
+			}
+
+			if beforeRun.ClusterName != afterRun.ClusterName {
+				a.output.Notice("the inspection runs belong to the different clusters [%s] and [%s]", beforeRun.ClusterName, afterRun.ClusterName)
+			}
+			runs := [][]interface{}{
+				{"A", d.Before.ID, d.Before.ClusterName, d.Before.InspectionTime, d.Before.InspectionWindow},
+				{"B", d.After.ID, d.After.ClusterName, d.After.InspectionTime, d.After.InspectionWindow},
+			}
+			if err := a.output.Table("cluster inspection run content", []string{"run", "id", "cluster_name", "inspection_time", "inspection_window"}, runs); err != nil {
+				return err
+			}
+
+			if len(d.Items) == 0 {
+				a.output.Notice("no difference detected between the inspection runs [%d] and [%d]", beforeRun.ID, afterRun.ID)
+			} else {
+				columns := []string{"category", "item", "instance", "change", fmt.Sprintf("run_%d", beforeRun.ID), fmt.Sprintf("run_%d", afterRun.ID), "delta"}
+				var rows [][]interface{}
+				for _, it := range d.Items {
+					rows = append(rows, []interface{}{it.Category, it.Item, it.Instance, it.Change, it.Before, it.After, it.Delta})
+				}
+				if err := a.output.Table("cluster inspection diff content", columns, rows); err != nil {
+					return err
+				}
+			}
+			if a.htmlFile != "" {
+				a.output.Notice("the inspection diff report exported to [%s]", a.htmlFile)
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().Float64Var(&a.tolerance, "tolerance", inspect.DefaultInspectDiffTolerance, "the tolerance of the metric delta in percent, the metric changed beyond the tolerance is shown")
+	cmd.Flags().StringVar(&a.htmlFile, "html", "", "write the html diff report to the file")
 	return cmd
 }
@@ -88,7 +88,9 @@ func (a *AppKillSql) Cmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger.NewLoggerConsoleOutput(true)
 			defer logger.Sync()
-			return kill.GenerateKillSessionSqlBySqlDigest(context.Background(), a.clusterName, a.sqlDigests, a.duration, a.interval, a.concurrency)
+			return runAudited(cmd, args, a.clusterName, func() error {
+				return kill.GenerateKillSessionSqlBySqlDigest(context.Background(), a.clusterName, a.sqlDigests, a.duration, a.interval, a.concurrency)
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
@@ -129,7 +131,9 @@ func (a *AppKillUsername) Cmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger.NewLoggerConsoleOutput(true)
 			defer logger.Sync()
-			return kill.GenerateKillSessionSqlByUsername(context.Background(), a.clusterName, a.usernames, a.duration, a.interval, a.concurrency)
+			return runAudited(cmd, args, a.clusterName, func() error {
+				return kill.GenerateKillSessionSqlByUsername(context.Background(), a.clusterName, a.usernames, a.duration, a.interval, a.concurrency)
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
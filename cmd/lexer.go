@@ -0,0 +1,277 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultSQLDelimiter = ";"
+	// VerticalSQLDelimiter terminates the statement and displays the result vertically, it is recognized whatever the delimiter is
+	VerticalSQLDelimiter = `\G`
+)
+
+const (
+	lexStateNormal = iota
+	lexStateQuote
+	lexStateLineComment
+	lexStateBlockComment
+)
+
+// Statement is the complete sql statement split from the input
+type Statement struct {
+	Text      string // statement text without the delimiter, the comments and hints are kept
+	Delimiter string // the delimiter terminates the statement, ; / \G or the delimiter configured by DELIMITER
+}
+
+// SQLLexer splits the multi-line input into the sql statements, the delimiter inside the quoted string, the backtick quoted identifier
+// and the comment is not recognized as the end of the statement. The lexer state is kept across the lines, so that the statement
+// and the quoted string can span multiple lines. The DELIMITER {delimiter} line changes the delimiter as the mysql client does
+type SQLLexer struct {
+	delimiter string
+	buf       []rune
+	state     int
+	quote     rune
+}
+
+func NewSQLLexer() *SQLLexer {
+	return &SQLLexer{delimiter: DefaultSQLDelimiter}
+}
+
+// Delimiter returns the current statement delimiter
+func (l *SQLLexer) Delimiter() string {
+	return l.delimiter
+}
+
+// Pending returns whether the incomplete statement is buffered, waiting for the next line
+func (l *SQLLexer) Pending() bool {
+	return l.state != lexStateNormal || strings.TrimSpace(string(l.buf)) != ""
+}
+
+// Reset discards the buffered incomplete statement, the delimiter is kept
+func (l *SQLLexer) Reset() {
+	l.buf = nil
+	l.state = lexStateNormal
+	l.quote = 0
+}
+
+// Feed appends the input line and returns the statements completed by the line, the rest of the line is buffered
+func (l *SQLLexer) Feed(line string) []Statement {
+	if !l.Pending() {
+		if fields := strings.Fields(line); len(fields) > 0 && strings.EqualFold(fields[0], "DELIMITER") {
+			if len(fields) > 1 {
+				l.delimiter = fields[1]
+			}
+			l.Reset()
+			return nil
+		}
+	}
+
+	var (
+		stmts []Statement
+		input = []rune(line + "\n")
+		delim = []rune(l.delimiter)
+	)
+	for i := 0; i < len(input); i++ {
+		r := input[i]
+		switch l.state {
+		case lexStateQuote:
+			l.buf = append(l.buf, r)
+			switch {
+			case r == '\\' && l.quote != '`' && i+1 < len(input):
+				// the escaped character inside the string
+				i++
+				l.buf = append(l.buf, input[i])
+			case r == l.quote:
+				l.state = lexStateNormal
+			}
+			continue
+		case lexStateLineComment:
+			l.buf = append(l.buf, r)
+			if r == '\n' {
+				l.state = lexStateNormal
+			}
+			continue
+		case lexStateBlockComment:
+			l.buf = append(l.buf, r)
+			if r == '*' && i+1 < len(input) && input[i+1] == '/' {
+				i++
+				l.buf = append(l.buf, input[i])
+				l.state = lexStateNormal
+			}
+			continue
+		}
+
+		switch {
+		case hasRunesPrefix(input[i:], delim):
+			stmts = append(stmts, l.emit(l.delimiter))
+			i += len(delim) - 1
+		case r == '\\' && i+1 < len(input) && input[i+1] == 'G':
+			stmts = append(stmts, l.emit(VerticalSQLDelimiter))
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			// the doubled quote is recognized as closing and reopening the quote
+			l.buf = append(l.buf, r)
+			l.state = lexStateQuote
+			l.quote = r
+		case r == '#' || (r == '-' && i+2 < len(input) && input[i+1] == '-' && unicode.IsSpace(input[i+2])):
+			l.buf = append(l.buf, r)
+			l.state = lexStateLineComment
+		case r == '/' && i+1 < len(input) && input[i+1] == '*':
+			// the optimizer hint /*+ */ and the executable comment /*! */ are kept and sent to the database
+			l.buf = append(l.buf, r, input[i+1])
+			i++
+			l.state = lexStateBlockComment
+		default:
+			l.buf = append(l.buf, r)
+		}
+	}
+	// the comments without the statement are discarded, e.g. the comment only line, the punctuation is kept since it may begin
+	// the statement, e.g. the ( line of the parenthesized union
+	if l.state == lexStateNormal && isCommentOnly(string(l.buf)) {
+		l.buf = nil
+	}
+	return stmts
+}
+
+// isCommentOnly returns whether the text only contains the spaces and the comments, the executable comment /*! */ is the statement
+func isCommentOnly(text string) bool {
+	input := []rune(text)
+	for i := 0; i < len(input); i++ {
+		r := input[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '#' || (r == '-' && i+1 < len(input) && input[i+1] == '-' && (i+2 == len(input) || unicode.IsSpace(input[i+2]))):
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(input) && input[i+1] == '*' && (i+2 == len(input) || input[i+2] != '!'):
+			j := i + 2
+			for j+1 < len(input) && (input[j] != '*' || input[j+1] != '/') {
+				j++
+			}
+			i = j + 1
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (l *SQLLexer) emit(delimiter string) Statement {
+	s := Statement{Text: strings.TrimSpace(string(l.buf)), Delimiter: delimiter}
+	l.buf = nil
+	return s
+}
+
+// StatementKeywords returns the first n keywords of the statement, the leading comments are skipped,
+// and the content of the executable comment /*! */ is recognized as the statement, e.g. /*!40101 SET ... */.
+// The quoted identifier is returned without the backticks
+func StatementKeywords(text string, n int) []string {
+	var (
+		words []string
+		word  []rune
+		input = []rune(text)
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	for i := 0; i < len(input) && len(words) < n; i++ {
+		r := input[i]
+		switch {
+		case r == '/' && i+2 < len(input) && input[i+1] == '*' && input[i+2] == '!':
+			// skip the executable comment mark and the version number, the content is the statement
+			flush()
+			i += 2
+			for i+1 < len(input) && unicode.IsDigit(input[i+1]) {
+				i++
+			}
+		case r == '/' && i+1 < len(input) && input[i+1] == '*':
+			flush()
+			j := i + 2
+			for j+1 < len(input) && (input[j] != '*' || input[j+1] != '/') {
+				j++
+			}
+			if j+1 >= len(input) {
+				return words
+			}
+			i = j + 1
+		case r == '*' && i+1 < len(input) && input[i+1] == '/':
+			// the end of the executable comment
+			flush()
+			i++
+		case r == '#' || (r == '-' && i+1 < len(input) && input[i+1] == '-' && (i+2 == len(input) || unicode.IsSpace(input[i+2]))):
+			flush()
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case r == '`' || r == '\'' || r == '"':
+			// the quoted identifier or string is one keyword, the backticks are removed
+			flush()
+			if r != '`' {
+				word = append(word, r)
+			}
+			for i++; i < len(input); i++ {
+				if input[i] == '\\' && r != '`' && i+1 < len(input) {
+					word = append(word, input[i], input[i+1])
+					i++
+					continue
+				}
+				if input[i] == r {
+					if i+1 < len(input) && input[i+1] == r {
+						if word = append(word, r); r != '`' {
+							word = append(word, r)
+						}
+						i++
+						continue
+					}
+					break
+				}
+				word = append(word, input[i])
+			}
+			if r != '`' {
+				word = append(word, r)
+			}
+			flush()
+		case unicode.IsSpace(r) || r == ';' || r == '(' || r == ')' || r == ',':
+			flush()
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
+func hasRunesPrefix(s, prefix []rune) bool {
+	if len(prefix) == 0 || len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
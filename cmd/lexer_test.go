@@ -0,0 +1,219 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLLexerFeed(t *testing.T) {
+	cases := []struct {
+		name    string
+		lines   []string
+		want    []Statement
+		pending bool
+	}{
+		{
+			name:  "single statement",
+			lines: []string{"SELECT 1;"},
+			want:  []Statement{{Text: "SELECT 1", Delimiter: ";"}},
+		},
+		{
+			name:  "multiple statements per line",
+			lines: []string{"SELECT 1; SELECT 2;SELECT 3\\G"},
+			want:  []Statement{{Text: "SELECT 1", Delimiter: ";"}, {Text: "SELECT 2", Delimiter: ";"}, {Text: "SELECT 3", Delimiter: `\G`}},
+		},
+		{
+			name:  "statement spans lines",
+			lines: []string{"SELECT *", "FROM t", "WHERE id = 1;"},
+			want:  []Statement{{Text: "SELECT *\nFROM t\nWHERE id = 1", Delimiter: ";"}},
+		},
+		{
+			name:    "incomplete statement is pending",
+			lines:   []string{"SELECT 1; SELECT"},
+			want:    []Statement{{Text: "SELECT 1", Delimiter: ";"}},
+			pending: true,
+		},
+		{
+			name:  "backslash escaped quote",
+			lines: []string{`SELECT 'a\';b';`},
+			want:  []Statement{{Text: `SELECT 'a\';b'`, Delimiter: ";"}},
+		},
+		{
+			name:  "doubled quote",
+			lines: []string{`SELECT 'a'';b', "c"";d";`},
+			want:  []Statement{{Text: `SELECT 'a'';b', "c"";d"`, Delimiter: ";"}},
+		},
+		{
+			name:  "backtick identifier",
+			lines: []string{"SELECT `a;b` FROM t;"},
+			want:  []Statement{{Text: "SELECT `a;b` FROM t", Delimiter: ";"}},
+		},
+		{
+			name:  "quoted string spans lines",
+			lines: []string{"SELECT 'a;", "b';"},
+			want:  []Statement{{Text: "SELECT 'a;\nb'", Delimiter: ";"}},
+		},
+		{
+			name:    "unterminated quote is pending",
+			lines:   []string{"SELECT 'a;"},
+			pending: true,
+		},
+		{
+			name:  "dash comment",
+			lines: []string{"SELECT 1 -- comment;", "FROM dual;"},
+			want:  []Statement{{Text: "SELECT 1 -- comment;\nFROM dual", Delimiter: ";"}},
+		},
+		{
+			name:  "double dash without space is not comment",
+			lines: []string{"SELECT 1--1;"},
+			want:  []Statement{{Text: "SELECT 1--1", Delimiter: ";"}},
+		},
+		{
+			name:  "hash comment",
+			lines: []string{"SELECT 1 # comment;", ";"},
+			want:  []Statement{{Text: "SELECT 1 # comment;", Delimiter: ";"}},
+		},
+		{
+			name:  "block comment",
+			lines: []string{"SELECT /* a; */ 1;"},
+			want:  []Statement{{Text: "SELECT /* a; */ 1", Delimiter: ";"}},
+		},
+		{
+			name:  "block comment spans lines",
+			lines: []string{"SELECT /* a;", "b; */ 1;"},
+			want:  []Statement{{Text: "SELECT /* a;\nb; */ 1", Delimiter: ";"}},
+		},
+		{
+			name:  "optimizer hint",
+			lines: []string{"SELECT /*+ USE_INDEX(t, idx); */ * FROM t;"},
+			want:  []Statement{{Text: "SELECT /*+ USE_INDEX(t, idx); */ * FROM t", Delimiter: ";"}},
+		},
+		{
+			name:  "executable comment",
+			lines: []string{"/*!40101 SET NAMES utf8mb4 */;"},
+			want:  []Statement{{Text: "/*!40101 SET NAMES utf8mb4 */", Delimiter: ";"}},
+		},
+		{
+			name:  "executable comment only line is kept",
+			lines: []string{"/*!40101 SET NAMES utf8mb4 */", ";"},
+			want:  []Statement{{Text: "/*!40101 SET NAMES utf8mb4 */", Delimiter: ";"}},
+		},
+		{
+			name:  "comment only lines are discarded",
+			lines: []string{"-- comment", "# comment", "/* comment */", "SELECT 1;"},
+			want:  []Statement{{Text: "SELECT 1", Delimiter: ";"}},
+		},
+		{
+			name:  "punctuation only line is kept",
+			lines: []string{"(", "SELECT 1) UNION (SELECT 2);"},
+			want:  []Statement{{Text: "(\nSELECT 1) UNION (SELECT 2)", Delimiter: ";"}},
+		},
+		{
+			name:  "punctuation line after comment is kept",
+			lines: []string{"-- union", "(SELECT 1)", "UNION", "(SELECT 2);"},
+			want:  []Statement{{Text: "(SELECT 1)\nUNION\n(SELECT 2)", Delimiter: ";"}},
+		},
+		{
+			name:  "vertical delimiter",
+			lines: []string{"SHOW PROCESSLIST\\G"},
+			want:  []Statement{{Text: "SHOW PROCESSLIST", Delimiter: `\G`}},
+		},
+		{
+			name:  "vertical delimiter inside quote",
+			lines: []string{`SELECT '\G';`},
+			want:  []Statement{{Text: `SELECT '\G'`, Delimiter: ";"}},
+		},
+		{
+			name: "delimiter block",
+			lines: []string{
+				"DELIMITER //",
+				"CREATE PROCEDURE p()",
+				"BEGIN",
+				"  SELECT 1;",
+				"  SELECT 2;",
+				"END//",
+				"DELIMITER ;",
+				"SELECT 3;",
+			},
+			want: []Statement{
+				{Text: "CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND", Delimiter: "//"},
+				{Text: "SELECT 3", Delimiter: ";"},
+			},
+		},
+		{
+			name:  "vertical delimiter inside delimiter block",
+			lines: []string{"delimiter $$", "SELECT 1\\G SELECT 2$$"},
+			want:  []Statement{{Text: "SELECT 1", Delimiter: `\G`}, {Text: "SELECT 2", Delimiter: "$$"}},
+		},
+		{
+			name:    "delimiter inside statement is not command",
+			lines:   []string{"SELECT", "DELIMITER //"},
+			pending: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := NewSQLLexer()
+			var got []Statement
+			for _, line := range c.lines {
+				got = append(got, l.Feed(line)...)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Feed(%q) = %q, want %q", c.lines, got, c.want)
+			}
+			if l.Pending() != c.pending {
+				t.Errorf("Feed(%q) pending = %v, want %v", c.lines, l.Pending(), c.pending)
+			}
+		})
+	}
+}
+
+func TestSQLLexerDelimiter(t *testing.T) {
+	l := NewSQLLexer()
+	if stmts := l.Feed("DELIMITER //"); len(stmts) != 0 || l.Delimiter() != "//" {
+		t.Fatalf("Feed(DELIMITER //) = %q, delimiter %q, want no statement and delimiter //", stmts, l.Delimiter())
+	}
+	l.Feed("SELECT 1;")
+	l.Reset()
+	if l.Pending() || l.Delimiter() != "//" {
+		t.Errorf("Reset() pending = %v, delimiter %q, want no pending and delimiter //", l.Pending(), l.Delimiter())
+	}
+}
+
+func TestStatementKeywords(t *testing.T) {
+	cases := []struct {
+		text string
+		n    int
+		want []string
+	}{
+		{text: "select * from t", n: 2, want: []string{"select", "*"}},
+		{text: "/* comment */ SELECT 1", n: 1, want: []string{"SELECT"}},
+		{text: "-- comment\nSHOW TABLES", n: 2, want: []string{"SHOW", "TABLES"}},
+		{text: "# comment\nSHOW TABLES", n: 1, want: []string{"SHOW"}},
+		{text: "/*!40101 SET NAMES utf8mb4 */", n: 2, want: []string{"SET", "NAMES"}},
+		{text: "SELECT /*+ HINT() */ 1", n: 2, want: []string{"SELECT", "1"}},
+		{text: "(SELECT 1) UNION (SELECT 2)", n: 1, want: []string{"SELECT"}},
+		{text: "USE `my db`", n: 2, want: []string{"USE", "my db"}},
+		{text: "-- comment", n: 1, want: nil},
+	}
+	for _, c := range cases {
+		if got := StatementKeywords(c.text, c.n); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("StatementKeywords(%q, %d) = %q, want %q", c.text, c.n, got, c.want)
+		}
+	}
+}
@@ -19,10 +19,9 @@ import (
 	"fmt"
 	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/mysql"
 	"github.com/wentaojin/tidba/model"
 )
 
@@ -61,7 +60,7 @@ func (a *AppLogin) Cmd() *cobra.Command {
 				return fmt.Errorf("the cluster_name [%v] are actived. no need to log in again", clusters[0])
 			}
 
-			p := tea.NewProgram(model.NewClusterLoginModel(a.clusterName))
+			p := newProgram(model.NewClusterLoginModel(a.clusterName))
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -74,17 +73,23 @@ func (a *AppLogin) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if len(lModel.Msgs) > 0 {
-				t := table.NewWriter()
-				t.AppendHeader(table.Row{"cluster_name", "database", "path", "private_key"})
-				t.AppendSeparator()
+				columns := []string{"cluster_name", "database", "active_endpoint", "path", "private_key"}
+				var rows [][]interface{}
 				for _, c := range lModel.Msgs {
-					t.AppendRow(table.Row{
+					var endpoint string
+					if conn, ok := database.Connector.LoadDatabase(c.ClusterName); ok {
+						endpoint = conn.(*mysql.Database).ActiveEndpoint()
+					}
+					rows = append(rows, []interface{}{
 						c.ClusterName,
-						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.DbPassword, c.DbHost, c.DbPort),
+						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.MaskedPassword(), c.DbHost, c.DbPort),
+						endpoint,
 						c.Path,
 						c.PrivateKey})
 				}
-				fmt.Printf("cluster config content:\n%s\n\n", t.Render())
+				if err := a.output.Table("cluster config content", columns, rows); err != nil {
+					return err
+				}
 			}
 
 			// reset app prompt
@@ -122,7 +127,7 @@ func (a *AppLogout) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(model.NewClusterLogoutModel(a.clusterName))
+			p := newProgram(model.NewClusterLogoutModel(a.clusterName))
 			if _, err := p.Run(); err != nil {
 				return err
 			}
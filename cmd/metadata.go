@@ -19,13 +19,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
-	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
 	"github.com/wentaojin/tidba/model"
+	"github.com/wentaojin/tidba/utils/secret"
 	"github.com/wentaojin/tidba/utils/stringutil"
 )
 
@@ -82,7 +87,12 @@ func (a *AppMetaCreate) Cmd() *cobra.Command {
 				fmt.Printf("cluster config content:\n%s\n\n", content)
 				return nil
 			}
-			p := tea.NewProgram(model.NewClusterCreateModel(), tea.WithAltScreen())
+			// the cluster password is encrypted by the master key before it is stored,
+			// resolve the master key before the terminal ui is started
+			if _, err := secret.DefaultKeyring.MasterKey(); err != nil {
+				return err
+			}
+			p := newProgram(model.NewClusterCreateModel(), tea.WithAltScreen())
 			if _, err := p.Run(); err != nil {
 				return err
 			}
@@ -127,7 +137,7 @@ func (a *AppMetaDelete) Cmd() *cobra.Command {
 					}
 				}
 			}
-			p := tea.NewProgram(model.NewClusterDeleteModel(a.clusterName))
+			p := newProgram(model.NewClusterDeleteModel(a.clusterName))
 			if _, err := p.Run(); err != nil {
 				return err
 			}
@@ -182,7 +192,12 @@ func (a *AppMetaUpdate) Cmd() *cobra.Command {
 				fmt.Printf("cluster config content:\n%s\n\n", content)
 				return nil
 			}
-			p := tea.NewProgram(model.NewClusterUpdateModel(a.clusterName), tea.WithAltScreen())
+			// the cluster password is encrypted by the master key before it is stored,
+			// resolve the master key before the terminal ui is started
+			if _, err := secret.DefaultKeyring.MasterKey(); err != nil {
+				return err
+			}
+			p := newProgram(model.NewClusterUpdateModel(a.clusterName), tea.WithAltScreen())
 			if _, err := p.Run(); err != nil {
 				return err
 			}
@@ -198,6 +213,7 @@ func (a *AppMetaUpdate) Cmd() *cobra.Command {
 
 type AppMetaQuery struct {
 	*AppMeta
+	showSecret bool
 }
 
 func (a *AppMeta) AppMetaQuery() Cmder {
@@ -216,7 +232,7 @@ func (a *AppMetaQuery) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(model.NewClusterListModel(a.clusterName, 0, 0))
+			p := newProgram(model.NewClusterListModel(a.clusterName, 0, 0))
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -226,17 +242,28 @@ func (a *AppMetaQuery) Cmd() *cobra.Command {
 			lModel := teaModel.(model.ClusterListModel)
 
 			if lModel.Error == nil && len(lModel.Msgs) > 0 {
-				t := table.NewWriter()
-				t.AppendHeader(table.Row{"cluster_name", "database", "path", "private_key"})
-				t.AppendSeparator()
+				columns := []string{"cluster_name", "database", "failover_endpoints", "groups", "sql_policy", "path", "private_key"}
+				var rows [][]interface{}
 				for _, c := range lModel.Msgs {
-					t.AppendRow(table.Row{
+					passwd := c.MaskedPassword()
+					if a.showSecret {
+						passwd, err = database.ResolveClusterPassword(context.Background(), c)
+						if err != nil {
+							return err
+						}
+					}
+					rows = append(rows, []interface{}{
 						c.ClusterName,
-						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.DbPassword, c.DbHost, c.DbPort),
+						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, passwd, c.DbHost, c.DbPort),
+						c.DbEndpoints,
+						c.Groups,
+						strings.Join(database.ResolveClusterSqlPolicy(c), ","),
 						c.Path,
 						c.PrivateKey})
 				}
-				fmt.Printf("cluster config content:\n%s\n\n", t.Render())
+				if err := a.output.Table("cluster config content", columns, rows); err != nil {
+					return err
+				}
 			}
 			return nil
 		},
@@ -244,13 +271,15 @@ func (a *AppMetaQuery) Cmd() *cobra.Command {
 		SilenceErrors:    true,
 		SilenceUsage:     true,
 	}
+	cmd.Flags().BoolVar(&a.showSecret, "show-secret", false, "show the decrypted cluster password instead of the masked value")
 	return cmd
 }
 
 type AppMetaList struct {
 	*AppMeta
-	page     uint64
-	pageSize uint64
+	page       uint64
+	pageSize   uint64
+	showSecret bool
 }
 
 func (a *AppMeta) AppMetaList() Cmder {
@@ -263,7 +292,7 @@ func (a *AppMetaList) Cmd() *cobra.Command {
 		Short: "list the cluster metadata",
 		Long:  "Get information about all accessible clusters",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(model.NewClusterListModel("", a.page, a.pageSize))
+			p := newProgram(model.NewClusterListModel("", a.page, a.pageSize))
 			teaModel, err := p.Run()
 			if err != nil {
 				return err
@@ -273,17 +302,28 @@ func (a *AppMetaList) Cmd() *cobra.Command {
 			lModel := teaModel.(model.ClusterListModel)
 
 			if lModel.Error == nil && len(lModel.Msgs) > 0 {
-				t := table.NewWriter()
-				t.AppendHeader(table.Row{"cluster_name", "database", "path", "private_key"})
-				t.AppendSeparator()
+				columns := []string{"cluster_name", "database", "failover_endpoints", "groups", "sql_policy", "path", "private_key"}
+				var rows [][]interface{}
 				for _, c := range lModel.Msgs {
-					t.AppendRow(table.Row{
+					passwd := c.MaskedPassword()
+					if a.showSecret {
+						passwd, err = database.ResolveClusterPassword(context.Background(), c)
+						if err != nil {
+							return err
+						}
+					}
+					rows = append(rows, []interface{}{
 						c.ClusterName,
-						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.DbPassword, c.DbHost, c.DbPort),
+						fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, passwd, c.DbHost, c.DbPort),
+						c.DbEndpoints,
+						c.Groups,
+						strings.Join(database.ResolveClusterSqlPolicy(c), ","),
 						c.Path,
 						c.PrivateKey})
 				}
-				fmt.Printf("cluster config content:\n%s\n\n", t.Render())
+				if err := a.output.Table("cluster config content", columns, rows); err != nil {
+					return err
+				}
 			}
 			return nil
 		},
@@ -294,5 +334,323 @@ func (a *AppMetaList) Cmd() *cobra.Command {
 
 	cmd.Flags().Uint64Var(&a.page, "page", 1, "specify the query results page")
 	cmd.Flags().Uint64Var(&a.pageSize, "pageSize", 50, "specify the query results page size")
+	cmd.Flags().BoolVar(&a.showSecret, "show-secret", false, "show the decrypted cluster password instead of the masked value")
+	return cmd
+}
+
+type AppMetaRekey struct {
+	*AppMeta
+	newKeyFile string
+}
+
+func (a *AppMeta) AppMetaRekey() Cmder {
+	return &AppMetaRekey{AppMeta: a}
+}
+
+func (a *AppMetaRekey) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "rotate the cluster password master key",
+		Long:  "Re-encrypt all cluster passwords with a new master key, the plaintext passwords of the old metadata are encrypted at the same time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := model.MetadataRekey(context.Background(), func() ([]byte, error) {
+				if a.newKeyFile != "" {
+					keyFile, err := homedir.Expand(a.newKeyFile)
+					if err != nil {
+						return nil, err
+					}
+					if stringutil.IsPathExist(keyFile) {
+						return secret.ReadKeyFile(keyFile)
+					}
+					fmt.Printf("the new master key file [%s] not found, generate a random master key\n", keyFile)
+					return secret.GenerateKeyFile(keyFile)
+				}
+				passphrase := stringutil.PromptForPassword("Please input the new master key passphrase: ")
+				if passphrase == "" {
+					return nil, fmt.Errorf("the new master key passphrase cannot be empty")
+				}
+				if passphrase != stringutil.PromptForPassword("Please input the new master key passphrase again: ") {
+					return nil, fmt.Errorf("the new master key passphrases entered twice are inconsistent")
+				}
+				return []byte(passphrase), nil
+			})
+			if err != nil {
+				return err
+			}
+
+			columns := []string{"cluster_name", "action"}
+			var rows [][]interface{}
+			for _, r := range results {
+				rows = append(rows, []interface{}{r.ClusterName, r.Action})
+			}
+			if err := a.output.Table("cluster rekey content", columns, rows); err != nil {
+				return err
+			}
+			a.output.Notice("the master key has been rotated, please configure the new master key by the environment variable [%s] or the flag [--master-key-file] later", secret.EnvMasterKey)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().StringVar(&a.newKeyFile, "new-key-file", "", "the new master key file, a random master key is generated if the file does not exist (default: prompt for a new passphrase)")
+	return cmd
+}
+
+type AppMetaExport struct {
+	*AppMeta
+	file          string
+	format        string
+	secret        string
+	bundleKeyFile string
+}
+
+func (a *AppMeta) AppMetaExport() Cmder {
+	return &AppMetaExport{AppMeta: a}
+}
+
+func (a *AppMetaExport) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export the cluster metadata bundle",
+		Long:  "Export the cluster, inspect, resource group and sql binding metadata into a versioned json / yaml bundle, used to move tidba between tiup control machines",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.file == "" {
+				return fmt.Errorf(`the bundle file cannot be empty, required flag(s) --file {bundleFile} not set`)
+			}
+			if a.format == "" {
+				a.format = model.BundleFormatJSON
+				if ext := strings.ToLower(filepath.Ext(a.file)); ext == ".yaml" || ext == ".yml" {
+					a.format = model.BundleFormatYAML
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundle, err := model.MetadataExport(context.Background(), a.clusters, a.secret, func() ([]byte, error) {
+				return promptBundleKey(a.bundleKeyFile, true)
+			})
+			if err != nil {
+				return err
+			}
+			content, err := bundle.Marshal(a.format)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(a.file, content, 0600); err != nil {
+				return err
+			}
+
+			columns := []string{"cluster_name", "inspect", "resource_group", "sql_bindings"}
+			var rows [][]interface{}
+			for _, c := range bundle.Clusters {
+				rows = append(rows, []interface{}{c.ClusterName, c.Inspect != nil, c.ResourceGroup != nil, len(c.SqlBindings)})
+			}
+			if err := a.output.Table("cluster export content", columns, rows); err != nil {
+				return err
+			}
+			a.output.Notice("the metadata bundle [version: %d, secret: %s] output: [%s]", bundle.Version, bundle.Secret, a.file)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().StringVarP(&a.file, "file", "f", "", "the metadata bundle output file path")
+	cmd.Flags().StringVar(&a.format, "format", "", "the metadata bundle format, options: json / yaml (default: determined by the file extension, otherwise json)")
+	cmd.Flags().StringVar(&a.secret, "secret", model.BundleSecretRedact, "how the cluster password is written into the bundle, options: redact / encrypt / plain")
+	cmd.Flags().StringVar(&a.bundleKeyFile, "bundle-key-file", "", "the key file used to encrypt the bundle password when --secret encrypt (default: prompt for a passphrase)")
+	return cmd
+}
+
+type AppMetaImport struct {
+	*AppMeta
+	file          string
+	conflict      string
+	renameSuffix  string
+	bundleKeyFile string
+}
+
+func (a *AppMeta) AppMetaImport() Cmder {
+	return &AppMetaImport{AppMeta: a}
+}
+
+func (a *AppMetaImport) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "import the cluster metadata bundle",
+		Long:  "Import the cluster, inspect, resource group and sql binding metadata from the bundle generated by the [meta export] command",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.file == "" {
+				return fmt.Errorf(`the bundle file cannot be empty, required flag(s) --file {bundleFile} not set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := os.ReadFile(a.file)
+			if err != nil {
+				return err
+			}
+			bundle, err := model.UnmarshalMetadataBundle(content)
+			if err != nil {
+				return err
+			}
+			results, err := model.MetadataImport(context.Background(), bundle, a.clusters, a.conflict, a.renameSuffix, func() ([]byte, error) {
+				return promptBundleKey(a.bundleKeyFile, false)
+			})
+			if len(results) > 0 {
+				columns := []string{"cluster_name", "import_name", "action", "message"}
+				var rows [][]interface{}
+				for _, r := range results {
+					rows = append(rows, []interface{}{r.ClusterName, r.ImportName, r.Action, r.Message})
+				}
+				if err := a.output.Table("cluster import content", columns, rows); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().StringVarP(&a.file, "file", "f", "", "the metadata bundle file path")
+	cmd.Flags().StringVar(&a.conflict, "conflict", model.BundleConflictSkip, "the policy when the cluster name is existed, options: skip / overwrite / rename")
+	cmd.Flags().StringVar(&a.renameSuffix, "rename-suffix", "-imported", "the suffix appended to the cluster name when --conflict rename")
+	cmd.Flags().StringVar(&a.bundleKeyFile, "bundle-key-file", "", "the key file used to decrypt the bundle password (default: prompt for a passphrase)")
+	return cmd
+}
+
+// promptBundleKey reads the bundle key from the key file or the passphrase prompt, the export passphrase is confirmed twice
+func promptBundleKey(keyFile string, confirm bool) ([]byte, error) {
+	if keyFile != "" {
+		path, err := homedir.Expand(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return secret.ReadKeyFile(path)
+	}
+	passphrase := stringutil.PromptForPassword("Please input the bundle passphrase: ")
+	if passphrase == "" {
+		return nil, fmt.Errorf("the bundle passphrase cannot be empty")
+	}
+	if confirm && passphrase != stringutil.PromptForPassword("Please input the bundle passphrase again: ") {
+		return nil, fmt.Errorf("the bundle passphrases entered twice are inconsistent")
+	}
+	return []byte(passphrase), nil
+}
+
+type AppMetaSync struct {
+	*AppMeta
+	dbUser   string
+	prune    bool
+	noPrompt bool
+}
+
+func (a *AppMeta) AppMetaSync() Cmder {
+	return &AppMetaSync{AppMeta: a}
+}
+
+func (a *AppMetaSync) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "sync the cluster metadata from tiup",
+		Long:  "Discover all tiup clusters and fill the cluster metadata, only the database user and password of the newly discovered cluster are asked",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := model.MetadataSync(context.Background(), a.clusters, a.prune, func(clusterName string) (string, string, error) {
+				dbUser := a.dbUser
+				if a.noPrompt {
+					if dbUser == "" {
+						dbUser = "root"
+					}
+					return dbUser, "", nil
+				}
+				if dbUser == "" {
+					dbUser = strings.TrimSpace(stringutil.Prompt(fmt.Sprintf("Please input the database user of the cluster [%s] (default: root):", color.HiYellowString(clusterName))))
+					if dbUser == "" {
+						dbUser = "root"
+					}
+				}
+				dbPassword := stringutil.PromptForPassword("Please input the database password of the cluster [%s] user [%s]: ", color.HiYellowString(clusterName), dbUser)
+				return dbUser, dbPassword, nil
+			})
+			if len(results) > 0 {
+				columns := []string{"cluster_name", "action", "database", "message"}
+				var rows [][]interface{}
+				for _, r := range results {
+					rows = append(rows, []interface{}{r.ClusterName, r.Action, r.Database, r.Message})
+				}
+				if err := a.output.Table("cluster sync content", columns, rows); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().StringVar(&a.dbUser, "db-user", "", "the database user of the newly discovered clusters (default: prompt, root if empty)")
+	cmd.Flags().BoolVar(&a.prune, "prune", false, "delete the cluster metadata that disappeared from tiup")
+	cmd.Flags().BoolVar(&a.noPrompt, "no-prompt", false, "do not prompt for the database user and password, the password is left empty and can be configured by [meta update] later")
+	return cmd
+}
+
+type AppMetaMigrate struct {
+	*AppMeta
+	status bool
+}
+
+func (a *AppMeta) AppMetaMigrate() Cmder {
+	return &AppMetaMigrate{AppMeta: a}
+}
+
+func (a *AppMetaMigrate) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "migrate the metadata database schema",
+		Long:  "Apply the pending metadata database schema migrations, the migrations are also applied automatically when tidba starts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+			if err != nil {
+				return err
+			}
+			meta := db.(*sqlite.Database)
+			if !a.status {
+				if err := meta.Migrate(context.Background()); err != nil {
+					return err
+				}
+			}
+			status, err := meta.MigrationStatus(context.Background())
+			if err != nil {
+				return err
+			}
+
+			columns := []string{"version", "name", "status", "applied_at"}
+			var rows [][]interface{}
+			for _, s := range status {
+				if s.Applied {
+					rows = append(rows, []interface{}{s.Version, s.Name, "applied", s.AppliedAt.In(time.Local).Format("2006-01-02 15:04:05")})
+				} else {
+					rows = append(rows, []interface{}{s.Version, s.Name, "pending", ""})
+				}
+			}
+			if err := a.output.Table("metadata schema migration content", columns, rows); err != nil {
+				return err
+			}
+			a.output.Notice("the metadata schema version supported by the current tidba: [%d]", sqlite.LatestSchemaVersion())
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	cmd.Flags().BoolVar(&a.status, "status", false, "only display the applied status of the metadata schema migrations")
 	return cmd
 }
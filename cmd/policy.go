@@ -0,0 +1,209 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/wentaojin/tidba/database"
+)
+
+// sqlStatementClasses is the statement class of the leading keyword, the statement starting with the keyword is executed
+// as the sql statement in the interactive query mode. The keyword not listed is recognized as the tidba command
+var sqlStatementClasses = map[string]string{
+	"SELECT":   database.SqlClassRead,
+	"SHOW":     database.SqlClassRead,
+	"USE":      database.SqlClassRead,
+	"EXPLAIN":  database.SqlClassRead,
+	"DESC":     database.SqlClassRead,
+	"DESCRIBE": database.SqlClassRead,
+	"TRACE":    database.SqlClassRead,
+	"WITH":     database.SqlClassRead,
+	"TABLE":    database.SqlClassRead,
+	"VALUES":   database.SqlClassRead,
+
+	"INSERT":  database.SqlClassWrite,
+	"UPDATE":  database.SqlClassWrite,
+	"DELETE":  database.SqlClassWrite,
+	"REPLACE": database.SqlClassWrite,
+	"LOAD":    database.SqlClassWrite,
+	"IMPORT":  database.SqlClassWrite,
+	"BATCH":   database.SqlClassWrite,
+	"CALL":    database.SqlClassWrite,
+
+	"CREATE":    database.SqlClassDDL,
+	"ALTER":     database.SqlClassDDL,
+	"DROP":      database.SqlClassDDL,
+	"TRUNCATE":  database.SqlClassDDL,
+	"RENAME":    database.SqlClassDDL,
+	"FLASHBACK": database.SqlClassDDL,
+	"RECOVER":   database.SqlClassDDL,
+
+	"ADMIN":      database.SqlClassAdmin,
+	"KILL":       database.SqlClassAdmin,
+	"SET":        database.SqlClassAdmin,
+	"GRANT":      database.SqlClassAdmin,
+	"REVOKE":     database.SqlClassAdmin,
+	"FLUSH":      database.SqlClassAdmin,
+	"ANALYZE":    database.SqlClassAdmin,
+	"SPLIT":      database.SqlClassAdmin,
+	"BEGIN":      database.SqlClassAdmin,
+	"START":      database.SqlClassAdmin,
+	"COMMIT":     database.SqlClassAdmin,
+	"ROLLBACK":   database.SqlClassAdmin,
+	"SAVEPOINT":  database.SqlClassAdmin,
+	"RELEASE":    database.SqlClassAdmin,
+	"LOCK":       database.SqlClassAdmin,
+	"UNLOCK":     database.SqlClassAdmin,
+	"PREPARE":    database.SqlClassAdmin,
+	"EXECUTE":    database.SqlClassAdmin,
+	"DEALLOCATE": database.SqlClassAdmin,
+	"DO":         database.SqlClassAdmin,
+	"BACKUP":     database.SqlClassAdmin,
+	"RESTORE":    database.SqlClassAdmin,
+	"CANCEL":     database.SqlClassAdmin,
+	"QUERY":      database.SqlClassAdmin,
+	"CALIBRATE":  database.SqlClassAdmin,
+	"PLAN":       database.SqlClassAdmin,
+	"SHUTDOWN":   database.SqlClassAdmin,
+}
+
+// sqlSideEffectFunctions are the functions changing the state in the read statement, e.g. SELECT NEXTVAL(seq) increases the
+// sequence, the read statement calling them is classified as the class of the function
+var sqlSideEffectFunctions = map[string]string{
+	"NEXTVAL":           database.SqlClassWrite,
+	"SETVAL":            database.SqlClassWrite,
+	"GET_LOCK":          database.SqlClassAdmin,
+	"RELEASE_LOCK":      database.SqlClassAdmin,
+	"RELEASE_ALL_LOCKS": database.SqlClassAdmin,
+}
+
+// ClassifyStatement returns the statement class read / write / ddl / admin of the sql statement. The statement executed by
+// EXPLAIN ANALYZE and TRACE is classified as the inner statement, the locking read SELECT ... FOR UPDATE / FOR SHARE /
+// LOCK IN SHARE MODE, SELECT ... INTO OUTFILE / DUMPFILE and the read statement calling the sequence functions are classified
+// as write, ADMIN SHOW ... is classified as read, and the unknown statement is classified as admin.
+//
+// The statement is classified by the keywords instead of the sql parser, the content of the executable comment /*! ... */
+// is classified as the statement and the quoted string is never the keyword. The known gaps are: the identifier named as
+// the side effect function or the locking clause keywords is classified as write or admin, the user defined function called
+// by the read statement is classified as read, and the statement of the syntax unknown to the keyword table is classified
+// as admin. The read statement is also executed inside the read only transaction, see Session.ReadOnlyQuery
+func ClassifyStatement(text string) string {
+	keywords := StatementKeywords(text, math.MaxInt)
+	for i := range keywords {
+		keywords[i] = strings.ToUpper(keywords[i])
+	}
+	return classifyKeywords(keywords)
+}
+
+func classifyKeywords(keywords []string) string {
+	if len(keywords) == 0 {
+		return database.SqlClassRead
+	}
+	class, ok := sqlStatementClasses[keywords[0]]
+	if !ok {
+		return database.SqlClassAdmin
+	}
+
+	switch keywords[0] {
+	case "SELECT", "TABLE", "VALUES", "WITH":
+		for i, k := range keywords {
+			next := ""
+			if i+1 < len(keywords) {
+				next = keywords[i+1]
+			}
+			if class, ok := sqlSideEffectFunctions[k]; ok {
+				return class
+			}
+			switch {
+			case keywords[0] == "WITH" && (k == "INSERT" || k == "UPDATE" || k == "DELETE" || k == "REPLACE"):
+				return database.SqlClassWrite
+			case k == "NEXT" && next == "VALUE":
+				return database.SqlClassWrite
+			case k == "FOR" && (next == "UPDATE" || next == "SHARE"):
+				return database.SqlClassWrite
+			case k == "LOCK" && next == "IN":
+				return database.SqlClassWrite
+			case k == "INTO" && (next == "OUTFILE" || next == "DUMPFILE"):
+				return database.SqlClassWrite
+			}
+		}
+	case "EXPLAIN", "DESC", "DESCRIBE", "TRACE":
+		inner, analyze := skipExplainOptions(keywords[1:])
+		if keywords[0] == "TRACE" && len(inner) > 0 && inner[0] == "PLAN" {
+			inner, _ = skipExplainOptions(inner[1:])
+		}
+		// the statement is executed by EXPLAIN ANALYZE and TRACE, the plain EXPLAIN only shows the plan
+		if (analyze || keywords[0] == "TRACE") && len(inner) > 0 {
+			if _, ok := sqlStatementClasses[inner[0]]; ok {
+				return classifyKeywords(inner)
+			}
+		}
+	case "ADMIN":
+		if len(keywords) > 1 && keywords[1] == "SHOW" {
+			return database.SqlClassRead
+		}
+	}
+	return class
+}
+
+// skipExplainOptions skips the ANALYZE and FORMAT = {format} options of EXPLAIN and TRACE, returns the rest keywords
+func skipExplainOptions(keywords []string) ([]string, bool) {
+	analyze := false
+	for len(keywords) > 0 {
+		switch k := keywords[0]; {
+		case k == "ANALYZE":
+			analyze = true
+			keywords = keywords[1:]
+		case k == "FORMAT" || k == "FORMAT=":
+			// FORMAT = 'brief' / FORMAT= 'brief' / FORMAT ='brief'
+			keywords = keywords[1:]
+			if len(keywords) > 0 && strings.HasPrefix(keywords[0], "=") && keywords[0] != "=" {
+				keywords = keywords[1:]
+				continue
+			}
+			if len(keywords) > 0 && keywords[0] == "=" {
+				keywords = keywords[1:]
+			}
+			if len(keywords) > 0 {
+				keywords = keywords[1:]
+			}
+		case strings.HasPrefix(k, "FORMAT="):
+			keywords = keywords[1:]
+		default:
+			return keywords, analyze
+		}
+	}
+	return keywords, analyze
+}
+
+// sqlStatementKeywords returns the sorted leading keywords of the sql statements
+func sqlStatementKeywords() []string {
+	var keywords []string
+	for k := range sqlStatementClasses {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// isSqlStatementKeyword returns whether the keyword starts the sql statement
+func isSqlStatementKeyword(keyword string) bool {
+	_, ok := sqlStatementClasses[strings.ToUpper(keyword)]
+	return ok
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/wentaojin/tidba/database"
+)
+
+func TestClassifyStatement(t *testing.T) {
+	cases := []struct {
+		text  string
+		class string
+	}{
+		// the read statements
+		{text: "SELECT 1", class: database.SqlClassRead},
+		{text: "select * from t where a = 'for update'", class: database.SqlClassRead},
+		{text: "(SELECT a FROM t) UNION (SELECT a FROM t2)", class: database.SqlClassRead},
+		{text: "SELECT a INTO @v FROM t LIMIT 1", class: database.SqlClassRead},
+		{text: "/*+ MAX_EXECUTION_TIME(1000) */ SELECT 1", class: database.SqlClassRead},
+		{text: "SELECT /*+ USE_INDEX(t, idx) */ * FROM t", class: database.SqlClassRead},
+		{text: "SELECT LASTVAL(seq)", class: database.SqlClassRead},
+		{text: "WITH cte AS (SELECT 1) SELECT * FROM cte", class: database.SqlClassRead},
+		{text: "WITH RECURSIVE cte(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM cte WHERE n < 5) SELECT * FROM cte", class: database.SqlClassRead},
+		{text: "TABLE t", class: database.SqlClassRead},
+		{text: "VALUES ROW(1, 2)", class: database.SqlClassRead},
+		{text: "SHOW PROCESSLIST", class: database.SqlClassRead},
+		{text: "show create table t", class: database.SqlClassRead},
+		{text: "DESC t", class: database.SqlClassRead},
+		{text: "EXPLAIN DELETE FROM t", class: database.SqlClassRead},
+		{text: "EXPLAIN FORMAT = 'brief' UPDATE t SET a = 1", class: database.SqlClassRead},
+		{text: "EXPLAIN ANALYZE SELECT * FROM t", class: database.SqlClassRead},
+		{text: "EXPLAIN FOR CONNECTION 1", class: database.SqlClassRead},
+		{text: "TRACE SELECT 1", class: database.SqlClassRead},
+		{text: "ADMIN SHOW DDL JOBS", class: database.SqlClassRead},
+		{text: "USE test", class: database.SqlClassRead},
+		{text: "-- comment only", class: database.SqlClassRead},
+
+		// the write statements
+		{text: "INSERT INTO t VALUES (1)", class: database.SqlClassWrite},
+		{text: "REPLACE INTO t SELECT * FROM t2", class: database.SqlClassWrite},
+		{text: "UPDATE t SET a = 1", class: database.SqlClassWrite},
+		{text: "DELETE FROM t", class: database.SqlClassWrite},
+		{text: "LOAD DATA LOCAL INFILE '/tmp/t.csv' INTO TABLE t", class: database.SqlClassWrite},
+		{text: "IMPORT INTO t FROM '/tmp/t.csv'", class: database.SqlClassWrite},
+		{text: "BATCH ON id LIMIT 1000 DELETE FROM t", class: database.SqlClassWrite},
+		{text: "SELECT * FROM t FOR UPDATE", class: database.SqlClassWrite},
+		{text: "SELECT * FROM t FOR UPDATE NOWAIT", class: database.SqlClassWrite},
+		{text: "select * from t for share", class: database.SqlClassWrite},
+		{text: "SELECT * FROM t LOCK IN SHARE MODE", class: database.SqlClassWrite},
+		{text: "SELECT * FROM t INTO OUTFILE '/tmp/t.csv'", class: database.SqlClassWrite},
+		{text: "SELECT a FROM t INTO DUMPFILE '/tmp/t'", class: database.SqlClassWrite},
+		{text: "WITH cte AS (SELECT 1) SELECT * FROM cte FOR UPDATE", class: database.SqlClassWrite},
+		{text: "WITH cte AS (SELECT 1 AS a) DELETE FROM t WHERE a IN (SELECT a FROM cte)", class: database.SqlClassWrite},
+		{text: "WITH cte AS (SELECT 1 AS a) UPDATE t, cte SET t.a = cte.a", class: database.SqlClassWrite},
+		{text: "SELECT NEXTVAL(seq)", class: database.SqlClassWrite},
+		{text: "SELECT NEXT VALUE FOR seq", class: database.SqlClassWrite},
+		{text: "SELECT SETVAL(seq, 10)", class: database.SqlClassWrite},
+		{text: "EXPLAIN ANALYZE DELETE FROM t", class: database.SqlClassWrite},
+		{text: "EXPLAIN ANALYZE FORMAT='brief' INSERT INTO t VALUES (1)", class: database.SqlClassWrite},
+		{text: "TRACE INSERT INTO t VALUES (1)", class: database.SqlClassWrite},
+		{text: "TRACE PLAN UPDATE t SET a = 1", class: database.SqlClassWrite},
+		{text: "/*!40101 DELETE FROM t */", class: database.SqlClassWrite},
+		{text: "/* comment */ INSERT INTO t VALUES (1)", class: database.SqlClassWrite},
+
+		// the ddl statements
+		{text: "CREATE TABLE t (a INT)", class: database.SqlClassDDL},
+		{text: "ALTER TABLE t ADD INDEX idx (a)", class: database.SqlClassDDL},
+		{text: "DROP TABLE t", class: database.SqlClassDDL},
+		{text: "TRUNCATE TABLE t", class: database.SqlClassDDL},
+		{text: "RENAME TABLE t TO t2", class: database.SqlClassDDL},
+		{text: "FLASHBACK TABLE t TO t2", class: database.SqlClassDDL},
+		{text: "RECOVER TABLE t", class: database.SqlClassDDL},
+		{text: "EXPLAIN ANALYZE CREATE TABLE t (a INT)", class: database.SqlClassDDL},
+
+		// the admin statements
+		{text: "SET GLOBAL tidb_gc_life_time = '24h'", class: database.SqlClassAdmin},
+		{text: "SET @a = 1", class: database.SqlClassAdmin},
+		{text: "KILL TIDB QUERY 1", class: database.SqlClassAdmin},
+		{text: "GRANT SELECT ON *.* TO u", class: database.SqlClassAdmin},
+		{text: "ANALYZE TABLE t", class: database.SqlClassAdmin},
+		{text: "SPLIT TABLE t BETWEEN (0) AND (100) REGIONS 4", class: database.SqlClassAdmin},
+		{text: "ADMIN CHECK TABLE t", class: database.SqlClassAdmin},
+		{text: "BEGIN", class: database.SqlClassAdmin},
+		{text: "START TRANSACTION READ ONLY", class: database.SqlClassAdmin},
+		{text: "LOCK TABLES t WRITE", class: database.SqlClassAdmin},
+		{text: "DO SLEEP(1)", class: database.SqlClassAdmin},
+		{text: "SELECT GET_LOCK('l', 10)", class: database.SqlClassAdmin},
+		{text: "SELECT RELEASE_ALL_LOCKS()", class: database.SqlClassAdmin},
+		{text: "BACKUP DATABASE * TO 's3://bucket/prefix'", class: database.SqlClassAdmin},
+		{text: "HANDLER t OPEN", class: database.SqlClassAdmin},
+		{text: "UNKNOWNSTATEMENT 1", class: database.SqlClassAdmin},
+	}
+	for _, c := range cases {
+		if got := ClassifyStatement(c.text); got != c.class {
+			t.Errorf("ClassifyStatement(%q) = %s, want %s", c.text, got, c.class)
+		}
+	}
+}
@@ -20,30 +20,22 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/fatih/color"
-	"github.com/mattn/go-shellwords"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/database/mysql"
+	"github.com/wentaojin/tidba/database/sqlite"
 	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/utils/stringutil"
 )
 
-var (
-	allowedSelectQueryCommand  = "SELECT"
-	allowedShowQueryCommand    = "SHOW"
-	allowedUseQueryCommand     = "USE"
-	allowedExplainQueryCommand = "EXPLAIN"
-	allowedQueryCommands       = []string{allowedSelectQueryCommand, allowedShowQueryCommand, allowedUseQueryCommand, allowedExplainQueryCommand}
-)
-
 var cli *CommandLine
 
 // CommandLine represents the interactive command line structure
@@ -56,45 +48,82 @@ type CommandLine struct {
 	activeCluster     string // The name of the cluster where the interactive command line is logged in and active
 	activeClusterConn *mysql.Database
 	activceDbName     string // activce dbName
+	completer         *Completer
+	session           *mysql.Session // the dedicated connection of the sql statements, see getSession
+	queryTimeout      time.Duration  // the timeout of the sql statement set by \timeout, 0 means unlimited
+	result            resultSettings // the output settings of the query results set by \o, \format, \pager, \width and \truncate
+	scriptDepth       int            // the nested depth of the script executed by source, see RunScript
+	recordHistory     bool           // the executed input is recorded in the metadata history, only the interactive command line records
 }
 
-// NewCommandLine creates a new CommandLine instance
-func NewCommandLine(rootCmd *cobra.Command, clusterName string, histFile string) (*CommandLine, error) {
-	prompt := `tidba »»» `
+const (
+	// queryKillTimeout bounds the KILL TIDB QUERY statement issued by Ctrl+C or the query timeout
+	queryKillTimeout = 5 * time.Second
+	// queryKillGracePeriod is the time waiting for the killed statement to return before the connection is closed
+	queryKillGracePeriod = 3 * time.Second
+)
 
+// NewCommandLine creates a new CommandLine instance
+func NewCommandLine(rootCmd *cobra.Command, clusterName string) (*CommandLine, error) {
+	l := newCommandLine(rootCmd)
+	// the history is stored in the metadata database instead of the history file, the complete input is saved by Run
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:            prompt,
-		HistoryFile:       histFile,
-		InterruptPrompt:   "^C",
-		EOFPrompt:         "^D",
-		HistorySearchFold: true,
-		AutoComplete:      readline.NewPrefixCompleter(GenCompleter(rootCmd)...),
+		Prompt:                 l.prompt,
+		HistoryLimit:           historyLoadLimit,
+		DisableAutoSaveHistory: true,
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "^D",
+		HistorySearchFold:      true,
+		AutoComplete:           l.completer,
 	})
 	if err != nil {
 		return nil, err
 	}
+	l.readliner = rl
+	l.recordHistory = true
+	if err := l.loadHistory(); err != nil {
+		return nil, err
+	}
 
+	if err := l.login(clusterName); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewScriptCommandLine creates the CommandLine instance executing the script, the terminal input is not read
+func NewScriptCommandLine(rootCmd *cobra.Command, clusterName string) (*CommandLine, error) {
+	l := newCommandLine(rootCmd)
+	if err := l.login(clusterName); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func newCommandLine(rootCmd *cobra.Command) *CommandLine {
 	l := &CommandLine{
 		mutex:       &sync.RWMutex{},
-		prompt:      prompt,
-		readliner:   rl,
+		prompt:      `tidba »»» `,
 		promptColor: color.New(color.FgGreen),
 		rootCmd:     rootCmd,
 	}
+	l.completer = NewCompleter(l, rootCmd)
+	return l
+}
 
-	// the new prompt is set if and only if the cluster persistentFlags is specified and the cluster is logged in.
-	if clusterName != "" {
-		// login cluster
-		clConn, err := database.Connector.GetDatabase(clusterName)
-		if err != nil {
-			return nil, err
-		}
-		l.activeClusterConn = clConn.(*mysql.Database)
-		l.SetClusterName(clusterName)
-	} else {
+// login logs in the cluster specified by the cluster persistentFlags, the new prompt is set if and only if the cluster is logged in
+func (l *CommandLine) login(clusterName string) error {
+	if clusterName == "" {
 		l.SetDefaultPrompt()
+		return nil
 	}
-	return l, nil
+	clConn, err := database.Connector.GetDatabase(clusterName)
+	if err != nil {
+		return err
+	}
+	l.SetClusterName(clusterName)
+	l.activeClusterConn = clConn.(*mysql.Database)
+	return nil
 }
 
 // SetDefaultPrompt resets the current prompt to the default prompt as
@@ -102,7 +131,9 @@ func NewCommandLine(rootCmd *cobra.Command, clusterName string, histFile string)
 func (l *CommandLine) SetDefaultPrompt() {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
-	l.readliner.SetPrompt(l.promptColor.Sprint(`tidba »»» `))
+	if l.readliner != nil {
+		l.readliner.SetPrompt(l.promptColor.Sprint(`tidba »»» `))
+	}
 	l.activeCluster = ""
 }
 
@@ -127,7 +158,9 @@ func (l *CommandLine) SetClusterPrompt() {
 	}
 	b.WriteString(l.promptColor.Sprint(" »»» "))
 	l.prompt = b.String()
-	l.readliner.SetPrompt(l.prompt)
+	if l.readliner != nil {
+		l.readliner.SetPrompt(l.prompt)
+	}
 }
 
 func (l *CommandLine) GetClusterName() string {
@@ -136,24 +169,53 @@ func (l *CommandLine) GetClusterName() string {
 	return l.activeCluster
 }
 
+func (l *CommandLine) GetDatabaseName() string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.activceDbName
+}
+
 func (l *CommandLine) SetDatabaseName(databaseName string) {
 	l.mutex.RLock()
 	defer l.mutex.RUnlock()
 	l.activceDbName = databaseName
+	l.completer.Invalidate()
 }
 
 func (l *CommandLine) SetClusterName(clusterName string) {
-	l.mutex.RLock()
-	defer l.mutex.RUnlock()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.activeCluster != clusterName {
+		// the session and the connection of the previous cluster are no longer used
+		l.closeSession()
+		l.activeClusterConn = nil
+		l.activceDbName = ""
+	}
 	l.activeCluster = clusterName
+	l.completer.Invalidate()
 }
 
 func (l *CommandLine) ResetClusterName() {
-	l.mutex.RLock()
-	defer l.mutex.RUnlock()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.closeSession()
 	l.activeCluster = ""
 	l.activceDbName = ""
 	l.activeClusterConn = nil
+	l.completer.Invalidate()
+}
+
+// getActiveClusterConn returns the connection of the logged in cluster, the connection created by login is used before the first sql is executed
+func (l *CommandLine) getActiveClusterConn() *mysql.Database {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.activeClusterConn != nil {
+		return l.activeClusterConn
+	}
+	if conn, ok := database.Connector.LoadDatabase(l.activeCluster); ok {
+		return conn.(*mysql.Database)
+	}
+	return nil
 }
 
 // Run starts the interactive command line
@@ -161,58 +223,10 @@ func (l *CommandLine) Run() error {
 	defer l.readliner.Close()
 	printCLIASCIILogo(l.promptColor)
 
-	var cmds []string
-	// each time readline is called, it needs to re-acquire the cobra command
-	getRootCmd := func() *cobra.Command {
-		rootCmd := Cmd(&App{})
-
-		// disableInteractive default hidden command
-		// non-disableInteractive mode disable hidden command, display normal
-		for _, subCmd := range rootCmd.Commands() {
-			if subCmd.Use == "login" || subCmd.Use == "logout" || subCmd.Use == "clear" {
-				subCmd.Hidden = false
-			}
-			cmds = append(cmds, subCmd.Use)
-		}
-
-		// when origin rootCmd persistentFlags changed, loading and new rootCmd setting
-		l.rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
-			if f.Changed {
-				// running mode
-				// tidba -c {clusterName} or tidba -> login -c {clusterName}
-				rootCmd.PersistentFlags().Set(f.Name, f.Value.String())
-			}
-		})
-
-		clusterName, err := rootCmd.PersistentFlags().GetString("cluster")
-		if err != nil {
-			fmt.Printf("the interactive command line get cluster flag failed: %v", err)
-			os.Exit(1)
-		}
-
-		activeClusterName := l.GetClusterName()
-
-		// when the cluster is logged in, the flag parameter clusterName is automatically set so that the cobra command can be executed normally.
-		// tidba -> login -c {clusterName}
-		if strings.EqualFold(clusterName, "") && !strings.EqualFold(activeClusterName, "") {
-			rootCmd.PersistentFlags().Set("cluster", activeClusterName)
-		}
-
-		// hide metadata parameter settings. Only available in non-interactive mode. Cannot be changed in interactive mode.
-		rootCmd.LocalFlags().MarkHidden("metadata")
-		rootCmd.LocalFlags().MarkHidden("disable-interactive")
-
-		return rootCmd
-	}
-
-	var inputBuffer []string
-
-	// define regex match ; or \G
-	re := regexp.MustCompile(`(;|\\G)`)
-
+	e := l.newExecutor(false)
 	for {
 		// determine whether the first character of the first line belongs to command. If not, execute it as a SQL statement.
-		if len(inputBuffer) > 0 {
+		if e.Pending() {
 			l.readliner.SetPrompt("    -> ") // Multi-line input prompt
 		} else {
 			l.SetClusterPrompt()
@@ -221,7 +235,9 @@ func (l *CommandLine) Run() error {
 		line, err := l.readliner.Readline()
 		if err != nil {
 			if err == readline.ErrInterrupt {
-				break
+				// Ctrl+C discards the incomplete statement instead of exiting, type exit / quit or Ctrl+D to exit
+				e.Reset()
+				continue
 			} else if err == io.EOF {
 				break
 			}
@@ -252,140 +268,297 @@ func (l *CommandLine) Run() error {
 			continue
 		}
 
-		rootCmd := getRootCmd()
-
-		if line == "help" {
-			rootCmd.SetArgs([]string{"help"})
-			if err := rootCmd.Execute(); err != nil {
-				fmt.Printf("\n❌ Execute command error: %v\n", err)
-			}
-			// write the complete input to the history
-			if err := l.readliner.SaveHistory(line); err != nil {
-				return fmt.Errorf("save history error: %v", err)
-			}
+		// the error is reported by the executor, the interactive command line continues
+		input, _ := e.Execute(line)
+		if input == "" {
 			continue
 		}
+		// write the complete input to the history
+		if err := l.readliner.SaveHistory(input); err != nil {
+			return fmt.Errorf("save history error: %v", err)
+		}
+	}
+	return nil
+}
 
-		// tidba command
-		dbaCmdSli := strings.Fields(line)
-		if (stringutil.IsContainStringIgnoreCase(dbaCmdSli[0], cmds) && len(inputBuffer) == 0) || (!stringutil.IsContainStringIgnoreCase(dbaCmdSli[0], allowedQueryCommands) && len(inputBuffer) == 0) {
-			// command exec
-			args, err := shellwords.Parse(line)
-			if err != nil {
-				return fmt.Errorf("parse command err: %v", err)
-			}
+// newRootCmd creates the cobra command for each input, the flags of the origin root command and the logged in cluster are inherited
+func (l *CommandLine) newRootCmd() *cobra.Command {
+	rootCmd := Cmd(&App{})
 
-			rootCmd.SetArgs(args)
-			rootCmd.ParseFlags(args)
-			if err := rootCmd.Execute(); err != nil {
-				fmt.Printf("\n❌ Execute command error: %v\n", err)
-			}
+	// disableInteractive default hidden command
+	// non-disableInteractive mode disable hidden command, display normal
+	for _, subCmd := range rootCmd.Commands() {
+		if subCmd.Use == "login" || subCmd.Use == "logout" || subCmd.Use == "clear" || subCmd.Use == "source" {
+			subCmd.Hidden = false
+		}
+	}
 
-			// write the complete input to the history
-			if err := l.readliner.SaveHistory(line); err != nil {
-				return fmt.Errorf("save history error: %v", err)
-			}
+	// when origin rootCmd persistentFlags changed, loading and new rootCmd setting
+	l.rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			// running mode
+			// tidba -c {clusterName} or tidba -> login -c {clusterName}
+			rootCmd.PersistentFlags().Set(f.Name, f.Value.String())
+		}
+	})
+
+	clusterName, err := rootCmd.PersistentFlags().GetString("cluster")
+	if err != nil {
+		fmt.Printf("the interactive command line get cluster flag failed: %v", err)
+		os.Exit(1)
+	}
+
+	activeClusterName := l.GetClusterName()
+
+	// when the cluster is logged in, the flag parameter clusterName is automatically set so that the cobra command can be executed normally.
+	// tidba -> login -c {clusterName}
+	if strings.EqualFold(clusterName, "") && !strings.EqualFold(activeClusterName, "") {
+		rootCmd.PersistentFlags().Set("cluster", activeClusterName)
+	}
+
+	// hide metadata parameter settings. Only available in non-interactive mode. Cannot be changed in interactive mode.
+	rootCmd.LocalFlags().MarkHidden("metadata")
+	rootCmd.LocalFlags().MarkHidden("disable-interactive")
+	rootCmd.LocalFlags().MarkHidden("execute")
+	rootCmd.LocalFlags().MarkHidden("file")
+	rootCmd.LocalFlags().MarkHidden("stop-on-error")
+
+	return rootCmd
+}
+
+// executeStatements executes the complete sql statements split by the lexer on the logged in cluster, the statements
+// after the failed statement are still executed unless stopOnError is set
+func (l *CommandLine) executeStatements(stmts []Statement, stopOnError bool) error {
+	if l.GetClusterName() == "" {
+		err := fmt.Errorf("the cluster_name cannot be empty, if you need to execute the sql command, please log in to the cluster in advance by running [login -c {clusterName}]. Otherwise, run the [help] command to view")
+		fmt.Printf("\n❌ Execute command error: %v\n", err)
+		return err
+	}
+
+	// the sql policy is loaded for each input, so that the policy changed by [meta update] takes effect immediately
+	policy, err := l.getClusterSqlPolicy()
+	if err != nil {
+		fmt.Printf("\n❌ Execute command error: %v\n", err)
+		return err
+	}
+
+	// database connection not init
+	if l.activeClusterConn == nil {
+		clConn, err := database.Connector.GetDatabase(l.activeCluster)
+		if err != nil {
+			fmt.Printf("\n❌ Execute command error: %v\n", err)
+			return err
+		}
+		l.activeClusterConn = clConn.(*mysql.Database)
+	}
+
+	var lastErr error
+	for _, g := range stmts {
+		var (
+			rows  int
+			err   error
+			stime = time.Now()
+			h     = &sqlite.History{ClusterName: l.GetClusterName(), DbName: l.GetDatabaseName(), Statement: g.Text, Delimiter: g.Delimiter}
+		)
+		queryCmd := StatementKeywords(g.Text, 2)
+		if g.Text == "" || len(queryCmd) == 0 {
+			err = fmt.Errorf("no query specified")
+			fmt.Println("ERROR: No query specified")
+		} else if class := ClassifyStatement(g.Text); !stringutil.IsContainString(class, policy) {
+			// to avoid operation and maintenance security, only the statement classes allowed by the cluster sql policy are executed
+			err = fmt.Errorf("operation and maintenance security control, the sql policy of the cluster [%s] only allows the [%s] statements to be executed, current statement [%s] is classified as [%s] and not allowed, the policy can be changed by the sqlPolicy of [meta update]", l.activeCluster, strings.Join(policy, "/"), queryCmd[0], class)
+			fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
+		} else {
+			rows, err = l.executeStatement(g, class, queryCmd)
+		}
+		if g.Text != "" {
+			l.saveHistory(h, time.Since(stime), rows, err)
+		}
+		if err == nil {
 			continue
 		}
+		lastErr = err
+		if stopOnError {
+			return lastErr
+		}
+	}
+	return lastErr
+}
 
-		// append buffer
-		inputBuffer = append(inputBuffer, line)
+// executeStatement executes the statement on the session of the logged in cluster, the running statement is killed
+// by Ctrl+C or the \timeout setting, and the elapsed time until the statement is terminated is shown. The returned
+// rows is the row count of the query result
+func (l *CommandLine) executeStatement(g Statement, class string, queryCmd []string) (int, error) {
+	session, err := l.getSession()
+	if err != nil {
+		fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
+		return 0, err
+	}
 
-		// if the current line ends with a semicolon, the input is complete
-		if strings.HasSuffix(line, ";") || strings.HasSuffix(line, "\\G") {
-			if l.activeCluster == "" {
-				fmt.Printf("\n❌ Execute command error: the cluster_name cannot be empty, if you need to execute the [%s] sql command, please log in to the cluster in advance by running [login -c {clusterName}]. Otherwise, run the [help] command to view.\n", strings.Join(allowedQueryCommands, "/"))
-				inputBuffer = nil
-				continue
-			}
-			fullInput := strings.Join(inputBuffer, " ")
+	// the write, ddl and admin statements are recorded in the operation audit trail like the kill, runaway and sql bind commands,
+	// the exact statement executed is collected by the recorder of the cluster database
+	var auditor *model.Auditor
+	if class != database.SqlClassRead {
+		auditor, err = model.AuditStart(context.Background(), l.GetClusterName(), "statement "+class, g.Text+g.Delimiter)
+		if err != nil {
+			fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
+			return 0, err
+		}
+	}
 
-			// write the complete input to the history
-			if err := l.readliner.SaveHistory(fullInput); err != nil {
-				return fmt.Errorf("save history error: %v", err)
-			}
+	var (
+		cols []string
+		res  []map[string]string
+	)
+	isUse := strings.EqualFold(queryCmd[0], "USE")
+	stime := time.Now()
+	endpoint := l.activeClusterConn.ActiveEndpoint()
+	interrupted, err := l.runInterruptible(session, func(ctx context.Context) error {
+		var err error
+		// the read statement runs inside the read only transaction, the write slipped through the classification is refused and rolled back
+		if class == database.SqlClassRead && !isUse {
+			cols, res, err = session.ReadOnlyQuery(ctx, g.Text)
+		} else {
+			cols, res, err = session.ExecQuery(ctx, g.Text)
+		}
+		return err
+	})
+	l.printEndpointFailover(endpoint)
+	if interrupted != "" {
+		err = fmt.Errorf("query %s", interrupted)
+	}
+	if auditor != nil {
+		err = finishAudited(context.Background(), auditor, err)
+	}
+	if interrupted != "" {
+		fmt.Printf("❌ Query %s, terminated after %.2f sec\n\n", interrupted, time.Since(stime).Seconds())
+		return 0, err
+	}
+	if err != nil {
+		fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
+		return 0, err
+	}
 
-			groups, found := splitStringWithDelimiter(re, fullInput)
-			if !found {
-				// No ; or \\G found
-				continue
-			}
+	if isUse {
+		if len(queryCmd) > 1 {
+			l.SetDatabaseName(queryCmd[1])
+		}
+		l.SetClusterPrompt()
+		fmt.Fprintln(os.Stdout, "\n✅ Database changed")
+		return 0, nil
+	}
+	if len(cols) == 0 {
+		fmt.Printf("Query OK (%.2f sec)\n\n", time.Since(stime).Seconds())
+		return 0, nil
+	}
+	if err := l.writeResult(cols, res, g.Delimiter, time.Since(stime).Seconds()); err != nil {
+		fmt.Printf("\n❌ Format result error: %v\n", err)
+		return len(res), err
+	}
+	return len(res), nil
+}
 
-			// database connection not init
-			if l.activeClusterConn == nil {
-				clConn, err := database.Connector.GetDatabase(l.activeCluster)
-				if err != nil {
-					return err
-				}
-				l.activeClusterConn = clConn.(*mysql.Database)
-			}
+// runInterruptible runs the statement in the background, Ctrl+C or the query timeout kills the running statement by KILL TIDB QUERY,
+// and the context is canceled if the statement is not terminated within the grace period or Ctrl+C is pressed again.
+// The returned reason is not empty when the statement is interrupted
+func (l *CommandLine) runInterruptible(session *mysql.Session, fn func(ctx context.Context) error) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	var timeoutC, graceC <-chan time.Time
+	timeout := l.getQueryTimeout()
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
 
-			for _, g := range groups {
-				if g.Value == "" {
-					fmt.Println("ERROR: No query specified")
-					continue
-				}
-				queryCmd := strings.Fields(skipComments(g.Value))
-				queryType := queryCmd[0]
-				// to avoid operation and maintenance security, only select / show / use sql command are allowed...
-				isAllowed := false
-				if strings.EqualFold(queryType, allowedSelectQueryCommand) {
-					isAllowed = true
-				}
-				if strings.EqualFold(queryType, allowedShowQueryCommand) {
-					isAllowed = true
-				}
-				if strings.EqualFold(queryType, allowedUseQueryCommand) {
-					isAllowed = true
-					_, err := l.activeClusterConn.ExecContext(context.Background(), g.Value)
-					if err != nil {
-						fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
-						inputBuffer = nil
-						continue
-					}
-					l.SetDatabaseName(strings.Trim(queryCmd[1], ";"))
-					l.SetClusterPrompt()
-					fmt.Fprintln(os.Stdout, "\n✅ Database changed")
-					// clear the buffer and prepare to accept the next command
-					inputBuffer = nil
-					continue
-				}
-				if strings.EqualFold(queryType, allowedExplainQueryCommand) {
-					isAllowed = true
-				}
-
-				if isAllowed && !strings.EqualFold(queryType, allowedUseQueryCommand) {
-					stime := time.Now()
-					cols, res, err := l.activeClusterConn.GeneralQuery(context.Background(), g.Value)
-					if err != nil {
-						fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", err)
-						inputBuffer = nil
-						continue
-					}
-					if g.Delimiter == "\\G" {
-						if err := model.QueryResultFormatWithoutTableStyle(cols, res, time.Since(stime).Seconds()); err != nil {
-							fmt.Printf("\n❌ Format result error: %v", err)
-							inputBuffer = nil
-							continue
-						}
-					} else {
-						if err := model.QueryResultFormatTableStyle(cols, res, time.Since(stime).Seconds()); err != nil {
-							fmt.Printf("\n❌ Format result error: %v", err)
-							inputBuffer = nil
-							continue
-						}
-					}
-				} else {
-					fmt.Printf("❌ Execute query failed!\n\nquery error content:\n%v\n\n", fmt.Errorf("operation and maintenance security control, only [%s] sql commands are allowed to be executed, current command [%s] are not allowed", strings.Join(allowedQueryCommands, "/"), queryType))
-				}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
 
+	var reason string
+	for {
+		select {
+		case err := <-done:
+			return reason, err
+		case <-sigChan:
+			if reason != "" {
+				cancel()
+				continue
+			}
+			reason = "canceled by Ctrl+C"
+		case <-timeoutC:
+			if reason != "" {
+				continue
 			}
+			reason = fmt.Sprintf("exceeded the timeout [%v]", timeout)
+		case <-graceC:
+			// the connection is closed by the driver when the context is canceled
+			cancel()
+			continue
+		}
 
-			// clear the buffer and prepare to accept the next command
-			inputBuffer = nil
+		kctx, kcancel := context.WithTimeout(context.Background(), queryKillTimeout)
+		if err := session.KillQuery(kctx); err != nil {
+			fmt.Printf("\n⚠️  %v, the connection is closed\n", err)
+			cancel()
 		}
+		kcancel()
+		graceC = time.After(queryKillGracePeriod)
+	}
+}
+
+// getSession returns the dedicated session of the logged in cluster, the broken session is replaced by the new session
+// and the database changed by USE is restored
+func (l *CommandLine) getSession() (*mysql.Session, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.session != nil && !l.session.Broken() {
+		return l.session, nil
+	}
+	if l.session != nil {
+		l.session.Close()
+		l.session = nil
+	}
+	session, err := l.activeClusterConn.NewSession(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if l.activceDbName != "" {
+		if _, _, err := session.ExecQuery(context.Background(), fmt.Sprintf("USE `%s`", strings.ReplaceAll(l.activceDbName, "`", "``"))); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	l.session = session
+	return session, nil
+}
+
+// closeSession releases the session connection, it is called with the mutex held
+func (l *CommandLine) closeSession() {
+	if l.session != nil {
+		l.session.Close()
+		l.session = nil
+	}
+}
+
+func (l *CommandLine) getQueryTimeout() time.Duration {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.queryTimeout
+}
+
+// printEndpointFailover reports the active tidb endpoint switched by the connection failover
+func (l *CommandLine) printEndpointFailover(endpoint string) {
+	if active := l.activeClusterConn.ActiveEndpoint(); active != endpoint {
+		fmt.Printf("\n⚠️  the tidb endpoint [%s] is unavailable, the connection failover to the endpoint [%s]\n\n", endpoint, active)
 	}
-	return nil
 }
 
 func printCLIASCIILogo(c *color.Color) {
@@ -403,7 +576,12 @@ func printCLIASCIILogo(c *color.Color) {
 	fmt.Println(newColor(`Type 'exit' or 'quit' for command exit. Type 'clear' to clear the current input statement.`))
 	fmt.Println(newColor(`Note:                                                        `))
 	fmt.Println(newColor(`-    the logged in cluster supports the execution of tidba operation commands, type 'help' for commands help.`))
-	fmt.Println(newColor(`-    the logged in cluster supports the execution of SQL commands, options: [Select, Show, Use, Explain] other commands are not supported.`))
+	fmt.Println(newColor(`-    the logged in cluster supports the execution of SQL commands, the statement classes [read, write, ddl, admin] allowed are configured by the cluster sql policy, read only by default.`))
+	fmt.Println(newColor(`-    the read SQL statements run inside the READ ONLY transaction refusing the write, tidb_enable_noop_functions is turned on for the session to begin the READ ONLY transaction.`))
+	fmt.Println(newColor(`-    press Ctrl+C to cancel the running SQL statement, type '\timeout {duration}' to set the SQL statement timeout.`))
+	fmt.Println(newColor(`-    type 'source {file}' to execute the tidba commands and SQL statements of the script file in sequence.`))
+	fmt.Println(newColor(`-    type '\history' to show the history of the logged in cluster, type '\rerun {id}' to execute the history again, press Ctrl+R to search the history.`))
+	fmt.Println(newColor(`-    type '\watch {seconds} {statement | command}' to execute the read SQL statement or the tidba command on the interval, press Ctrl+C to stop.`))
 	fmt.Println(newColor(`                                                              `))
 
 }
@@ -422,104 +600,58 @@ func printHelpASCIILogo(c *color.Color) {
 	fmt.Println(newColor(`                                                    /___/  `))
 }
 
-func GenCompleter(cmd *cobra.Command) []readline.PrefixCompleterInterface {
-	pc := []readline.PrefixCompleterInterface{}
-	if len(cmd.Commands()) != 0 {
-		for _, v := range cmd.Commands() {
-			if v.HasFlags() {
-				flagsPc := []readline.PrefixCompleterInterface{}
-				flagUsages := strings.Split(strings.Trim(v.Flags().FlagUsages(), " "), "\"n")
-				for i := 0; i < len(flagUsages)-1; i++ {
-					flagsPc = append(flagsPc, readline.PcItem(strings.Split(strings.Trim(flagUsages[i], " "), " ")[0]))
-				}
-				flagsPc = append(flagsPc, GenCompleter(v)...)
-				pc = append(pc, readline.PcItem(strings.Split(v.Use, " ")[0], flagsPc...))
-
-			} else {
-				pc = append(pc, readline.PcItem(strings.Split(v.Use, " ")[0], GenCompleter(v)...))
-			}
-		}
+// getClusterSqlPolicy returns the statement classes allowed by the sql policy of the logged in cluster
+func (l *CommandLine) getClusterSqlPolicy() ([]string, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
 	}
-	return pc
-}
-
-// Define a structure to store groups and their corresponding separators
-// Used to sql command
-type Group struct {
-	Value     string // group value
-	Delimiter string // separators (; 或者 \G)
+	c, err := db.(*sqlite.Database).GetCluster(context.Background(), l.GetClusterName())
+	if err != nil {
+		return nil, err
+	}
+	return database.ResolveClusterSqlPolicy(c), nil
 }
 
-func splitStringWithDelimiter(re *regexp.Regexp, input string) ([]Group, bool) {
-	matches := re.FindAllStringIndex(input, -1)
-
-	if len(matches) == 0 {
-		// No ; or \\G found
-		return nil, false
+// isQueryInput returns whether the input line starts the sql statement instead of the tidba command, the line starting with
+// the comment or the DELIMITER command is handled by the sql lexer. The tidba command name that is also the sql keyword, e.g.
+// kill and split, is the tidba command only if the line is parsed as the exact tidba command path, e.g. [kill sql] is the tidba
+// command, and [kill query 1] and [split table t ...] are the sql statements in any case
+func isQueryInput(line string, rootCmd *cobra.Command) bool {
+	keywords := StatementKeywords(line, 1)
+	if len(keywords) == 0 {
+		return true
 	}
-
-	var result []Group
-	lastIndex := 0
-	for _, match := range matches {
-		// Extract the part before the match
-		result = append(result, Group{
-			Value:     input[lastIndex:match[0]],
-			Delimiter: input[match[0]:match[1]], // 记录分隔符
-		})
-		lastIndex = match[1] // Update lastIndex to the matching index
+	if strings.EqualFold(keywords[0], "DELIMITER") {
+		return true
 	}
-
-	// Handle the last part (after the last delimiter)
-	lastValue := strings.TrimSpace(input[lastIndex:])
-	if lastValue != "" {
-		result = append(result, Group{
-			Value:     lastValue,
-			Delimiter: "", // No delimiter for the last part
-		})
+	fields := strings.Fields(line)
+	var cmd *cobra.Command
+	for _, sub := range rootCmd.Commands() {
+		if sub.Name() == fields[0] || stringutil.IsContainString(fields[0], sub.Aliases) {
+			cmd = sub
+			break
+		}
 	}
-
-	return result, true
+	if cmd == nil {
+		return isSqlStatementKeyword(keywords[0])
+	}
+	if !isSqlStatementKeyword(keywords[0]) {
+		return false
+	}
+	return !isCommandPath(cmd, fields[1:])
 }
 
-// Skip comments (-- for single-line and /* */ for block comments)
-func skipComments(input string) string {
-	var result strings.Builder
-	inBlockComment := false
-	lines := strings.Split(input, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if inBlockComment {
-			// Handle the end of a block comment */
-			if idx := strings.Index(line, "*/"); idx != -1 {
-				inBlockComment = false
-				line = line[idx+2:] // Skip the block comment part
-			} else {
-				continue // The entire line is inside a block comment, skip it
-			}
-		}
-
-		// Handle single-line comments --
-		if idx := strings.Index(line, "--"); idx != -1 {
-			line = line[:idx] // Keep only the part before --
-		}
-
-		// Handle block comments /*
-		if idx := strings.Index(line, "/*"); idx != -1 {
-			endIdx := strings.Index(line, "*/")
-			if endIdx == -1 {
-				// Block comment spans multiple lines
-				inBlockComment = true
-				line = line[:idx] // Keep only the part before /*
-			} else {
-				// Block comment ends on the same line
-				line = line[:idx] + line[endIdx+2:]
-			}
+// isCommandPath returns whether the arguments following the command name continue the tidba command path, that is the
+// command has no subcommands, or the next argument is the flag or the subcommand of the command
+func isCommandPath(cmd *cobra.Command, args []string) bool {
+	if !cmd.HasSubCommands() || len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return true
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == args[0] || stringutil.IsContainString(args[0], sub.Aliases) {
+			return true
 		}
-
-		// Append the processed line to the result
-		result.WriteString(line)
 	}
-
-	return strings.TrimSpace(result.String())
+	return false
 }
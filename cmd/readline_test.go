@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+)
+
+func TestIsQueryInput(t *testing.T) {
+	rootCmd := Cmd(&App{})
+
+	cases := []struct {
+		line  string
+		query bool
+	}{
+		// the tidba commands
+		{line: "topsql elapsed --nearly 10", query: false},
+		{line: "meta list", query: false},
+		{line: "login -c tidb-test", query: false},
+		// the sql statements
+		{line: "SELECT 1;", query: true},
+		{line: "select * from t;", query: true},
+		{line: "show databases;", query: true},
+		{line: "/* comment */ SELECT 1;", query: true},
+		{line: "-- comment", query: true},
+		{line: "DELIMITER //", query: true},
+		{line: "delimiter ;", query: true},
+		// kill is the tidba command and the sql keyword
+		{line: "kill", query: false},
+		{line: "kill sql --sql-digest abc", query: false},
+		{line: "kill user --user root", query: false},
+		{line: "kill --help", query: false},
+		{line: "kill query 123;", query: true},
+		{line: "kill 123;", query: true},
+		{line: "kill tidb query 123;", query: true},
+		{line: "kill connection 123;", query: true},
+		{line: "KILL QUERY 123;", query: true},
+		{line: "KILL SQL", query: true},
+		// split is the tidba command and the sql keyword
+		{line: "split", query: false},
+		{line: "split range --database db --tables t", query: false},
+		{line: "split key --database db --tables t", query: false},
+		{line: "split sampling --database db --tables t", query: false},
+		{line: "split estimate --database db --tables t", query: false},
+		{line: "split -h", query: false},
+		{line: "split table t between (0) and (1000000) regions 16;", query: true},
+		{line: "split table t index idx by ('a'), ('b');", query: true},
+		{line: "split region for table t by (100);", query: true},
+		{line: "SPLIT TABLE t BETWEEN (0) AND (100) REGIONS 4;", query: true},
+	}
+	for _, c := range cases {
+		if got := isQueryInput(c.line, rootCmd); got != c.query {
+			t.Errorf("isQueryInput(%q) = %v, want %v", c.line, got, c.query)
+		}
+	}
+}
+
+// TestIsQueryInputKeywordCommands checks that every tidba command named as the sql keyword leaves the sql statement to the session
+func TestIsQueryInputKeywordCommands(t *testing.T) {
+	rootCmd := Cmd(&App{})
+	for _, sub := range rootCmd.Commands() {
+		if !isSqlStatementKeyword(sub.Name()) {
+			continue
+		}
+		if isQueryInput(sub.Name()+" --help", rootCmd) {
+			t.Errorf("isQueryInput(%q) = true, want the tidba command", sub.Name()+" --help")
+		}
+		if !sub.HasSubCommands() {
+			continue
+		}
+		if line := sub.Name() + " unknown_object 1;"; !isQueryInput(line, rootCmd) {
+			t.Errorf("isQueryInput(%q) = false, want the sql statement", line)
+		}
+	}
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/tidwall/pretty"
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/logger"
-	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/model/region"
 	"github.com/wentaojin/tidba/utils/stringutil"
 )
@@ -98,7 +97,7 @@ func (a *AppRegionHotspot) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(region.NewRegionQueryModel(
+			p := newProgram(region.NewRegionQueryModel(
 				a.clusterName,
 				a.database,
 				a.stores,
@@ -120,9 +119,8 @@ func (a *AppRegionHotspot) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if lModel.Msgs != nil && len(lModel.Msgs.([]*region.QueriedRespMsg)) > 0 {
-				fmt.Println("cluster hotspot region content:")
 				for _, m := range lModel.Msgs.([]*region.QueriedRespMsg) {
-					if err := model.QueryResultFormatTableStyle(m.Columns, m.Results); err != nil {
+					if err := a.output.Rows("cluster hotspot region content", m.Columns, m.Results); err != nil {
 						return err
 					}
 				}
@@ -166,7 +164,7 @@ func (a *AppRegionLeader) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(region.NewRegionQueryModel(
+			p := newProgram(region.NewRegionQueryModel(
 				a.clusterName,
 				a.database,
 				a.stores,
@@ -188,9 +186,8 @@ func (a *AppRegionLeader) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if lModel.Msgs != nil && len(lModel.Msgs.([]*region.QueriedRespMsg)) > 0 {
-				fmt.Println("cluster region leader distributed content:")
 				for _, m := range lModel.Msgs.([]*region.QueriedRespMsg) {
-					if err := model.QueryResultFormatTableStyle(m.Columns, m.Results); err != nil {
+					if err := a.output.Rows("cluster region leader distributed content", m.Columns, m.Results); err != nil {
 						return err
 					}
 				}
@@ -235,9 +232,9 @@ func (a *AppRegionReplica) Cmd() *cobra.Command {
 			var opts []tea.ProgramOption
 
 			if a.daemon || !isatty.IsTerminal(os.Stdout.Fd()) {
-				// If we're in daemon mode don't render the TUI
+				// If we're in daemon mode don't render the TUI, the progress log is not written to the machine readable results
 				opts = []tea.ProgramOption{tea.WithoutRenderer()}
-				logger.NewLoggerConsoleOutput(true)
+				logger.NewLoggerConsoleOutput(a.output.IsTable())
 				defer logger.Sync()
 			} else {
 				// If we're in TUI mode, discard log output
@@ -245,7 +242,7 @@ func (a *AppRegionReplica) Cmd() *cobra.Command {
 				defer logger.Sync()
 			}
 
-			p := tea.NewProgram(
+			p := newProgram(
 				region.NewRegionQueryModel(
 					a.clusterName,
 					a.database,
@@ -273,31 +270,30 @@ func (a *AppRegionReplica) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*region.MajorityResp)
 				if reflect.DeepEqual(resp, &region.MajorityResp{}) {
-					fmt.Println("the cluster region replica peers normal, not found major down peers, please ignore and skip")
+					a.output.Notice("the cluster region replica peers normal, not found major down peers, please ignore and skip")
 					return nil
 				}
 
-				fmt.Println("cluster region down majority content:")
-
 				var rows []interface{}
 				rows = append(rows, resp.ReplicaCounts)
 				rows = append(rows, resp.RegionCounts)
 				rows = append(rows, resp.DownRegionQueryCounts)
 				rows = append(rows, resp.DownRegionEffectiveCounts)
 
-				if err := model.QueryResultFormatTableStyleWithRowsArray(
+				if err := a.output.Table("cluster region down majority content",
 					[]string{"cluster replica count", "total region count", "down region query count", "down region effective count"},
 					append([][]interface{}{}, rows)); err != nil {
 					return err
 				}
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.TableHeader, resp.TableRows); err != nil {
+				if err := a.output.Table("cluster region down majority regions content", resp.TableHeader, resp.TableRows); err != nil {
 					return err
 				}
 
 				if len(resp.DownRegionPanics) > 0 {
-					fmt.Println("cluster region down majority panics content:")
+					a.output.Value("cluster region down majority panics content", resp.DownRegionPanics)
+					a.output.Println("cluster region down majority panics content:")
 					for _, rs := range stringutil.ArrayStringGroups(resp.DownRegionPanics, 10) {
-						fmt.Println(strings.Join(rs, ","))
+						a.output.Println(strings.Join(rs, ","))
 					}
 				}
 			}
@@ -342,7 +338,7 @@ func (a *AppRegionQuery) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(region.NewRegionQueryModel(
+			p := newProgram(region.NewRegionQueryModel(
 				a.clusterName,
 				a.database,
 				a.stores,
@@ -364,14 +360,15 @@ func (a *AppRegionQuery) Cmd() *cobra.Command {
 				return lModel.Error
 			}
 			if lModel.Msgs != nil && len(lModel.Msgs.([]*region.SingleRegion)) > 0 {
-				fmt.Println("cluster regions query content:")
+				a.output.Value("cluster regions query content", lModel.Msgs)
+				a.output.Println("cluster regions query content:")
 				for _, rs := range lModel.Msgs.([]*region.SingleRegion) {
-					fmt.Println("------")
+					a.output.Println("------")
 					jsonByte, err := json.Marshal(rs)
 					if err != nil {
 						return err
 					}
-					fmt.Printf("%s", pretty.Pretty(jsonByte))
+					a.output.Printf("%s", pretty.Pretty(jsonByte))
 				}
 			}
 			return nil
@@ -18,15 +18,22 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model"
+	"github.com/wentaojin/tidba/utils/credential"
+	"github.com/wentaojin/tidba/utils/secret"
 	"github.com/wentaojin/tidba/utils/stringutil"
 	"github.com/wentaojin/tidba/utils/version"
 )
@@ -37,10 +44,23 @@ func init() {
 
 type App struct {
 	metadata           string
+	masterKeyFile      string
+	allowExecCreds     bool
 	clusterName        string
+	endpoint           string
+	group              string
+	clusters           []string
+	fanoutConcurrency  int
 	disableInteractive bool
 	version            bool
-	history            string
+	execute            []string
+	file               string
+	stopOnError        bool
+	outputFormat       string
+	outputFile         string
+	output             *model.Output
+	watch              int
+	watchLog           string
 }
 
 /*
@@ -66,67 +86,100 @@ func (a *App) Cmd() *cobra.Command {
 		Use:  "tidba",
 		Long: "TiDBA (tidba) is a CLI for tidb distributed data dba operation and maintenance, which can quickly analyze, diagnose and troubleshoot problems.",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			dir, err := homedir.Expand(a.metadata)
+			var err error
+			a.output, err = model.NewOutput(a.outputFormat, a.outputFile, strings.Join(strings.Fields(cmd.CommandPath())[1:], " "), a.clusterName)
 			if err != nil {
 				return err
 			}
+			// the terminal ui is disabled when the results are machine readable or written without terminal, e.g. the script and the pipe
+			headlessProgram = !a.output.IsTable() || !isatty.IsTerminal(os.Stdout.Fd()) || (cli != nil && cli.readliner == nil)
+
+			metaCfg, err := database.NewMetadataConfig(a.metadata)
+			if err != nil {
+				return err
+			}
+			// the local directory stores the master key file, the remote metadata database uses the default directory
+			dir := metaCfg.DSN
+			if metaCfg.DbType != database.DatabaseTypeSqlite {
+				if dir, err = homedir.Expand(database.DefaultMetadataDir); err != nil {
+					return err
+				}
+			}
 			err = stringutil.PathNotExistOrCreate(dir)
 			if err != nil {
 				return err
 			}
 
 			if _, ok := database.Connector.LoadDatabase(database.DefaultSqliteClusterName); !ok {
-				connector, err := database.CreateConnector(context.Background(), &database.ClusterConfig{
-					DbType: database.DatabaseTypeSqlite,
-					DSN:    dir,
-				})
+				connector, err := database.CreateMetadataConnector(context.Background(), metaCfg)
 				if err != nil {
 					return err
 				}
 				database.Connector.AddDatabase(database.DefaultSqliteClusterName, connector)
 			}
 
-			// license skip Limits
-			if cmd.Use == "license" || cmd.Use == "generate" || cmd.Use == "activate" {
-				return nil
+			// the cluster password encryption master key, the environment variable takes precedence over the key file
+			keyFile := a.masterKeyFile
+			if keyFile == "" {
+				keyFile = filepath.Join(dir, secret.DefaultKeyFileName)
+			} else if keyFile, err = homedir.Expand(keyFile); err != nil {
+				return err
 			}
+			secret.DefaultKeyring.SetKeyFile(keyFile)
+			credential.AllowExec = a.allowExecCreds
 
-			// if built with -tags nolicense, skip license verification entirely (for tests)
-			if !IsLicenseCheckEnabled() {
-				a.history = fmt.Sprintf("%s/tidba_history", dir)
-				return nil
+			if a.watch < 0 {
+				return fmt.Errorf("the flag --watch [%d] must be greater than 0", a.watch)
 			}
-
-			macAddr, err := getDefaultMACAddress()
-			if err != nil {
-				return err
+			if a.watchLog != "" && a.watch == 0 {
+				return fmt.Errorf("the flag --watch-log must be used with the flag --watch {seconds}")
 			}
-			db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
-			if err != nil {
-				return err
+			// the subcommand is executed repeatedly in the current process, the watch execution handles the fan-out execution
+			if a.watch > 0 {
+				return a.prepareWatch(cmd)
 			}
-			lic, err := db.(*sqlite.Database).GetLicense(context.Background(), macAddr)
-			if err != nil {
-				return err
+
+			// the subcommand is executed on the selected clusters concurrently, the license is verified once before the execution
+			if (a.group != "" || len(a.clusters) > 0) && !isClusterSelectCommand(cmd) {
+				if err := verifyLicense(); err != nil {
+					return err
+				}
+				return a.prepareFanout(cmd)
 			}
-			if reflect.DeepEqual(lic, &sqlite.License{}) {
-				return fmt.Errorf("the software is not authorized. please use [tidba license generate --user {userName} --day {licenseDays}] to generate the authorization code, and then contact the author to obtain the authorization key to activate the software")
+			if a.group != "" {
+				return fmt.Errorf("the command [%s] does not support the flag --group, the clusters are selected by the flag --clusters", cmd.CommandPath())
 			}
 
-			expir, err := time.ParseInLocation("2006-01-02 15:04:05", lic.ExpireTime, time.Local)
-			if err != nil {
-				return err
+			if a.endpoint != "" {
+				if a.clusterName == "" {
+					return fmt.Errorf("the flag --endpoint must be used with the flag -c {clusterName}")
+				}
+				if _, _, err := database.SplitClusterEndpoint(a.endpoint); err != nil {
+					return err
+				}
 			}
-			if time.Now().In(time.Local).After(expir) {
-				return fmt.Errorf("the license has expired and cannot be used, please contact the author for reactivation")
+			database.Connector.PinEndpoint(a.clusterName, a.endpoint)
+
+			// license skip Limits
+			if cmd.Use == "license" || cmd.Use == "generate" || cmd.Use == "activate" {
+				return nil
 			}
-			// verify MAC
-			if macAddr != lic.MacAddress {
-				return fmt.Errorf("the server address does not match. please use it on the original activation server")
+
+			// resolve the master key before the terminal ui is started, avoid the passphrase prompt being swallowed
+			if a.clusterName != "" && cmd.Parent() != nil && cmd.Parent().Use != "meta" && cmd.Parent().Use != "audit" && cmd.Parent().Use != "snippet" && cmd.Parent().Use != "history" {
+				if err := database.PrepareClusterMasterKey(context.Background(), a.clusterName); err != nil {
+					return err
+				}
 			}
 
-			a.history = fmt.Sprintf("%s/tidba_history", dir)
-			return nil
+			return verifyLicense()
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			// the root command starts the interactive command line or the script, the results are flushed by the subcommands
+			if !cmd.HasParent() {
+				return nil
+			}
+			return a.output.Flush()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if a.version {
@@ -134,12 +187,13 @@ func (a *App) Cmd() *cobra.Command {
 				return nil
 			}
 
+			if len(a.execute) > 0 || a.file != "" {
+				return a.runScript(cmd)
+			}
+
 			if !a.disableInteractive {
 				var err error
-				cli, err = NewCommandLine(
-					cmd,
-					a.clusterName,
-					a.history)
+				cli, err = NewCommandLine(cmd, a.clusterName)
 				if err != nil {
 					return err
 				}
@@ -151,10 +205,23 @@ func (a *App) Cmd() *cobra.Command {
 		SilenceUsage:  true,
 	}
 
-	rootCmd.PersistentFlags().StringVarP(&a.metadata, "metadata", "M", "~/.tidba", "location of the tidba metadata database")
+	rootCmd.PersistentFlags().StringVarP(&a.metadata, "metadata", "M", database.DefaultMetadataDir, "location of the tidba metadata database, the local sqlite directory or the shared mysql / tidb schema url mysql://{user}:{password}@{host}:{port}/{schema}")
+	rootCmd.PersistentFlags().StringVar(&a.masterKeyFile, "master-key-file", "", "location of the master key file used to encrypt cluster passwords (default: {metadata}/tidba.key, env TIDBA_MASTER_KEY takes precedence)")
+	rootCmd.PersistentFlags().BoolVar(&a.allowExecCreds, "allow-exec-credentials", false, "allow the exec: password provider of the cluster metadata to execute its command by the local shell, disabled by default")
 	rootCmd.PersistentFlags().StringVarP(&a.clusterName, "cluster", "c", "", "configure the cluster name that tidba needs to operate")
+	rootCmd.PersistentFlags().StringVar(&a.endpoint, "endpoint", "", "pin the cluster database connection to the specified tidb endpoint {host}:{port}, the failover endpoints are not used")
+	rootCmd.PersistentFlags().StringVar(&a.group, "group", "", "execute the subcommand concurrently on all clusters of the cluster group")
+	rootCmd.PersistentFlags().StringSliceVar(&a.clusters, "clusters", nil, "execute the subcommand concurrently on the specified clusters, separated by comma, meta export / import / sync only operate the metadata of the specified clusters")
+	rootCmd.PersistentFlags().IntVar(&a.fanoutConcurrency, "fanout-concurrency", 5, "the maximum number of clusters executed concurrently by the flag --group or --clusters")
+	rootCmd.PersistentFlags().StringVarP(&a.outputFormat, "output-format", "F", model.OutputFormatTable, "the output format of the command results, options: table / json / yaml / csv / xlsx, the terminal ui is disabled except the table format")
+	rootCmd.PersistentFlags().StringVar(&a.outputFile, "output-file", "", "write the command results to the file instead of the terminal, required by the xlsx output format")
+	rootCmd.PersistentFlags().IntVar(&a.watch, "watch", 0, "execute the subcommand every {seconds} until Ctrl+C is pressed, the results are refreshed in place and the changed cells are highlighted")
+	rootCmd.PersistentFlags().StringVar(&a.watchLog, "watch-log", "", "append the results of each sample of the flag --watch to the file, one json object per line")
 	rootCmd.Flags().BoolVarP(&a.disableInteractive, "disable-interactive", "d", false, "interactive for the tidba application (default: interactive mode)")
 	rootCmd.Flags().BoolVarP(&a.version, "version", "v", false, "version for the tidba application")
+	rootCmd.Flags().StringArrayVarP(&a.execute, "execute", "e", nil, "execute the tidba commands and sql statements in sequence and exit, the flag can be specified multiple times, e.g. -e \"topsql elapsed\" -e \"SELECT 1;\"")
+	rootCmd.Flags().StringVarP(&a.file, "file", "f", "", "execute the tidba commands and sql statements of the script file in sequence and exit")
+	rootCmd.Flags().BoolVar(&a.stopOnError, "stop-on-error", false, "stop the flag --execute or --file script at the first failed step, the rest steps are still executed by default")
 
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		fgGreen := color.New(color.FgGreen)
@@ -164,6 +231,44 @@ func (a *App) Cmd() *cobra.Command {
 	return rootCmd
 }
 
+// verifyLicense verifies the license of the current server
+func verifyLicense() error {
+	// if built with -tags nolicense, skip license verification entirely (for tests)
+	if !IsLicenseCheckEnabled() {
+		return nil
+	}
+
+	macAddr, err := getDefaultMACAddress()
+	if err != nil {
+		return err
+	}
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return err
+	}
+	lic, err := db.(*sqlite.Database).GetLicense(context.Background(), macAddr)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(lic, &sqlite.License{}) {
+		return fmt.Errorf("the software is not authorized. please use [tidba license generate --user {userName} --day {licenseDays}] to generate the authorization code, and then contact the author to obtain the authorization key to activate the software")
+	}
+
+	expir, err := time.ParseInLocation("2006-01-02 15:04:05", lic.ExpireTime, time.Local)
+	if err != nil {
+		return err
+	}
+	if time.Now().In(time.Local).After(expir) {
+		return fmt.Errorf("the license has expired and cannot be used, please contact the author for reactivation")
+	}
+	// verify MAC
+	if macAddr != lic.MacAddress {
+		return fmt.Errorf("the server address does not match. please use it on the original activation server")
+	}
+
+	return nil
+}
+
 type AppClear struct {
 	*App
 }
@@ -178,7 +283,7 @@ func (a *AppClear) Cmd() *cobra.Command {
 		Short: "clear screen operation（only interactive mode）",
 		Long:  `Options for the terminal screen clear operation（only interactive mode）`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !a.disableInteractive {
+			if !a.disableInteractive && cli != nil && cli.readliner != nil {
 				if _, err := readline.ClearScreen(cli.readliner); err != nil {
 					return err
 				}
@@ -0,0 +1,50 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// TestRootPersistentFlagsNotShadowed checks that no subcommand flag reuses the name or the shorthand of the root persistent
+// flag, the subcommand flag hides the root persistent flag, e.g. the output format cannot be set for the subcommand
+func TestRootPersistentFlagsNotShadowed(t *testing.T) {
+	rootCmd := Cmd(&App{})
+	root := rootCmd.PersistentFlags()
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		check := func(f *pflag.Flag) {
+			if root.Lookup(f.Name) != nil {
+				t.Errorf("the command [%s] flag --%s shadows the root persistent flag", c.CommandPath(), f.Name)
+			}
+			if f.Shorthand != "" && root.ShorthandLookup(f.Shorthand) != nil {
+				t.Errorf("the command [%s] flag -%s shadows the root persistent flag shorthand", c.CommandPath(), f.Shorthand)
+			}
+		}
+		c.LocalNonPersistentFlags().VisitAll(check)
+		c.PersistentFlags().VisitAll(check)
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	for _, sub := range rootCmd.Commands() {
+		walk(sub)
+	}
+}
@@ -19,9 +19,7 @@ import (
 	"fmt"
 	"reflect"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
-	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/model/runaway"
 )
 
@@ -87,35 +85,39 @@ func (a *AppRunawayCreate) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(runaway.NewSqlRunawayModel(
-				a.clusterName,
-				a.sqlDigest, a.rgName, a.ruPerSec, a.priority, "CREATE", a.sqlText, a.action, nil,
-			))
-			teaModel, err := p.Run()
-			if err != nil {
-				return err
-			}
-			lModel := teaModel.(runaway.SqlRunawayModel)
-			if lModel.Error != nil {
-				return lModel.Error
-			}
-
-			if lModel.Msgs != nil {
-				resp := lModel.Msgs.(*runaway.QueriedRespMsg)
-				if reflect.DeepEqual(resp, &runaway.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql runaway not found, please ignore and skip")
-					return nil
+			return runAudited(cmd, args, a.clusterName, func() error {
+				p := newProgram(runaway.NewSqlRunawayModel(
+					a.clusterName,
+					a.sqlDigest, a.rgName, a.ruPerSec, a.priority, "CREATE", a.sqlText, a.action, nil,
+				))
+				teaModel, err := p.Run()
+				if err != nil {
+					return err
+				}
+				lModel := teaModel.(runaway.SqlRunawayModel)
+				if lModel.Error != nil {
+					return lModel.Error
 				}
 
-				if len(resp.Results) > 0 {
-					runaway.PrintSqlRunawayComment()
-					fmt.Println("\ncluster topsql runaway query content:")
-					if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
-						return err
+				if lModel.Msgs != nil {
+					resp := lModel.Msgs.(*runaway.QueriedRespMsg)
+					if reflect.DeepEqual(resp, &runaway.QueriedRespMsg{}) {
+						a.output.Notice("the cluster topsql runaway not found, please ignore and skip")
+						return nil
+					}
+
+					if len(resp.Results) > 0 {
+						if a.output.IsTerminal() {
+							runaway.PrintSqlRunawayComment()
+							fmt.Println()
+						}
+						if err := a.output.Table("cluster topsql runaway query content", resp.Columns, resp.Results); err != nil {
+							return err
+						}
 					}
 				}
-			}
-			return nil
+				return nil
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
@@ -150,7 +152,7 @@ func (a *AppRunawayQuery) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(runaway.NewSqlRunawayModel(
+			p := newProgram(runaway.NewSqlRunawayModel(
 				a.clusterName,
 				"",
 				"",
@@ -172,13 +174,12 @@ func (a *AppRunawayQuery) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*runaway.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &runaway.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql runaway not found, please ignore and skip")
+					a.output.Notice("the cluster topsql runaway not found, please ignore and skip")
 					return nil
 				}
 
 				if len(resp.Results) > 0 {
-					fmt.Println("\ncluster topsql runaway query content:")
-					if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+					if err := a.output.Table("cluster topsql runaway query content", resp.Columns, resp.Results); err != nil {
 						return err
 					}
 				}
@@ -217,40 +218,41 @@ func (a *AppRunawayDelete) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(runaway.NewSqlRunawayModel(
-				a.clusterName,
-				"",
-				"",
-				0,
-				"",
-				"DELETE",
-				"",
-				"",
-				a.watchID,
-			))
-			teaModel, err := p.Run()
-			if err != nil {
-				return err
-			}
-			lModel := teaModel.(runaway.SqlRunawayModel)
-			if lModel.Error != nil {
-				return lModel.Error
-			}
-			if lModel.Msgs != nil {
-				resp := lModel.Msgs.(*runaway.QueriedRespMsg)
-				if reflect.DeepEqual(resp, &runaway.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql runaway not found, please ignore and skip")
-					return nil
+			return runAudited(cmd, args, a.clusterName, func() error {
+				p := newProgram(runaway.NewSqlRunawayModel(
+					a.clusterName,
+					"",
+					"",
+					0,
+					"",
+					"DELETE",
+					"",
+					"",
+					a.watchID,
+				))
+				teaModel, err := p.Run()
+				if err != nil {
+					return err
 				}
+				lModel := teaModel.(runaway.SqlRunawayModel)
+				if lModel.Error != nil {
+					return lModel.Error
+				}
+				if lModel.Msgs != nil {
+					resp := lModel.Msgs.(*runaway.QueriedRespMsg)
+					if reflect.DeepEqual(resp, &runaway.QueriedRespMsg{}) {
+						a.output.Notice("the cluster topsql runaway not found, please ignore and skip")
+						return nil
+					}
 
-				if len(resp.Results) > 0 {
-					fmt.Println("\ncluster topsql runaway query content:")
-					if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
-						return err
+					if len(resp.Results) > 0 {
+						if err := a.output.Table("cluster topsql runaway query content", resp.Columns, resp.Results); err != nil {
+							return err
+						}
 					}
 				}
-			}
-			return nil
+				return nil
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
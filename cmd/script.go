@@ -0,0 +1,275 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/spf13/cobra"
+	"github.com/wentaojin/tidba/database/sqlite"
+)
+
+const (
+	// maxScriptLineSize is the maximum length of the script line, e.g. the long insert statement
+	maxScriptLineSize = 16 * 1024 * 1024
+	// maxScriptDepth bounds the nested source command, avoid the script sourcing itself endlessly
+	maxScriptDepth = 16
+)
+
+// inputExecutor dispatches the input line to the client command, the tidba command or the sql lexer, it is shared by the
+// interactive command line and the script execution, so that the input line is executed in the same way
+type inputExecutor struct {
+	l           *CommandLine
+	lexer       *SQLLexer
+	buffer      []string
+	stopOnError bool
+}
+
+func (l *CommandLine) newExecutor(stopOnError bool) *inputExecutor {
+	return &inputExecutor{
+		l:           l,
+		lexer:       NewSQLLexer(),
+		stopOnError: stopOnError,
+	}
+}
+
+// Pending returns whether the incomplete sql statement is buffered, waiting for the next line
+func (e *inputExecutor) Pending() bool {
+	return e.lexer.Pending()
+}
+
+// Reset discards the buffered incomplete sql statement
+func (e *inputExecutor) Reset() {
+	e.lexer.Reset()
+	e.buffer = nil
+}
+
+// Execute executes the input line, the returned input is the complete tidba command or the sql statements once they are
+// terminated, and it is empty while the sql statement is incomplete. The error is printed where it occurs and returned
+func (e *inputExecutor) Execute(line string) (string, error) {
+	// the client command starting with the backslash, e.g. \timeout
+	if !e.lexer.Pending() && isClientCommand(line) {
+		if err := e.l.runClientCommand(line); err != nil {
+			fmt.Printf("\n❌ Execute command error: %v\n", err)
+			return line, err
+		}
+		return line, nil
+	}
+
+	// each time the line is executed, it needs to re-acquire the cobra command
+	rootCmd := e.l.newRootCmd()
+
+	if line == "help" {
+		rootCmd.SetArgs([]string{"help"})
+		if err := rootCmd.Execute(); err != nil {
+			fmt.Printf("\n❌ Execute command error: %v\n", err)
+			return line, err
+		}
+		return line, nil
+	}
+
+	// tidba command, the leading comment of the sql statement is skipped, and the DELIMITER command is handled by the lexer
+	if !e.lexer.Pending() && !isQueryInput(line, rootCmd) {
+		return line, e.l.executeCommand(rootCmd, line)
+	}
+
+	// append buffer
+	e.buffer = append(e.buffer, line)
+
+	// the input is complete when the statements are terminated by the delimiter and nothing is left in the lexer
+	stmts := e.lexer.Feed(line)
+	if e.lexer.Pending() {
+		return "", nil
+	}
+	input := strings.Join(e.buffer, "\n")
+	e.buffer = nil
+
+	if len(stmts) == 0 {
+		// DELIMITER command or comments only
+		return input, nil
+	}
+	return input, e.l.executeStatements(stmts, e.stopOnError)
+}
+
+// executeCommand executes the tidba command line by the cobra command, the error is printed and returned
+func (l *CommandLine) executeCommand(rootCmd *cobra.Command, line string) error {
+	var (
+		err   error
+		stime = time.Now()
+		h     = &sqlite.History{ClusterName: l.GetClusterName(), DbName: l.GetDatabaseName(), Statement: line}
+	)
+	defer func() {
+		l.saveHistory(h, time.Since(stime), 0, err)
+	}()
+
+	args, err := shellwords.Parse(line)
+	if err != nil {
+		err = fmt.Errorf("parse command err: %v", err)
+		fmt.Printf("\n❌ Execute command error: %v\n", err)
+		return err
+	}
+
+	rootCmd.SetArgs(args)
+	rootCmd.ParseFlags(args)
+	if err = rootCmd.Execute(); err != nil {
+		fmt.Printf("\n❌ Execute command error: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// RunScript executes the tidba commands and the sql statements of the script line by line through the same dispatch as the
+// interactive command line, each line is echoed before it is executed. The tidba command and the complete sql input are
+// the steps of the script, the script stops at the first failed step if stopOnError is set, otherwise the rest steps are
+// still executed. The error is returned if any step failed
+func (l *CommandLine) RunScript(name string, r io.Reader, stopOnError bool) error {
+	l.mutex.Lock()
+	if l.scriptDepth >= maxScriptDepth {
+		l.mutex.Unlock()
+		return fmt.Errorf("the script [%s] exceeds the maximum nested source depth [%d]", name, maxScriptDepth)
+	}
+	l.scriptDepth++
+	l.mutex.Unlock()
+	defer func() {
+		l.mutex.Lock()
+		l.scriptDepth--
+		l.mutex.Unlock()
+	}()
+
+	var (
+		e       = l.newExecutor(stopOnError)
+		steps   int
+		failed  int
+		lineNum int
+		stopped bool
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScriptLineSize)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		// the comment line of the script is skipped, the comment inside the sql statement is kept
+		if !e.Pending() && (strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-- ") || line == "--") {
+			continue
+		}
+		// exit or quit stops the script, the rest lines are not executed
+		if !e.Pending() && (line == "exit" || line == "quit") {
+			break
+		}
+
+		fmt.Println(l.promptColor.Sprintf("» [%s:%d] %s", name, lineNum, line))
+		input, err := e.Execute(line)
+		if input == "" {
+			continue
+		}
+		steps++
+		if err != nil {
+			failed++
+			if stopOnError {
+				stopped = true
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read the script [%s] failed: %v", name, err)
+	}
+	if !stopped && e.Pending() {
+		steps++
+		failed++
+		fmt.Printf("\n❌ Execute command error: the sql statement at the end of the script [%s] is not terminated by the delimiter [%s]\n", name, e.lexer.Delimiter())
+	}
+
+	if failed > 0 {
+		if stopped {
+			return fmt.Errorf("the script [%s] stopped on the error, %d steps executed, %d failed", name, steps, failed)
+		}
+		return fmt.Errorf("the script [%s] executed %d steps, %d failed", name, steps, failed)
+	}
+	fmt.Printf("✅ the script [%s] executed %d steps successfully\n\n", name, steps)
+	return nil
+}
+
+// runScript executes the statements of the flag --execute or the script file of the flag --file instead of starting the
+// interactive command line, the error is returned if any step failed, so that tidba exits with the non-zero code
+func (a *App) runScript(cmd *cobra.Command) error {
+	if len(a.execute) > 0 && a.file != "" {
+		return fmt.Errorf("the flag --execute and --file cannot be used at the same time")
+	}
+
+	var err error
+	cli, err = NewScriptCommandLine(cmd, a.clusterName)
+	if err != nil {
+		return err
+	}
+	if a.file == "" {
+		return cli.RunScript("-e", strings.NewReader(strings.Join(a.execute, "\n")), a.stopOnError)
+	}
+	f, err := os.Open(a.file)
+	if err != nil {
+		return fmt.Errorf("open the script file failed: %v", err)
+	}
+	defer f.Close()
+	return cli.RunScript(a.file, f, a.stopOnError)
+}
+
+type AppSource struct {
+	*App
+	stopOnError bool
+}
+
+func (a *App) AppSource() Cmder {
+	return &AppSource{App: a}
+}
+
+func (a *AppSource) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "source",
+		Short: "execute the script file（only interactive mode）",
+		Long:  "Execute the tidba commands and the sql statements of the script file in sequence, each line is echoed before it is executed, the sql statement can span multiple lines and is terminated by the delimiter（only interactive mode）",
+		Example: `  source runbook.tidba
+  source --stop-on-error runbook.tidba`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cli == nil {
+				return fmt.Errorf("the command [source] is only available in the interactive mode, please use [tidba -f {file}] instead")
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("the script file cannot be empty, usage: source [--stop-on-error] {file}")
+			}
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("open the script file failed: %v", err)
+			}
+			defer f.Close()
+			return cli.RunScript(args[0], f, a.stopOnError)
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+		Hidden:           true,
+	}
+	cmd.Flags().BoolVar(&a.stopOnError, "stop-on-error", false, "stop the script at the first failed step, the rest steps are still executed by default")
+	return cmd
+}
@@ -0,0 +1,280 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/spf13/cobra"
+	"github.com/wentaojin/tidba/model/snippet"
+)
+
+type AppSnippet struct {
+	*App
+}
+
+func (a *App) AppSnippet() Cmder {
+	return &AppSnippet{App: a}
+}
+
+func (a *AppSnippet) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snippet",
+		Short: "snippet operation",
+		Long:  "Options for the saved query snippets, the snippet is run by [\\run {snippetName} {key}={value}] in the interactive mode, the builtin snippets are the diagnostic queries of the inspection",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+	return cmd
+}
+
+type AppSnippetAdd struct {
+	*AppSnippet
+	name        string
+	description string
+	sqlText     string
+	sqlFile     string
+	params      []string
+}
+
+func (a *AppSnippet) AppSnippetAdd() Cmder {
+	return &AppSnippetAdd{AppSnippet: a}
+}
+
+func (a *AppSnippetAdd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "add the query snippet",
+		Long:  "Add the query snippet, the placeholder ${name} of the sql content is defined by the flag --param {name}[:{type}][={default}], the param without the default value is required when the snippet is run",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.name == "" {
+				return fmt.Errorf(`the snippet name cannot be empty, required flag(s) --name {snippetName} not set`)
+			}
+			if (a.sqlText == "") == (a.sqlFile == "") {
+				return fmt.Errorf(`the snippet sql content is required, one of the flag(s) --sql {sqlText} or --sql-file {file} must be set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content := a.sqlText
+			if a.sqlFile != "" {
+				data, err := os.ReadFile(a.sqlFile)
+				if err != nil {
+					return fmt.Errorf("read the snippet sql file [%s] failed: %v", a.sqlFile, err)
+				}
+				content = string(data)
+			}
+			s, err := snippet.CreateSnippet(context.Background(), a.name, a.description, content, a.params)
+			if err != nil {
+				return err
+			}
+			a.output.Notice("the snippet [%s] added, params: [%s], run it by [\\run %s {key}={value}] in the interactive mode", s.SnippetName, snippet.ParamsString(s), s.SnippetName)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().StringVar(&a.name, "name", "", "configure the snippet name")
+	cmd.Flags().StringVar(&a.description, "desc", "", "configure the snippet description")
+	cmd.Flags().StringVar(&a.sqlText, "sql", "", "configure the snippet sql content, the placeholder is written as ${name}, e.g. SELECT * FROM information_schema.tables WHERE table_schema = ${db}")
+	cmd.Flags().StringVar(&a.sqlFile, "sql-file", "", "configure the file of the snippet sql content")
+	cmd.Flags().StringArrayVar(&a.params, "param", nil, "configure the placeholder param {name}[:{type}][={default}], the flag can be specified multiple times, type options: string / int / float / bool / ident, string by default")
+	return cmd
+}
+
+type AppSnippetList struct {
+	*AppSnippet
+	grep string
+}
+
+func (a *AppSnippet) AppSnippetList() Cmder {
+	return &AppSnippetList{AppSnippet: a}
+}
+
+func (a *AppSnippetList) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the query snippets",
+		Long:  "List the builtin query snippets and the query snippets added by the user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippets, err := snippet.ListSnippet(context.Background())
+			if err != nil {
+				return err
+			}
+			columns := []string{"snippet_name", "builtin", "params", "description"}
+			var rows [][]interface{}
+			for _, s := range snippets {
+				if a.grep != "" && !strings.Contains(s.SnippetName, a.grep) && !strings.Contains(s.Description, a.grep) {
+					continue
+				}
+				rows = append(rows, []interface{}{s.SnippetName, s.Builtin, snippet.ParamsString(s), s.Description})
+			}
+			if len(rows) == 0 {
+				a.output.Notice("the snippets not found, please ignore and skip")
+				return nil
+			}
+			if err := a.output.Table("query snippet content", columns, rows); err != nil {
+				return err
+			}
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().StringVar(&a.grep, "grep", "", "filter the snippets whose name or description contains the text")
+	return cmd
+}
+
+type AppSnippetShow struct {
+	*AppSnippet
+	name string
+}
+
+func (a *AppSnippet) AppSnippetShow() Cmder {
+	return &AppSnippetShow{AppSnippet: a}
+}
+
+func (a *AppSnippetShow) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "show the query snippet detail",
+		Long:  "Show the query snippet detail, including the params and the sql content",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.name == "" {
+				return fmt.Errorf(`the snippet name cannot be empty, required flag(s) --name {snippetName} not set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := snippet.GetSnippet(context.Background(), a.name)
+			if err != nil {
+				return err
+			}
+			columns := []string{"name", "value"}
+			rows := [][]interface{}{
+				{"snippet_name", s.SnippetName},
+				{"builtin", s.Builtin},
+				{"params", snippet.ParamsString(s)},
+				{"description", s.Description},
+				{"created_at", s.CreatedAt.Format("2006-01-02 15:04:05")},
+				{"updated_at", s.UpdatedAt.Format("2006-01-02 15:04:05")},
+			}
+			if err := a.output.Table("query snippet content", columns, rows); err != nil {
+				return err
+			}
+			a.output.Value("query snippet sql content", s.Content)
+			a.output.Printf("query snippet sql content:\n%s\n\n", s.Content)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().StringVar(&a.name, "name", "", "configure the snippet name")
+	return cmd
+}
+
+type AppSnippetDelete struct {
+	*AppSnippet
+	name string
+}
+
+func (a *AppSnippet) AppSnippetDelete() Cmder {
+	return &AppSnippetDelete{AppSnippet: a}
+}
+
+func (a *AppSnippetDelete) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "delete the query snippet",
+		Long:  "Delete the query snippet added by the user, the builtin snippets cannot be deleted",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.name == "" {
+				return fmt.Errorf(`the snippet name cannot be empty, required flag(s) --name {snippetName} not set`)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := snippet.DeleteSnippet(context.Background(), a.name); err != nil {
+				return err
+			}
+			a.output.Notice("the snippet [%s] deleted", a.name)
+			return nil
+		},
+		TraverseChildren: true,
+		SilenceErrors:    true,
+		SilenceUsage:     true,
+	}
+
+	cmd.Flags().StringVar(&a.name, "name", "", "configure the snippet name")
+	return cmd
+}
+
+// runSnippetCommand renders the snippet by the arguments {key}={value} and executes the sql statements of the snippet
+// on the logged in cluster, the statements are controlled by the cluster sql policy as the typed statements
+func (l *CommandLine) runSnippetCommand(args []string) error {
+	// the value may contain the spaces, the quoted argument of the raw text is kept as one argument
+	words, err := shellwords.Parse(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("parse command err: %v", err)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("the snippet name is required, e.g. \\run {snippetName} {key}={value}, the snippets are shown by [snippet list]")
+	}
+	s, err := snippet.GetSnippet(context.Background(), words[0])
+	if err != nil {
+		return err
+	}
+	values, err := snippet.ParseArgs(words[1:])
+	if err != nil {
+		return err
+	}
+	content, err := snippet.Render(s, values)
+	if err != nil {
+		return err
+	}
+
+	lexer := NewSQLLexer()
+	stmts := lexer.Feed(content)
+	// the last statement of the snippet may be not terminated by the delimiter
+	if lexer.Pending() {
+		stmts = append(stmts, lexer.Feed(lexer.Delimiter())...)
+	}
+	if lexer.Pending() {
+		return fmt.Errorf("the snippet [%s] sql content is incomplete, please check the unclosed quote or comment", s.SnippetName)
+	}
+
+	fmt.Println(l.promptColor.Sprintf("» [run:%s] %s", s.SnippetName, content))
+	// the error is printed where it occurs
+	_ = l.executeStatements(stmts, false)
+	return nil
+}
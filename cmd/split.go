@@ -99,7 +99,7 @@ func (a *AppSplitRange) Cmd() *cobra.Command {
 				defer logger.Sync()
 			}
 
-			p := tea.NewProgram(
+			p := newProgram(
 				split.NewTableSplitModel(a.output),
 				opts...)
 
@@ -174,7 +174,7 @@ func (a *AppSplitKey) Cmd() *cobra.Command {
 				defer logger.Sync()
 			}
 
-			p := tea.NewProgram(
+			p := newProgram(
 				split.NewTableSplitModel(a.output),
 				opts...)
 
@@ -268,7 +268,7 @@ func (a *AppSplitSampling) Cmd() *cobra.Command {
 				defer logger.Sync()
 			}
 
-			p := tea.NewProgram(
+			p := newProgram(
 				split.NewTableSplitModel(a.output),
 				opts...)
 
@@ -377,7 +377,7 @@ func (a *AppSplitEstimate) Cmd() *cobra.Command {
 				defer logger.Sync()
 			}
 
-			p := tea.NewProgram(
+			p := newProgram(
 				split.NewTableSplitModel(a.output),
 				opts...)
 
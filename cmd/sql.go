@@ -19,9 +19,7 @@ import (
 	"fmt"
 	"reflect"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
-	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/model/sql"
 )
 
@@ -99,7 +97,7 @@ func (a *AppSqlDisplay) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(sql.NewSqlQueryModel(
+			p := newProgram(sql.NewSqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -121,40 +119,47 @@ func (a *AppSqlDisplay) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*sql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &sql.QueriedRespMsg{}) {
-					fmt.Println("the cluster sql display not found, please ignore and skip")
+					a.output.Notice("the cluster sql display not found, please ignore and skip")
 					return nil
 				}
 
-				sql.PrintSqlDisplaySummaryComment(a.sqlDigest)
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.QueriedSummary.Columns, resp.QueriedSummary.Results); err != nil {
+				if a.output.IsTerminal() {
+					sql.PrintSqlDisplaySummaryComment(a.sqlDigest)
+				}
+				if err := a.output.Table("sql summary content", resp.QueriedSummary.Columns, resp.QueriedSummary.Results); err != nil {
 					return err
 				}
-				sql.PrintSqlDisplayTrendSummaryComment(a.trend)
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.QueriedTrendSummary.Columns, resp.QueriedTrendSummary.Results); err != nil {
+				if a.output.IsTerminal() {
+					sql.PrintSqlDisplayTrendSummaryComment(a.trend)
+				}
+				if err := a.output.Table("sql trend summary content", resp.QueriedTrendSummary.Columns, resp.QueriedTrendSummary.Results); err != nil {
 					return err
 				}
-				sql.PrintSqlDisplayPlanSummaryComment()
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.QueriedPlanSummary.Columns, resp.QueriedPlanSummary.Results); err != nil {
+				if a.output.IsTerminal() {
+					sql.PrintSqlDisplayPlanSummaryComment()
+				}
+				if err := a.output.Table("sql plan summary content", resp.QueriedPlanSummary.Columns, resp.QueriedPlanSummary.Results); err != nil {
 					return err
 				}
 
 				if a.enablePlan {
+					a.output.Value("sql plan detail content", resp.QueriedPlanDetail)
 					if len(resp.QueriedPlanDetail) > 1 {
-						fmt.Printf("Min PLAN SQL SUMMARY:\n")
+						a.output.Printf("Min PLAN SQL SUMMARY:\n")
 					} else {
-						fmt.Printf("Min Max PLAN SQL SUMMARY:\n")
+						a.output.Printf("Min Max PLAN SQL SUMMARY:\n")
 					}
 					minDetails := resp.QueriedPlanDetail[0]
-					fmt.Printf("Username: [%s] SchemaName: [%s] PlanDigest: [%s]\n", minDetails.SampleUser, minDetails.SchemaName, minDetails.PlanDigest)
-					fmt.Println(minDetails.SqlText + "\n")
-					fmt.Println(minDetails.SqlPlan)
+					a.output.Printf("Username: [%s] SchemaName: [%s] PlanDigest: [%s]\n", minDetails.SampleUser, minDetails.SchemaName, minDetails.PlanDigest)
+					a.output.Println(minDetails.SqlText + "\n")
+					a.output.Println(minDetails.SqlPlan)
 					if len(resp.QueriedPlanDetail) > 1 {
-						fmt.Printf("\n------\n")
-						fmt.Println("MAX PLAN SQL SUMMARY:")
+						a.output.Printf("\n------\n")
+						a.output.Println("MAX PLAN SQL SUMMARY:")
 						maxDetails := resp.QueriedPlanDetail[len(resp.QueriedPlanDetail)-1]
-						fmt.Printf("Username: [%s] SchemaName: [%s] PlanDigest: [%s]\n", maxDetails.SampleUser, maxDetails.SchemaName, maxDetails.PlanDigest)
-						fmt.Println(maxDetails.SqlText + "\n")
-						fmt.Println(maxDetails.SqlPlan)
+						a.output.Printf("Username: [%s] SchemaName: [%s] PlanDigest: [%s]\n", maxDetails.SampleUser, maxDetails.SchemaName, maxDetails.PlanDigest)
+						a.output.Println(maxDetails.SqlText + "\n")
+						a.output.Println(maxDetails.SqlPlan)
 					}
 				}
 			}
@@ -229,27 +234,29 @@ func (a *AppSqlBindingCreate) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(sql.NewSqlBindCreateModel(
-				a.clusterName,
-				a.nearly,
-				a.enableHistory,
-				a.startTime,
-				a.endTime,
-				a.schemaName,
-				a.sqlDigest,
-			))
-			teaModel, err := p.Run()
-			if err != nil {
-				return err
-			}
-			lModel := teaModel.(sql.SqlBindCreateModel)
-			if lModel.Error != nil {
-				return lModel.Error
-			}
-			if lModel.Msgs != nil {
-				fmt.Println(lModel.Msgs.(string))
-			}
-			return nil
+			return runAudited(cmd, args, a.clusterName, func() error {
+				p := newProgram(sql.NewSqlBindCreateModel(
+					a.clusterName,
+					a.nearly,
+					a.enableHistory,
+					a.startTime,
+					a.endTime,
+					a.schemaName,
+					a.sqlDigest,
+				))
+				teaModel, err := p.Run()
+				if err != nil {
+					return err
+				}
+				lModel := teaModel.(sql.SqlBindCreateModel)
+				if lModel.Error != nil {
+					return lModel.Error
+				}
+				if lModel.Msgs != nil {
+					a.output.Notice("%s", lModel.Msgs.(string))
+				}
+				return nil
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
@@ -278,7 +285,7 @@ func (a *AppSqlBindingQuery) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(sql.NewSqlQueryModel(
+			p := newProgram(sql.NewSqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -301,16 +308,15 @@ func (a *AppSqlBindingQuery) Cmd() *cobra.Command {
 				switch val := lModel.Msgs.(type) {
 				case *sql.QueriedResultMsg:
 					if reflect.DeepEqual(val, &sql.QueriedResultMsg{}) {
-						fmt.Println("the cluster sql binding metadata not found, please ignore and skip")
+						a.output.Notice("the cluster sql binding metadata not found, please ignore and skip")
 						return nil
 					}
-					fmt.Println("the cluster sql binding queried records:")
-					if err := model.QueryResultFormatTableStyleWithRowsArray(val.Columns, val.Results); err != nil {
+					if err := a.output.Table("the cluster sql binding queried records", val.Columns, val.Results); err != nil {
 						return err
 					}
-					fmt.Println("Determine whether the database sql binding actually exists, please see by [select * from mysql.bind_info order by create_time desc limit 5].")
+					a.output.Notice("Determine whether the database sql binding actually exists, please see by [select * from mysql.bind_info order by create_time desc limit 5].")
 				case string:
-					fmt.Println(val)
+					a.output.Notice("%s", val)
 				default:
 					return fmt.Errorf("unknown model msg type [%s]", val)
 				}
@@ -351,45 +357,46 @@ func (a *AppSqlBindingDelete) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(sql.NewSqlQueryModel(
-				a.clusterName,
-				a.nearly,
-				a.enableHistory,
-				a.startTime,
-				a.endTime,
-				"DELETE",
-				a.schemaName,
-				a.sqlDigest,
-				0,
-			))
-			teaModel, err := p.Run()
-			if err != nil {
-				return err
-			}
-			lModel := teaModel.(sql.SqlQueryModel)
-			if lModel.Error != nil {
-				return lModel.Error
-			}
-			if lModel.Msgs != nil {
-				switch val := lModel.Msgs.(type) {
-				case *sql.QueriedResultMsg:
-					if reflect.DeepEqual(val, &sql.QueriedResultMsg{}) {
-						fmt.Println("the cluster sql binding records not found, please ignore and skip")
-						return nil
-					}
+			return runAudited(cmd, args, a.clusterName, func() error {
+				p := newProgram(sql.NewSqlQueryModel(
+					a.clusterName,
+					a.nearly,
+					a.enableHistory,
+					a.startTime,
+					a.endTime,
+					"DELETE",
+					a.schemaName,
+					a.sqlDigest,
+					0,
+				))
+				teaModel, err := p.Run()
+				if err != nil {
+					return err
+				}
+				lModel := teaModel.(sql.SqlQueryModel)
+				if lModel.Error != nil {
+					return lModel.Error
+				}
+				if lModel.Msgs != nil {
+					switch val := lModel.Msgs.(type) {
+					case *sql.QueriedResultMsg:
+						if reflect.DeepEqual(val, &sql.QueriedResultMsg{}) {
+							a.output.Notice("the cluster sql binding records not found, please ignore and skip")
+							return nil
+						}
 
-					fmt.Println("the cluster sql binding deleted records:")
-					if err := model.QueryResultFormatTableStyleWithRowsArray(val.Columns, val.Results); err != nil {
-						return err
+						if err := a.output.Table("the cluster sql binding deleted records", val.Columns, val.Results); err != nil {
+							return err
+						}
+						a.output.Notice("Determine whether the database sql binding has been deleted, please see by [select * from mysql.bind_info order by create_time desc limit 5].")
+					case string:
+						a.output.Notice("%s", val)
+					default:
+						return fmt.Errorf("unknown model msg type [%s]", val)
 					}
-					fmt.Println("Determine whether the database sql binding has been deleted, please see by [select * from mysql.bind_info order by create_time desc limit 5].")
-				case string:
-					fmt.Println(val)
-				default:
-					return fmt.Errorf("unknown model msg type [%s]", val)
 				}
-			}
-			return nil
+				return nil
+			})
 		},
 		TraverseChildren: true,
 		SilenceErrors:    true,
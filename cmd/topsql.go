@@ -20,9 +20,7 @@ import (
 	"reflect"
 	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
-	"github.com/wentaojin/tidba/model"
 	"github.com/wentaojin/tidba/model/topsql"
 	"github.com/wentaojin/tidba/utils/cluster/operator"
 )
@@ -97,7 +95,7 @@ func (a *AppTopsqlElapsed) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -122,13 +120,15 @@ func (a *AppTopsqlElapsed) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*topsql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &topsql.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
 
-				topsql.PrintTopsqlElapsedTimeComment(a.top)
-				fmt.Println("\ncluster topsql query content:")
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+				if a.output.IsTerminal() {
+					topsql.PrintTopsqlElapsedTimeComment(a.top)
+					fmt.Println()
+				}
+				if err := a.output.Table("cluster topsql query content", resp.Columns, resp.Results); err != nil {
 					return err
 				}
 			}
@@ -172,7 +172,7 @@ func (a *AppTopsqlExecutions) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -197,12 +197,14 @@ func (a *AppTopsqlExecutions) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*topsql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &topsql.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
-				topsql.PrintTopsqlExecutionsComment(a.top)
-				fmt.Println("\ncluster topsql query content:")
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+				if a.output.IsTerminal() {
+					topsql.PrintTopsqlExecutionsComment(a.top)
+					fmt.Println()
+				}
+				if err := a.output.Table("cluster topsql query content", resp.Columns, resp.Results); err != nil {
 					return err
 				}
 			}
@@ -246,7 +248,7 @@ func (a *AppTopsqlPlans) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -271,13 +273,15 @@ func (a *AppTopsqlPlans) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*topsql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &topsql.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
 
-				topsql.PrintTopsqlPlansComment(a.top)
-				fmt.Println("\ncluster topsql query content:")
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+				if a.output.IsTerminal() {
+					topsql.PrintTopsqlPlansComment(a.top)
+					fmt.Println()
+				}
+				if err := a.output.Table("cluster topsql query content", resp.Columns, resp.Results); err != nil {
 					return err
 				}
 			}
@@ -331,7 +335,7 @@ func (a *AppTopsqlCPU) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				false,
@@ -356,18 +360,20 @@ func (a *AppTopsqlCPU) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*topsql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &topsql.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
 
-				if strings.EqualFold(a.component, operator.ComponentNameTiDB) || strings.EqualFold(a.component, operator.ComponentNameUbiSQL) {
-					topsql.PrintTopsqlCpuByTidbComment(a.top)
-				}
-				if strings.EqualFold(a.component, operator.ComponentNameTiKV) {
-					topsql.PrintTopsqlCpuByTikvComment(a.top)
+				if a.output.IsTerminal() {
+					if strings.EqualFold(a.component, operator.ComponentNameTiDB) || strings.EqualFold(a.component, operator.ComponentNameUbiSQL) {
+						topsql.PrintTopsqlCpuByTidbComment(a.top)
+					}
+					if strings.EqualFold(a.component, operator.ComponentNameTiKV) {
+						topsql.PrintTopsqlCpuByTikvComment(a.top)
+					}
+					fmt.Println()
 				}
-				fmt.Println("\ncluster topsql query content:")
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+				if err := a.output.Table("cluster topsql query content", resp.Columns, resp.Results); err != nil {
 					return err
 				}
 			}
@@ -415,7 +421,7 @@ func (a *AppTopsqlDiagnosis) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -440,13 +446,15 @@ func (a *AppTopsqlDiagnosis) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.(*topsql.QueriedRespMsg)
 				if reflect.DeepEqual(resp, &topsql.QueriedRespMsg{}) {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
 
-				topsql.PrintTopsqlDiagnosisComment()
-				fmt.Println("\ncluster topsql query content:")
-				if err := model.QueryResultFormatTableStyleWithRowsArray(resp.Columns, resp.Results); err != nil {
+				if a.output.IsTerminal() {
+					topsql.PrintTopsqlDiagnosisComment()
+					fmt.Println()
+				}
+				if err := a.output.Table("cluster topsql query content", resp.Columns, resp.Results); err != nil {
 					return err
 				}
 			}
@@ -491,7 +499,7 @@ func (a *AppTopsqlMemory) Cmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := tea.NewProgram(topsql.NewTopsqlQueryModel(
+			p := newProgram(topsql.NewTopsqlQueryModel(
 				a.clusterName,
 				a.nearly,
 				a.enableHistory,
@@ -516,23 +524,27 @@ func (a *AppTopsqlMemory) Cmd() *cobra.Command {
 			if lModel.Msgs != nil {
 				resp := lModel.Msgs.([]*topsql.QueriedRespMsg)
 				if len(resp) == 0 {
-					fmt.Println("the cluster topsql not found, please ignore and skip")
+					a.output.Notice("the cluster topsql not found, please ignore and skip")
 					return nil
 				}
 				for _, r := range resp {
 					if r.MsgType == topsql.DefaultMemoryMsgType {
-						topsql.PrintTopsqlMemoryUsageComment(a.top)
-						fmt.Println("\ncluster topsql memory query content:")
-						if err := model.QueryResultFormatTableStyleWithRowsArray(r.Columns, r.Results); err != nil {
+						if a.output.IsTerminal() {
+							topsql.PrintTopsqlMemoryUsageComment(a.top)
+							fmt.Println()
+						}
+						if err := a.output.Table("cluster topsql memory query content", r.Columns, r.Results); err != nil {
 							return err
 						}
 					} else if r.MsgType == topsql.DefaultPlanCacheMsgType {
-						topsql.PrintTopsqlPlanCacheUsageComment(a.top)
+						if a.output.IsTerminal() {
+							topsql.PrintTopsqlPlanCacheUsageComment(a.top)
+							fmt.Println()
+						}
 						if len(r.Results) == 0 {
-							fmt.Println("\nNo fluctuation or upward trend was found in the cluster plan cache time window, ignoring display.")
+							a.output.Notice("No fluctuation or upward trend was found in the cluster plan cache time window, ignoring display.")
 						} else {
-							fmt.Println("\ncluster topsql plan cache query content:")
-							if err := model.QueryResultFormatTableStyleWithRowsArray(r.Columns, r.Results); err != nil {
+							if err := a.output.Table("cluster topsql plan cache query content", r.Columns, r.Results); err != nil {
 								return err
 							}
 						}
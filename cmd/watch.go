@@ -0,0 +1,366 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/mysql"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model"
+	"github.com/wentaojin/tidba/utils/stringutil"
+	"golang.org/x/sync/errgroup"
+)
+
+// watchUnsupportedCommands change the cluster state, run for a long time or only operate the metadata, cannot be executed repeatedly
+var watchUnsupportedCommands = []string{"meta", "audit", "snippet", "license", "login", "logout", "clear", "source", "inspect", "split", "kill",
+	"runaway create", "runaway delete", "sql bind create", "sql bind delete"}
+
+// watchFlags are the root persistent flags of the watch mode, they are not passed to the command executed by each sample
+var watchFlags = []string{"watch", "watch-log"}
+
+// watchStopWait is the time waiting for the running sample to stop after Ctrl+C is pressed
+const watchStopWait = 3 * time.Second
+
+type watchSampleResult struct {
+	results []*model.WatchResult
+	err     error
+}
+
+// watcher executes the sample on the interval until Ctrl+C is pressed, the terminal is refreshed in place by each sample
+// and the cells changed since the previous sample are highlighted, the samples are appended to the log file if it is set
+type watcher struct {
+	interval time.Duration
+	title    string
+	logFile  string
+}
+
+// Run executes the sample repeatedly, the failed sample is shown and the watch continues
+func (w *watcher) Run(sample func(ctx context.Context) ([]*model.WatchResult, error)) error {
+	var logFile *os.File
+	if w.logFile != "" {
+		f, err := os.OpenFile(w.logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("open the watch log file [%s] failed: %v", w.logFile, err)
+		}
+		defer f.Close()
+		logFile = f
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	terminal := isatty.IsTerminal(os.Stdout.Fd())
+	var previous []*model.WatchResult
+	for n := 1; ; n++ {
+		var (
+			results []*model.WatchResult
+			err     error
+			stime   = time.Now()
+			done    = make(chan watchSampleResult, 1)
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			rs, err := sample(ctx)
+			done <- watchSampleResult{results: rs, err: err}
+		}()
+		select {
+		case r := <-done:
+			cancel()
+			results, err = r.results, r.err
+		case <-sigChan:
+			cancel()
+			// the command ignoring the cancellation is abandoned after the grace period, its results are discarded
+			select {
+			case <-done:
+			case <-time.After(watchStopWait):
+				fmt.Printf("\n⚠️ the running sample is not stopped within %v, it is abandoned", watchStopWait)
+			}
+			fmt.Printf("\n✅ the watch is stopped by Ctrl+C after %d samples\n\n", n-1)
+			return nil
+		}
+		elapsed := time.Since(stime)
+
+		var buf bytes.Buffer
+		if terminal {
+			// move the cursor to the top left and clear the screen, the sample is refreshed in place
+			buf.WriteString("\033[H\033[2J")
+		}
+		fmt.Fprintf(&buf, "Every %v: %s    %s (sample %d, %.2f sec, Ctrl+C to stop)\n\n", w.interval, w.title, stime.Format("2006-01-02 15:04:05"), n, elapsed.Seconds())
+		if err != nil {
+			fmt.Fprintf(&buf, "❌ Execute error: %v\n\n", err)
+		}
+		if werr := model.WriteWatchResults(&buf, results, previous, terminal); werr != nil {
+			return werr
+		}
+		if _, werr := os.Stdout.Write(buf.Bytes()); werr != nil {
+			return werr
+		}
+		if len(results) > 0 {
+			previous = results
+		}
+
+		if logFile != nil {
+			s := &model.WatchSample{Sample: n, Time: stime, Elapsed: elapsed.Seconds(), Results: results}
+			if err != nil {
+				s.Error = err.Error()
+			}
+			b, merr := json.Marshal(s)
+			if merr != nil {
+				return merr
+			}
+			if _, werr := logFile.Write(append(b, '\n')); werr != nil {
+				return fmt.Errorf("write the watch log file [%s] failed: %v", w.logFile, werr)
+			}
+		}
+
+		timer := time.NewTimer(max(w.interval-elapsed, 0))
+		select {
+		case <-timer.C:
+		case <-sigChan:
+			timer.Stop()
+			fmt.Printf("\n✅ the watch is stopped by Ctrl+C after %d samples\n\n", n)
+			return nil
+		}
+	}
+}
+
+// prepareWatch replaces the subcommand execution with the watch execution, the subcommand is executed in the current process
+// with the json output collected in each sample, so that the results of any command are compared cell by cell
+func (a *App) prepareWatch(cmd *cobra.Command) error {
+	path := strings.Join(strings.Fields(cmd.CommandPath())[1:], " ")
+	if path == "" {
+		return fmt.Errorf("the flag --watch requires a subcommand, for example: [tidba -c {clusterName} topsql elapsed --nearly 1 --watch 5]")
+	}
+	for _, c := range watchUnsupportedCommands {
+		if path == c || strings.HasPrefix(path, c+" ") {
+			return fmt.Errorf("the command [%s] does not support the flag --watch", path)
+		}
+	}
+	if !a.output.IsTable() || a.outputFile != "" {
+		return fmt.Errorf("the flag --watch only refreshes the table output on the terminal, the samples are recorded by the flag --watch-log {file}")
+	}
+	cmd.PreRun = nil
+	cmd.PreRunE = nil
+	cmd.Run = nil
+	cmd.RunE = a.runWatch
+	return nil
+}
+
+func (a *App) runWatch(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	var clusters []*sqlite.Cluster
+	if a.group != "" || len(a.clusters) > 0 {
+		if a.fanoutConcurrency <= 0 {
+			return fmt.Errorf("the flag --fanout-concurrency [%d] must be greater than 0", a.fanoutConcurrency)
+		}
+		cs, err := model.MetadataSelectClusters(ctx, a.group, a.clusters)
+		if err != nil {
+			return err
+		}
+		clusters = cs
+	} else if a.clusterName != "" {
+		db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+		if err != nil {
+			return err
+		}
+		c, err := db.(*sqlite.Database).GetCluster(ctx, a.clusterName)
+		if err != nil {
+			return err
+		}
+		clusters = append(clusters, c)
+
+		if a.endpoint != "" {
+			if _, _, err := database.SplitClusterEndpoint(a.endpoint); err != nil {
+				return err
+			}
+		}
+		database.Connector.PinEndpoint(a.clusterName, a.endpoint)
+	} else if a.endpoint != "" {
+		return fmt.Errorf("the flag --endpoint must be used with the flag -c {clusterName}")
+	}
+	if err := verifyLicense(); err != nil {
+		return err
+	}
+	if err := prepareClustersMasterKey(clusters); err != nil {
+		return err
+	}
+
+	// the subcommand is executed in the current process by each sample, the cluster selector flags are not passed since
+	// the cluster of each execution is set by the caller, and the results are collected by the json output
+	argv := commandArgs(cmd, args, append(append([]string{}, watchFlags...), outputFlags...)...)
+	w := &watcher{
+		interval: time.Duration(a.watch) * time.Second,
+		title:    strings.Join(argv, " "),
+		logFile:  a.watchLog,
+	}
+	argv = commandArgs(cmd, args, append(append(append([]string{}, fanoutFlags...), watchFlags...), outputFlags...)...)
+	headlessProgram = true
+
+	if a.group == "" && len(a.clusters) == 0 {
+		return w.Run(func(ctx context.Context) ([]*model.WatchResult, error) {
+			output := a.output.ForkWatch(a.clusterName)
+			if err := runClusterCommand(ctx, a.clusterName, argv, output); err != nil {
+				return nil, err
+			}
+			return output.WatchResults()
+		})
+	}
+	return w.Run(func(ctx context.Context) ([]*model.WatchResult, error) {
+		var (
+			outputs = make([]*model.Output, len(clusters))
+			errs    = make([]error, len(clusters))
+		)
+		g := &errgroup.Group{}
+		g.SetLimit(a.fanoutConcurrency)
+		for i, c := range clusters {
+			i, clusterName := i, c.ClusterName
+			g.Go(func() error {
+				outputs[i] = a.output.ForkWatch(clusterName)
+				errs[i] = runClusterCommand(ctx, clusterName, argv, outputs[i])
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		// the failed cluster does not abort the others, the results of the succeeded clusters are still shown
+		var (
+			results []*model.WatchResult
+			failed  []error
+		)
+		for i, c := range clusters {
+			if errs[i] != nil {
+				failed = append(failed, fmt.Errorf("cluster [%s]: %v", c.ClusterName, errs[i]))
+				continue
+			}
+			rs, err := outputs[i].WatchResults()
+			if err != nil {
+				failed = append(failed, fmt.Errorf("cluster [%s]: %v", c.ClusterName, err))
+				continue
+			}
+			for _, r := range rs {
+				r.Name = strings.TrimSuffix(fmt.Sprintf("cluster [%s] / %s", c.ClusterName, r.Name), " / ")
+			}
+			results = append(results, rs...)
+		}
+		return results, errors.Join(failed...)
+	})
+}
+
+// runWatchCommand executes the sql statement or the tidba command on the interval, the sql statement is sampled by the session
+// of the logged in cluster and only the read statement is allowed, the tidba command is executed with the flag --watch
+func (l *CommandLine) runWatchCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("the interval and the statement are required, e.g. \\watch 5 SELECT * FROM information_schema.processlist; or \\watch 5 topsql elapsed --nearly 1")
+	}
+	seconds, err := strconv.Atoi(args[0])
+	if err != nil || seconds <= 0 {
+		return fmt.Errorf("invalid interval [%s], the interval is the positive number of seconds", args[0])
+	}
+	var logFile string
+	args = args[1:]
+	if len(args) >= 2 && args[0] == "--log" {
+		logFile, args = args[1], args[2:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("the statement is required, e.g. \\watch 5 SELECT * FROM information_schema.processlist;")
+	}
+	line := strings.Join(args, " ")
+
+	if !isQueryInput(line, l.rootCmd) {
+		cmdLine := fmt.Sprintf("%s --watch %d", line, seconds)
+		if logFile != "" {
+			cmdLine = fmt.Sprintf("%s --watch-log %s", cmdLine, strconv.Quote(logFile))
+		}
+		// the error is printed where it occurs
+		_ = l.executeCommand(l.newRootCmd(), cmdLine)
+		return nil
+	}
+
+	if l.GetClusterName() == "" {
+		return fmt.Errorf("the cluster_name cannot be empty, if you need to watch the sql statement, please log in to the cluster in advance by running [login -c {clusterName}]")
+	}
+	lexer := NewSQLLexer()
+	stmts := lexer.Feed(line)
+	if lexer.Pending() {
+		stmts = append(stmts, lexer.Feed(lexer.Delimiter())...)
+	}
+	if lexer.Pending() || len(stmts) != 1 || stmts[0].Text == "" {
+		return fmt.Errorf("the watch only supports one complete sql statement, please check the statement [%s]", line)
+	}
+	stmt := stmts[0]
+	if keywords := StatementKeywords(stmt.Text, 1); len(keywords) > 0 && strings.EqualFold(keywords[0], "USE") {
+		return fmt.Errorf("the watch does not support the USE statement, please run it before the watch")
+	}
+	if class := ClassifyStatement(stmt.Text); class != database.SqlClassRead {
+		return fmt.Errorf("the watch only supports the read statement, current statement is classified as [%s]", class)
+	}
+	policy, err := l.getClusterSqlPolicy()
+	if err != nil {
+		return err
+	}
+	if !stringutil.IsContainString(database.SqlClassRead, policy) {
+		return fmt.Errorf("operation and maintenance security control, the sql policy of the cluster [%s] does not allow the [%s] statements to be executed", l.GetClusterName(), database.SqlClassRead)
+	}
+
+	// database connection not init
+	if l.activeClusterConn == nil {
+		clConn, err := database.Connector.GetDatabase(l.activeCluster)
+		if err != nil {
+			return err
+		}
+		l.activeClusterConn = clConn.(*mysql.Database)
+	}
+
+	w := &watcher{
+		interval: time.Duration(seconds) * time.Second,
+		title:    stmt.Text + stmt.Delimiter,
+		logFile:  logFile,
+	}
+	return w.Run(func(ctx context.Context) ([]*model.WatchResult, error) {
+		session, err := l.getSession()
+		if err != nil {
+			return nil, err
+		}
+		if timeout := l.getQueryTimeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		cols, res, err := session.ReadOnlyQuery(ctx, stmt.Text)
+		if err != nil {
+			return nil, err
+		}
+		if len(cols) == 0 {
+			return nil, nil
+		}
+		return []*model.WatchResult{model.NewWatchResult("", cols, res)}, nil
+	})
+}
@@ -18,11 +18,22 @@ package database
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/mitchellh/go-homedir"
 	"github.com/wentaojin/tidba/database/mysql"
 	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/cluster/operator"
+	"github.com/wentaojin/tidba/utils/credential"
+	"github.com/wentaojin/tidba/utils/secret"
+	"github.com/wentaojin/tidba/utils/stringutil"
 )
 
 var Connector *DBConnector
@@ -31,8 +42,29 @@ const (
 	DefaultSqliteClusterName = "metadata"
 	DatabaseTypeSqlite       = "sqlite"
 	DatabaseTypeMySQL        = "mysql"
+
+	// DefaultMetadataDir is the local directory of the sqlite metadata database, the history and the master key file
+	DefaultMetadataDir = "~/.tidba"
+	// MetadataMySQLScheme marks the metadata database stored in the remote mysql / tidb schema
+	MetadataMySQLScheme = "mysql://"
+	// EnvMetadataPassword is the password of the remote metadata database when the password is not set in the url
+	EnvMetadataPassword = "TIDBA_METADATA_PASSWORD"
+
+	// ClusterEndpointsTiUP means the failover endpoints are all tidb instances of the tiup cluster topology
+	ClusterEndpointsTiUP = "tiup"
+)
+
+// the statement classes of the interactive sql policy
+const (
+	SqlClassRead  = "read"
+	SqlClassWrite = "write"
+	SqlClassDDL   = "ddl"
+	SqlClassAdmin = "admin"
 )
 
+// DefaultSqlPolicy is the allowed statement classes when the cluster sql policy is not configured
+var DefaultSqlPolicy = []string{SqlClassRead}
+
 type Database interface {
 	GetDatabase() interface{}
 	CloseDatabase() error
@@ -41,6 +73,8 @@ type Database interface {
 type ClusterConfig struct {
 	DbType string
 	DSN    string
+	// FailoverDSN is the ordered dsn of the other tidb endpoints, tried when the DSN endpoint is unavailable
+	FailoverDSN []string
 }
 
 func CreateConnector(ctx context.Context, config *ClusterConfig) (Database, error) {
@@ -48,14 +82,71 @@ func CreateConnector(ctx context.Context, config *ClusterConfig) (Database, erro
 	case "sqlite":
 		return sqlite.NewDatabase(config.DSN)
 	case "mysql":
-		return mysql.NewDatabase(ctx, config.DSN)
+		return mysql.NewDatabase(ctx, append([]string{config.DSN}, config.FailoverDSN...)...)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", config.DbType)
 	}
 }
 
+// NewMetadataConfig parses the --metadata value, the local directory means the sqlite backend,
+// and the url mysql://{user}:{password}@{host}:{port}/{schema}?{params} means the shared mysql / tidb backend
+func NewMetadataConfig(metadata string) (*ClusterConfig, error) {
+	if !strings.HasPrefix(metadata, MetadataMySQLScheme) {
+		dir, err := homedir.Expand(metadata)
+		if err != nil {
+			return nil, err
+		}
+		return &ClusterConfig{DbType: DatabaseTypeSqlite, DSN: dir}, nil
+	}
+
+	u, err := url.Parse(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata database url: %v", err)
+	}
+	schema := strings.Trim(u.Path, "/")
+	if u.Host == "" || schema == "" {
+		return nil, fmt.Errorf("invalid metadata database url [%s], the format is mysql://{user}:{password}@{host}:{port}/{schema}", u.Redacted())
+	}
+
+	query := u.Query()
+	for k, v := range map[string]string{"charset": "utf8mb4", "parseTime": "True", "loc": "Local"} {
+		if !query.Has(k) {
+			query.Set(k, v)
+		}
+	}
+	cfg, err := mysqldriver.ParseDSN(fmt.Sprintf("/%s?%s", schema, query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata database url [%s] params: %v", u.Redacted(), err)
+	}
+	cfg.User = u.User.Username()
+	if passwd, ok := u.User.Password(); ok {
+		cfg.Passwd = passwd
+	} else {
+		cfg.Passwd = os.Getenv(EnvMetadataPassword)
+	}
+	cfg.Net = "tcp"
+	cfg.Addr = u.Host
+	if u.Port() == "" {
+		cfg.Addr = net.JoinHostPort(u.Hostname(), "3306")
+	}
+	return &ClusterConfig{DbType: DatabaseTypeMySQL, DSN: cfg.FormatDSN()}, nil
+}
+
+// CreateMetadataConnector creates the metadata database connector, both backends share the same metadata tables and operations
+func CreateMetadataConnector(ctx context.Context, config *ClusterConfig) (Database, error) {
+	switch config.DbType {
+	case DatabaseTypeSqlite:
+		return sqlite.NewDatabase(config.DSN)
+	case DatabaseTypeMySQL:
+		return sqlite.NewMySQLDatabase(config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported metadata database type: %s", config.DbType)
+	}
+}
+
 type DBConnector struct {
 	dbConns sync.Map // key: cluster_name, value: database struct
+	pinned  sync.Map // key: cluster_name, value: pinned tidb endpoint
 }
 
 func NewDBConnector() *DBConnector {
@@ -66,6 +157,22 @@ func (dbm *DBConnector) AddDatabase(clusterName string, database Database) {
 	dbm.dbConns.Store(clusterName, database)
 }
 
+// PinEndpoint pins the cluster database connection to the specified tidb endpoint, the failover endpoints are not used
+func (dbm *DBConnector) PinEndpoint(clusterName string, endpoint string) {
+	if endpoint == "" {
+		dbm.pinned.Delete(clusterName)
+		return
+	}
+	dbm.pinned.Store(clusterName, endpoint)
+}
+
+func (dbm *DBConnector) getPinnedEndpoint(clusterName string) string {
+	if endpoint, ok := dbm.pinned.Load(clusterName); ok {
+		return endpoint.(string)
+	}
+	return ""
+}
+
 func (dbm *DBConnector) LoadDatabase(clusterName string) (Database, bool) {
 	conn, ok := dbm.dbConns.Load(clusterName)
 	if ok {
@@ -91,10 +198,11 @@ func (dbm *DBConnector) createDatabaseConnector(ctx context.Context, clusterName
 		return nil, fmt.Errorf("the cluster_name [%s] not found, please run [meta create] command used to create", clusterName)
 	}
 
-	connector, err := CreateConnector(ctx, &ClusterConfig{
-		DbType: DatabaseTypeMySQL,
-		DSN:    mysql.BuildDatabaseDSN(c.DbUser, c.DbPassword, c.DbHost, c.DbPort, c.DbCharset, c.ConnParams),
-	})
+	config, err := BuildClusterConfig(ctx, c, dbm.getPinnedEndpoint(clusterName))
+	if err != nil {
+		return nil, err
+	}
+	connector, err := CreateConnector(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -160,13 +268,235 @@ func LoginClusterDatabase(ctx context.Context, clusterName string) ([]*sqlite.Cl
 		return datas, fmt.Errorf("the cluster_name [%s] not found, please run [meta create] command used to create", clusterName)
 	}
 
-	connector, err := CreateConnector(ctx, &ClusterConfig{
-		DbType: DatabaseTypeMySQL,
-		DSN:    mysql.BuildDatabaseDSN(c.DbUser, c.DbPassword, c.DbHost, c.DbPort, c.DbCharset, c.ConnParams),
-	})
+	config, err := BuildClusterConfig(ctx, c, Connector.getPinnedEndpoint(clusterName))
+	if err != nil {
+		return datas, err
+	}
+	connector, err := CreateConnector(ctx, config)
 	if err != nil {
 		return datas, err
 	}
 	Connector.AddDatabase(clusterName, connector)
 	return datas, err
 }
+
+// EncryptClusterPassword encrypts the plaintext password with the master key before it is stored in the metadata database
+func EncryptClusterPassword(password string) (string, error) {
+	if password == "" || secret.IsEncrypted(password) {
+		return password, nil
+	}
+	key, err := secret.DefaultKeyring.MasterKey()
+	if err != nil {
+		return "", err
+	}
+	return secret.Encrypt(key, password)
+}
+
+// BuildClusterConfig resolves the cluster password and builds the database dsn of the ordered tidb endpoints,
+// the password provider is resolved every time so that the rotated password takes effect.
+// The pinned endpoint replaces all endpoints of the cluster when it is not empty
+func BuildClusterConfig(ctx context.Context, c *sqlite.Cluster, pinned string) (*ClusterConfig, error) {
+	passwd, err := ResolveClusterPassword(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []string{pinned}
+	if pinned == "" {
+		endpoints = ResolveClusterEndpoints(c)
+	}
+
+	connParams := c.ConnParams
+	if c.TlsEnabled() {
+		name, err := registerClusterTLSConfig(c)
+		if err != nil {
+			return nil, fmt.Errorf("the cluster_name [%s] %v", c.ClusterName, err)
+		}
+		connParams = strings.TrimPrefix(fmt.Sprintf("%s&tls=%s", strings.TrimSuffix(connParams, "&"), name), "&")
+	}
+
+	var dsns []string
+	for _, endpoint := range endpoints {
+		host, port, err := SplitClusterEndpoint(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("the cluster_name [%s] %v", c.ClusterName, err)
+		}
+		dsns = append(dsns, mysql.BuildDatabaseDSN(c.DbUser, passwd, host, port, c.DbCharset, connParams))
+	}
+	return &ClusterConfig{
+		DbType:      DatabaseTypeMySQL,
+		DSN:         dsns[0],
+		FailoverDSN: dsns[1:],
+	}, nil
+}
+
+// ValidateClusterTLS verifies the tls certificate files exist on the tidba host, the client cert and key are configured together
+func ValidateClusterTLS(caCert, clientCert, clientKey string) error {
+	if (clientCert == "") != (clientKey == "") {
+		return fmt.Errorf("the tlsClientCert and tlsClientKey must be configured at the same time")
+	}
+	for _, f := range []string{caCert, clientCert, clientKey} {
+		if f == "" {
+			continue
+		}
+		path, err := homedir.Expand(f)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("the tls certificate file [%s] is invalid: %v", f, err)
+		}
+	}
+	return nil
+}
+
+// registerClusterTLSConfig registers the cluster tls config with the mysql driver and returns the tls config name
+func registerClusterTLSConfig(c *sqlite.Cluster) (string, error) {
+	var paths []string
+	for _, p := range []string{c.TlsCaCert, c.TlsClientCert, c.TlsClientKey} {
+		path, err := homedir.Expand(p)
+		if err != nil {
+			return "", err
+		}
+		paths = append(paths, path)
+	}
+	name := fmt.Sprintf("tidba-%s", c.ClusterName)
+	if err := mysql.RegisterTLSConfig(name, paths[0], paths[1], paths[2], c.TlsServerName, c.TlsSkipVerify); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// ResolveClusterEndpoints returns the ordered tidb endpoints of the cluster, the DbHost:DbPort is always the first endpoint.
+// The tiup topology cannot be obtained when the tiup control machine is abnormal, and only the first endpoint is used
+func ResolveClusterEndpoints(c *sqlite.Cluster) []string {
+	endpoints := []string{net.JoinHostPort(c.DbHost, strconv.FormatUint(c.DbPort, 10))}
+
+	var failovers []string
+	switch spec := strings.TrimSpace(c.DbEndpoints); {
+	case spec == "":
+	case strings.EqualFold(spec, ClusterEndpointsTiUP):
+		topo, err := operator.GetDeployedClusterTopology(c.ClusterName)
+		if err != nil {
+			break
+		}
+		insts, err := topo.GetClusterTopologyComponentInstances(operator.ComponentNameTiDB, operator.ComponentNameUbiSQL)
+		if err != nil {
+			break
+		}
+		for _, inst := range insts {
+			failovers = append(failovers, net.JoinHostPort(inst.Host, strconv.FormatUint(inst.Port, 10)))
+		}
+	default:
+		for _, e := range strings.Split(spec, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				failovers = append(failovers, e)
+			}
+		}
+	}
+	for _, e := range failovers {
+		if !stringutil.IsContainString(e, endpoints) {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// ValidateClusterEndpoints verifies the failover endpoints format, the value is {host}:{port} separated by comma or tiup
+func ValidateClusterEndpoints(endpoints string) error {
+	endpoints = strings.TrimSpace(endpoints)
+	if endpoints == "" || strings.EqualFold(endpoints, ClusterEndpointsTiUP) {
+		return nil
+	}
+	for _, e := range strings.Split(endpoints, ",") {
+		if _, _, err := SplitClusterEndpoint(strings.TrimSpace(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveClusterSqlPolicy returns the statement classes allowed to be executed in the interactive query mode of the cluster
+func ResolveClusterSqlPolicy(c *sqlite.Cluster) []string {
+	var classes []string
+	for _, p := range strings.Split(c.SqlPolicy, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && !stringutil.IsContainString(p, classes) {
+			classes = append(classes, p)
+		}
+	}
+	if len(classes) == 0 {
+		return DefaultSqlPolicy
+	}
+	return classes
+}
+
+// ValidateClusterSqlPolicy verifies the sql policy, the value is the statement classes read / write / ddl / admin separated by comma
+func ValidateClusterSqlPolicy(policy string) error {
+	for _, p := range strings.Split(policy, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if !stringutil.IsContainStringIgnoreCase(p, []string{SqlClassRead, SqlClassWrite, SqlClassDDL, SqlClassAdmin}) {
+			return fmt.Errorf("invalid sql policy class [%s], options: %s / %s / %s / %s separated by comma", p, SqlClassRead, SqlClassWrite, SqlClassDDL, SqlClassAdmin)
+		}
+	}
+	return nil
+}
+
+// SplitClusterEndpoint splits the tidb endpoint {host}:{port}
+func SplitClusterEndpoint(endpoint string) (string, uint64, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid tidb endpoint [%s], the format is {host}:{port}: %v", endpoint, err)
+	}
+	p, err := strconv.ParseUint(port, 10, 64)
+	if err != nil || host == "" {
+		return "", 0, fmt.Errorf("invalid tidb endpoint [%s], the format is {host}:{port}", endpoint)
+	}
+	return host, p, nil
+}
+
+// ResolveClusterPassword returns the plaintext password from the password provider or the encrypted password
+func ResolveClusterPassword(ctx context.Context, c *sqlite.Cluster) (string, error) {
+	if c.DbPasswordProvider != "" {
+		passwd, err := credential.Resolve(ctx, c.DbPasswordProvider)
+		if err != nil {
+			return "", fmt.Errorf("the cluster_name [%s] %v", c.ClusterName, err)
+		}
+		return passwd, nil
+	}
+	return DecryptClusterPassword(c)
+}
+
+// DecryptClusterPassword returns the plaintext password of the cluster, legacy plaintext records are returned as is
+func DecryptClusterPassword(c *sqlite.Cluster) (string, error) {
+	if !secret.IsEncrypted(c.DbPassword) {
+		return c.DbPassword, nil
+	}
+	key, err := secret.DefaultKeyring.MasterKey()
+	if err != nil {
+		return "", err
+	}
+	passwd, err := secret.Decrypt(key, c.DbPassword)
+	if err != nil {
+		return "", fmt.Errorf("the cluster_name [%s] password %v", c.ClusterName, err)
+	}
+	return passwd, nil
+}
+
+// PrepareClusterMasterKey resolves the master key in advance when the cluster password is encrypted,
+// so that the passphrase prompt does not conflict with the terminal ui
+func PrepareClusterMasterKey(ctx context.Context, clusterName string) error {
+	db, err := Connector.GetDatabase(DefaultSqliteClusterName)
+	if err != nil {
+		return fmt.Errorf("invalid cluster [%s] database connector: %v", DefaultSqliteClusterName, err)
+	}
+	c, err := db.(*sqlite.Database).GetCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if c.DbPasswordProvider != "" || !secret.IsEncrypted(c.DbPassword) {
+		return nil
+	}
+	_, err = secret.DefaultKeyring.MasterKey()
+	return err
+}
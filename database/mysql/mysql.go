@@ -17,26 +17,113 @@ package mysql
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/wentaojin/tidba/utils/stringutil"
 	"golang.org/x/sync/errgroup"
 )
 
 type Database struct {
-	DB *sql.DB
+	mutex sync.RWMutex
+	DB    *sql.DB
+	// Endpoint is the active tidb endpoint {host}:{port}
+	Endpoint string
+	dsns     []string
+	recorder *Recorder
 }
 
-func NewDatabase(ctx context.Context, dsn string) (*Database, error) {
+// MaxRecordedStatements limits the statements collected by the recorder, the continuous kill may execute a large number of statements
+const MaxRecordedStatements = 10000
+
+// Recorder collects the statements executed on the database and the objects affected by the statements, used by the operation audit
+type Recorder struct {
+	mutex      sync.Mutex
+	statements []string
+	objects    []string
+	omitted    int
+}
+
+// Statements returns the recorded statements, the statements exceeding the limit are counted only
+func (r *Recorder) Statements() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.omitted > 0 {
+		return append(append([]string{}, r.statements...), fmt.Sprintf("... %d more statements omitted", r.omitted))
+	}
+	return append([]string{}, r.statements...)
+}
+
+// Objects returns the recorded affected objects, e.g. the killed session ids or the created watch ids
+func (r *Recorder) Objects() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.objects...)
+}
+
+func (r *Recorder) addStatement(query string, args []any, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.statements) >= MaxRecordedStatements {
+		r.omitted++
+		return
+	}
+	if len(args) > 0 {
+		query = fmt.Sprintf("%s /* args: %v */", query, args)
+	}
+	if err != nil {
+		query = fmt.Sprintf("%s /* failed: %v */", query, err)
+	}
+	r.statements = append(r.statements, query)
+}
+
+func (r *Recorder) addObject(object string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.objects = append(r.objects, object)
+}
+
+// NewDatabase opens the database on the ordered endpoint dsns, the first endpoint that can be pinged becomes the active endpoint,
+// and the query switches to the other endpoints in order when the active endpoint connection is broken
+func NewDatabase(ctx context.Context, dsns ...string) (*Database, error) {
+	if len(dsns) == 0 {
+		return nil, fmt.Errorf("error on open mysql database connection: the database dsn is empty")
+	}
+	d := &Database{dsns: dsns}
+	var errs []string
+	for _, dsn := range dsns {
+		sqlDB, endpoint, err := openDatabase(ctx, dsn)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		d.DB = sqlDB
+		d.Endpoint = endpoint
+		return d, nil
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+}
+
+func openDatabase(ctx context.Context, dsn string) (*sql.DB, string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("error on parse mysql database dsn: %v", err)
+	}
 	sqlDB, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("error on open mysql database connection: %v", err)
+		return nil, cfg.Addr, fmt.Errorf("error on open mysql database [%s] connection: %v", cfg.Addr, err)
 	}
 
 	// SetMaxIdleConns sets the maximum number of Databaseions in the idle Databaseion pool.
@@ -46,17 +133,46 @@ func NewDatabase(ctx context.Context, dsn string) (*Database, error) {
 	// SetConnMaxLifetime sets the maximum amount of time a Databaseion may be reused.
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("ping the sqlite database error: [%s]", err)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, cfg.Addr, fmt.Errorf("ping the mysql database [%s] error: [%s]", cfg.Addr, err)
 	}
-	return &Database{DB: sqlDB}, nil
+	return sqlDB, cfg.Addr, nil
 }
 
 func BuildDatabaseDSN(username, password, host string, port uint64, charset string, connParams string) string {
 	if !strings.EqualFold(charset, "") {
 		connParams = fmt.Sprintf("charset=%s&%s", strings.ToLower(charset), connParams)
 	}
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/?%s", username, password, host, port, connParams)
+	return fmt.Sprintf("%s:%s@tcp(%s)/?%s", username, password, net.JoinHostPort(host, strconv.FormatUint(port, 10)), connParams)
+}
+
+// RegisterTLSConfig registers the tls client config with the mysql driver, the dsn uses it by the connect param tls={name}.
+// The server name is verified against the connected host when it is empty, so that the failover endpoints are verified separately
+func RegisterTLSConfig(name, caCert, clientCert, clientKey, serverName string, skipVerify bool) error {
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: skipVerify,
+	}
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return fmt.Errorf("read tls ca cert file [%s] failed: %v", caCert, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("append tls ca cert file [%s] failed: invalid pem certificate", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if clientCert != "" || clientKey != "" {
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return fmt.Errorf("load tls client cert file [%s] and key file [%s] failed: %v", clientCert, clientKey, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return mysql.RegisterTLSConfig(name, tlsConfig)
 }
 
 func (d *Database) GetDatabase() interface{} {
@@ -64,31 +180,202 @@ func (d *Database) GetDatabase() interface{} {
 }
 
 func (d *Database) CloseDatabase() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
 	return d.DB.Close()
 }
 
+// ActiveEndpoint returns the tidb endpoint currently used by the connection
+func (d *Database) ActiveEndpoint() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.Endpoint
+}
+
+// StartRecorder starts collecting the statements executed by ExecContext until StopRecorder is called
+func (d *Database) StartRecorder() *Recorder {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.recorder = &Recorder{}
+	return d.recorder
+}
+
+func (d *Database) StopRecorder() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.recorder = nil
+}
+
+// RecordObject records the object affected by the executed statements, it is ignored when the recorder is not started
+func (d *Database) RecordObject(object string) {
+	if r := d.getRecorder(); r != nil {
+		r.addObject(object)
+	}
+}
+
+func (d *Database) getRecorder() *Recorder {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.recorder
+}
+
+func (d *Database) getDB() *sql.DB {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.DB
+}
+
+// QueryContext retries the query once on the failover endpoint when the active endpoint connection is broken
 func (d *Database) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return d.DB.QueryContext(ctx, query, args...)
+	rows, err := d.getDB().QueryContext(ctx, query, args...)
+	if err != nil && d.failover(ctx, err) {
+		return d.getDB().QueryContext(ctx, query, args...)
+	}
+	return rows, err
 }
 
+// ExecContext switches to the failover endpoint when the active endpoint connection is broken,
+// but the statement is not retried, because it may have been executed before the connection was broken.
+// The statement is collected by the started recorder whether it succeeded or not
 func (d *Database) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return d.DB.ExecContext(ctx, query, args...)
+	res, err := d.getDB().ExecContext(ctx, query, args...)
+	if r := d.getRecorder(); r != nil {
+		r.addStatement(query, args, err)
+	}
+	if err != nil {
+		d.failover(ctx, err)
+	}
+	return res, err
 }
 
-func (d *Database) GeneralQuery(ctx context.Context, query string, args ...any) ([]string, []map[string]string, error) {
-	var (
-		columns []string
-		results []map[string]string
-	)
+// failover switches the active endpoint to the next endpoint that can be pinged, returns true if the active endpoint is available again
+func (d *Database) failover(ctx context.Context, err error) bool {
+	if len(d.dsns) <= 1 || !isConnectionError(err) {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// the active endpoint may have been switched by the concurrent query
+	if err := d.DB.PingContext(ctx); err == nil {
+		return true
+	}
+
+	active := 0
+	for i, dsn := range d.dsns {
+		if cfg, err := mysql.ParseDSN(dsn); err == nil && cfg.Addr == d.Endpoint {
+			active = i
+			break
+		}
+	}
+	for i := 1; i < len(d.dsns); i++ {
+		sqlDB, endpoint, err := openDatabase(ctx, d.dsns[(active+i)%len(d.dsns)])
+		if err != nil {
+			continue
+		}
+		// the old pool may still be used by the running query or the session connection, it is closed after drained
+		go retireDatabase(d.DB)
+		d.DB = sqlDB
+		d.Endpoint = endpoint
+		return true
+	}
+	return false
+}
+
+// retireInterval is the interval checking whether the connections of the pool replaced by the failover are returned
+const retireInterval = time.Second
+
+// retireDatabase closes the pool replaced by the failover once no connection is in use, the returned connections are closed
+// instead of kept idle. The pool is checked after the interval at first, so that the caller getting the old pool just before
+// the failover can still acquire the connection
+func retireDatabase(db *sql.DB) {
+	db.SetMaxIdleConns(0)
+	for {
+		time.Sleep(retireInterval)
+		if db.Stats().InUse == 0 {
+			break
+		}
+	}
+	db.Close()
+}
+
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ReadOnlyQuery runs the query inside the read only transaction on the connection taken from the active endpoint, so that the
+// write is refused by the database server, the transaction is always rolled back, see beginReadOnly
+func (d *Database) ReadOnlyQuery(ctx context.Context, query string, args ...any) ([]string, []map[string]string, error) {
+	conn, err := d.getDB().Conn(ctx)
+	if err != nil {
+		d.failover(ctx, err)
+		return nil, nil, fmt.Errorf("[Scope]\nTransaction\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer conn.Close()
+
+	tx, err := beginReadOnly(ctx, conn)
+	if err != nil {
+		d.failover(ctx, err)
+		return nil, nil, fmt.Errorf("[Scope]\nTransaction\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[Scope]\nStart\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer rows.Close()
+	return scanQueryRows(query, rows)
+}
 
+// beginReadOnly begins the READ ONLY transaction on the connection. The tidb implements the READ ONLY transaction as the noop
+// function refused with ER_NOT_SUPPORTED_YET unless tidb_enable_noop_functions is enabled, the session flag is turned on before
+// the transaction begins and turned off again inside the transaction, so that the other statements of the connection still
+// get the error of the noop functions. The read statement never runs outside the READ ONLY transaction
+func beginReadOnly(ctx context.Context, conn *sql.Conn) (*sql.Tx, error) {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if !isReadOnlyRefused(err) {
+		return tx, err
+	}
+	if _, err := conn.ExecContext(ctx, `SET SESSION tidb_enable_noop_functions = ON`); err != nil {
+		return nil, fmt.Errorf("enable the READ ONLY transaction by tidb_enable_noop_functions failed: %v", err)
+	}
+	tx, err = conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		conn.ExecContext(ctx, `SET SESSION tidb_enable_noop_functions = OFF`)
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `SET SESSION tidb_enable_noop_functions = OFF`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// isReadOnlyRefused returns whether the READ ONLY transaction is refused by the tidb with tidb_enable_noop_functions disabled
+func isReadOnlyRefused(err error) bool {
+	var myErr *mysql.MySQLError
+	return err != nil && errors.As(err, &myErr) && myErr.Number == 1235
+}
+
+func (d *Database) GeneralQuery(ctx context.Context, query string, args ...any) ([]string, []map[string]string, error) {
 	rows, err := d.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("[Scope]\nStart\n[Query]\n%v\n[Error]\n%v", query, err)
 	}
 	defer rows.Close()
+	return scanQueryRows(query, rows)
+}
+
+func scanQueryRows(query string, rows *sql.Rows) ([]string, []map[string]string, error) {
+	var results []map[string]string
 
 	// general query, automatic get column name
-	columns, err = rows.Columns()
+	columns, err := rows.Columns()
 	if err != nil {
 		return nil, nil, fmt.Errorf("[Scope]\nColumn\n[Query]\n%v\n[Error]\n%v", query, err)
 	}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// killConnAttempts is the number of the connections taken from the pool to find the one landing on the session tidb-server,
+// the tidb-servers behind the load balancer are usually selected in turn
+const killConnAttempts = 8
+
+// Session is the dedicated database connection of the interactive query, the session state such as USE is kept across the queries,
+// and the running query can be killed by the connection id from another connection landing on the same tidb-server
+type Session struct {
+	db     *Database
+	conn   *sql.Conn
+	ID     uint64
+	server string
+	broken bool
+}
+
+// NewSession takes the dedicated connection from the active endpoint connection pool
+func (d *Database) NewSession(ctx context.Context) (*Session, error) {
+	conn, err := d.getDB().Conn(ctx)
+	if err != nil && d.failover(ctx, err) {
+		conn, err = d.getDB().Conn(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var id uint64
+	if err := conn.QueryRowContext(ctx, `SELECT CONNECTION_ID()`).Scan(&id); err != nil {
+		conn.Close()
+		if d.failover(ctx, err) {
+			return d.NewSession(ctx)
+		}
+		return nil, err
+	}
+	server, err := serverIdentity(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Session{db: d, conn: conn, ID: id, server: server}, nil
+}
+
+// ReadOnlyQuery runs the query inside the read only transaction, so that the write is refused by the database server instead of
+// only the client side classification of the sql policy, see beginReadOnly. The transaction is always rolled back
+func (s *Session) ReadOnlyQuery(ctx context.Context, query string, args ...any) ([]string, []map[string]string, error) {
+	tx, err := beginReadOnly(ctx, s.conn)
+	if err != nil {
+		s.checkBroken(ctx, err)
+		return nil, nil, fmt.Errorf("[Scope]\nTransaction\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.checkBroken(ctx, err)
+		return nil, nil, fmt.Errorf("[Scope]\nStart\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer rows.Close()
+	columns, results, err := scanQueryRows(query, rows)
+	if err != nil {
+		s.checkBroken(ctx, err)
+	}
+	return columns, results, err
+}
+
+// ExecQuery executes the statement that may change the cluster state and returns the result set if any, e.g. SELECT ... FOR UPDATE.
+// Like ExecContext, the statement is not retried on the failover endpoint and is collected by the started recorder
+func (s *Session) ExecQuery(ctx context.Context, query string, args ...any) ([]string, []map[string]string, error) {
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if r := s.db.getRecorder(); r != nil {
+		r.addStatement(query, args, err)
+	}
+	if err != nil {
+		s.checkBroken(ctx, err)
+		return nil, nil, fmt.Errorf("[Scope]\nStart\n[Query]\n%v\n[Error]\n%v", query, err)
+	}
+	defer rows.Close()
+	columns, results, err := scanQueryRows(query, rows)
+	if err != nil {
+		s.checkBroken(ctx, err)
+	}
+	return columns, results, err
+}
+
+// KillQuery terminates the statement the session is executing by KILL TIDB QUERY on another connection, the session connection
+// is kept. The connection id is only unique on the tidb-server without the global kill, the connections are taken from the pool
+// until one lands on the session tidb-server, so that the query of the other session is never killed behind the load balancer
+func (s *Session) KillQuery(ctx context.Context) error {
+	var conns []*sql.Conn
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	// the taken connections are held, so that the pool opens the new connection for the next attempt
+	for i := 0; i < killConnAttempts; i++ {
+		conn, err := s.db.getDB().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("kill the query of the connection [%d] failed: %v", s.ID, err)
+		}
+		conns = append(conns, conn)
+		server, err := serverIdentity(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("kill the query of the connection [%d] failed: %v", s.ID, err)
+		}
+		if server != s.server {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("KILL TIDB QUERY %d", s.ID)); err != nil {
+			return fmt.Errorf("kill the query of the connection [%d] failed: %v", s.ID, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("kill the query of the connection [%d] failed: no connection lands on the session tidb-server [%s] in %d attempts, please run [KILL TIDB QUERY %d] on the tidb-server", s.ID, s.server, killConnAttempts, s.ID)
+}
+
+// Broken returns whether the session connection is unusable, the new session should be created
+func (s *Session) Broken() bool {
+	return s.broken
+}
+
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+// checkBroken marks the session broken when the connection is lost, the driver closes the connection when the context is canceled
+func (s *Session) checkBroken(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		s.broken = true
+		return
+	}
+	if errors.Is(err, sql.ErrConnDone) || isConnectionError(err) {
+		s.broken = true
+		s.db.failover(ctx, err)
+	}
+}
+
+// serverIdentity returns the tidb-server the connection lands on
+func serverIdentity(ctx context.Context, conn *sql.Conn) (string, error) {
+	var (
+		host string
+		port int
+	)
+	if err := conn.QueryRowContext(ctx, `SELECT @@hostname, @@port`).Scan(&host, &port); err != nil {
+		return "", fmt.Errorf("query the tidb-server of the connection failed: %v", err)
+	}
+	return fmt.Sprintf("%s:%d", host, port), nil
+}
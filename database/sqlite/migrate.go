@@ -0,0 +1,427 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned up-migration of the metadata database schema.
+// The migration must use the table snapshot structs declared inside the migration instead of the live table structs,
+// so that the later table structure changes do not affect the migrations that have been released.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrations are applied in order, append the new migration to the end and never modify the released migrations
+var migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "create the initial metadata tables",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				ID          uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_clus_cluster_name;comment:name of cluster"`
+				DbUser      string `gorm:"not null;type:varchar(100);comment:username of database"`
+				DbPassword  string `gorm:"not null;type:varchar(100);comment:password of database"`
+				DbHost      string `gorm:"type:varchar(30);comment:host of database"`
+				DbPort      uint64 `gorm:"type:int;comment:port of database"`
+				DbCharset   string `gorm:"type:varchar(30);comment:charset of database"`
+				ConnParams  string `gorm:"type:varchar(60);comment:connect params of database"`
+				Path        string `gorm:"not null;type:varchar(120);comment:metadata of cluster"`
+				PrivateKey  string `gorm:"not null;type:varchar(120);comment:metadata of cluster"`
+				*Entity
+			}
+			type inspect struct {
+				ID            uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName   string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_insp_cluster_name;comment:name of cluster"`
+				InspectConfig string `gorm:"not null;type:text;comment:config of cluster inspect"`
+				*Entity
+			}
+			type resourceGroup struct {
+				ID                uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName       string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_name;comment:name of cluster"`
+				ResourceGroupName string `gorm:"not null;type:varchar(120);comment:name of switch resource group"`
+				*Entity
+			}
+			type sqlBinding struct {
+				ID           uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName  string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:name of cluster"`
+				SchemaName   string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:schema name of sql digest"`
+				SqlDigest    string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:sql digest"`
+				DigestText   string `gorm:"not null;type:varchar(120);comment:sql digest text"`
+				OptimizeText string `gorm:"not null;type:varchar(120);comment:optimize sql digest text"`
+				*Entity
+			}
+			type license struct {
+				ID         uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				Username   string `gorm:"not null;type:varchar(120);comment:username"`
+				MacAddress string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_mac_address;comment:mac address"`
+				ExpireTime string `gorm:"not null;type:int;comment:license expire time"`
+				License    string `gorm:"not null;type:varchar(120);comment:license"`
+				*Entity
+			}
+			// the metadata database created by the old version is already existed, the tables are left as is
+			for table, model := range map[string]interface{}{
+				"clusters":        &cluster{},
+				"inspects":        &inspect{},
+				"resource_groups": &resourceGroup{},
+				"sql_bindings":    &sqlBinding{},
+				"licenses":        &license{},
+			} {
+				if tx.Migrator().HasTable(table) {
+					continue
+				}
+				if err := tx.Table(table).Migrator().CreateTable(model); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add the cluster password provider and widen the encrypted password",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				ClusterName        string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_clus_cluster_name;comment:name of cluster"`
+				DbPassword         string `gorm:"not null;type:varchar(500);comment:encrypted password of database"`
+				DbPasswordProvider string `gorm:"type:varchar(500);comment:password provider of database"`
+			}
+			m := tx.Table("clusters").Migrator()
+			if !m.HasColumn(&cluster{}, "DbPasswordProvider") {
+				if err := m.AddColumn(&cluster{}, "DbPasswordProvider"); err != nil {
+					return err
+				}
+			}
+			return alterColumns(tx, "clusters", &cluster{}, []string{"uniq_clus_cluster_name"}, "DbPassword")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "fix the license expire time type from int to datetime string",
+		Up: func(tx *gorm.DB) error {
+			type license struct {
+				MacAddress string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_mac_address;comment:mac address"`
+				ExpireTime string `gorm:"not null;type:varchar(30);comment:license expire time"`
+			}
+			return alterColumns(tx, "licenses", &license{}, []string{"uniq_mac_address"}, "ExpireTime")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "widen the sql binding digest text and optimize text",
+		Up: func(tx *gorm.DB) error {
+			type sqlBinding struct {
+				ClusterName  string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:name of cluster"`
+				SchemaName   string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:schema name of sql digest"`
+				SqlDigest    string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:sql digest"`
+				DigestText   string `gorm:"not null;type:text;comment:sql digest text"`
+				OptimizeText string `gorm:"not null;type:text;comment:optimize sql digest text"`
+			}
+			return alterColumns(tx, "sql_bindings", &sqlBinding{}, []string{"uniq_cluster_complex"}, "DigestText", "OptimizeText")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add the cluster failover endpoints",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				DbEndpoints string `gorm:"type:varchar(1000);comment:failover endpoints of database"`
+			}
+			m := tx.Table("clusters").Migrator()
+			if m.HasColumn(&cluster{}, "DbEndpoints") {
+				return nil
+			}
+			return m.AddColumn(&cluster{}, "DbEndpoints")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add the cluster tls connection and widen the connect params",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				ClusterName   string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_clus_cluster_name;comment:name of cluster"`
+				ConnParams    string `gorm:"type:varchar(500);comment:connect params of database"`
+				TlsCaCert     string `gorm:"type:varchar(500);comment:tls ca cert file of database"`
+				TlsClientCert string `gorm:"type:varchar(500);comment:tls client cert file of database"`
+				TlsClientKey  string `gorm:"type:varchar(500);comment:tls client key file of database"`
+				TlsServerName string `gorm:"type:varchar(300);comment:tls server name of database"`
+				TlsSkipVerify bool   `gorm:"not null;default:false;comment:tls skip verify of database"`
+			}
+			m := tx.Table("clusters").Migrator()
+			for _, f := range []string{"TlsCaCert", "TlsClientCert", "TlsClientKey", "TlsServerName", "TlsSkipVerify"} {
+				if m.HasColumn(&cluster{}, f) {
+					continue
+				}
+				if err := m.AddColumn(&cluster{}, f); err != nil {
+					return err
+				}
+			}
+			return alterColumns(tx, "clusters", &cluster{}, []string{"uniq_clus_cluster_name"}, "ConnParams")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add the cluster groups",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				Groups string `gorm:"type:varchar(500);comment:groups of cluster"`
+			}
+			m := tx.Table("clusters").Migrator()
+			if m.HasColumn(&cluster{}, "Groups") {
+				return nil
+			}
+			return m.AddColumn(&cluster{}, "Groups")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create the operation audit table",
+		Up: func(tx *gorm.DB) error {
+			type audit struct {
+				ID          uint64     `gorm:"primarykey;autoIncrement;comment:id"`
+				OsUser      string     `gorm:"not null;type:varchar(120);comment:os user of operation"`
+				ClusterName string     `gorm:"not null;type:varchar(120);index:idx_audit_cluster_name;comment:name of cluster"`
+				Command     string     `gorm:"not null;type:varchar(120);comment:command of operation"`
+				CommandLine string     `gorm:"not null;type:text;comment:command line of operation"`
+				Statements  string     `gorm:"type:mediumtext;comment:statements issued by operation"`
+				Objects     string     `gorm:"type:mediumtext;comment:objects affected by operation"`
+				StartTime   time.Time  `gorm:"not null;index:idx_audit_start_time;comment:start time of operation"`
+				EndTime     *time.Time `gorm:"comment:end time of operation"`
+				Status      string     `gorm:"not null;type:varchar(30);comment:status of operation"`
+				Error       string     `gorm:"type:text;comment:error of operation"`
+				*Entity
+			}
+			if tx.Migrator().HasTable("audits") {
+				return nil
+			}
+			return tx.Table("audits").Migrator().CreateTable(&audit{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add the cluster sql policy",
+		Up: func(tx *gorm.DB) error {
+			type cluster struct {
+				SqlPolicy string `gorm:"type:varchar(100);comment:sql policy of cluster"`
+			}
+			m := tx.Table("clusters").Migrator()
+			if m.HasColumn(&cluster{}, "SqlPolicy") {
+				return nil
+			}
+			return m.AddColumn(&cluster{}, "SqlPolicy")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create the interactive history table",
+		Up: func(tx *gorm.DB) error {
+			type history struct {
+				ID          uint64  `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName string  `gorm:"not null;type:varchar(120);index:idx_hist_cluster_name;comment:name of cluster"`
+				DbName      string  `gorm:"type:varchar(120);comment:database of statement"`
+				Statement   string  `gorm:"not null;type:mediumtext;comment:statement text"`
+				Delimiter   string  `gorm:"type:varchar(30);comment:delimiter of sql statement"`
+				Elapsed     float64 `gorm:"not null;default:0;comment:elapsed seconds of statement"`
+				RowCount    int64   `gorm:"not null;default:0;comment:row count of statement result"`
+				Error       string  `gorm:"type:text;comment:error of statement"`
+				*Entity
+			}
+			if tx.Migrator().HasTable("histories") {
+				return nil
+			}
+			return tx.Table("histories").Migrator().CreateTable(&history{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create the query snippet table",
+		Up: func(tx *gorm.DB) error {
+			type snippet struct {
+				ID          uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				SnippetName string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_snip_snippet_name;comment:name of snippet"`
+				Description string `gorm:"type:varchar(1000);comment:description of snippet"`
+				Content     string `gorm:"not null;type:mediumtext;comment:sql content of snippet"`
+				Params      string `gorm:"type:text;comment:params of snippet"`
+				Builtin     bool   `gorm:"not null;default:false;comment:builtin snippet seeded by tidba"`
+				*Entity
+			}
+			if tx.Migrator().HasTable("snippets") {
+				return nil
+			}
+			return tx.Table("snippets").Migrator().CreateTable(&snippet{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create the inspection run table",
+		Up: func(tx *gorm.DB) error {
+			type inspectRun struct {
+				ID               uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				ClusterName      string `gorm:"not null;type:varchar(120);index:idx_insp_run_cluster_name;comment:name of cluster"`
+				InspectionTime   string `gorm:"not null;type:varchar(30);comment:time of inspection"`
+				InspectionWindow string `gorm:"type:varchar(120);comment:time window of inspection"`
+				AbnormalCount    int64  `gorm:"not null;default:0;comment:count of abnormal summary items"`
+				ReportFile       string `gorm:"type:varchar(1000);comment:html report file of inspection"`
+				AbnormalFile     string `gorm:"type:varchar(1000);comment:excel abnormal file of inspection"`
+				Report           string `gorm:"not null;type:longtext;comment:json report of inspection"`
+				*Entity
+			}
+			if tx.Migrator().HasTable("inspect_runs") {
+				return nil
+			}
+			return tx.Table("inspect_runs").Migrator().CreateTable(&inspectRun{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create the builtin revision table",
+		Up: func(tx *gorm.DB) error {
+			type builtinRevision struct {
+				ID       uint64 `gorm:"primarykey;autoIncrement;comment:id"`
+				Name     string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_buil_name;comment:name of builtin records"`
+				Revision uint64 `gorm:"not null;default:0;comment:revision of builtin records"`
+				*Entity
+			}
+			if tx.Migrator().HasTable("builtin_revisions") {
+				return nil
+			}
+			return tx.Table("builtin_revisions").Migrator().CreateTable(&builtinRevision{})
+		},
+	},
+}
+
+// alterColumns modifies the column definitions of the snapshot struct. The sqlite alters the column by recreating the table,
+// and the indexes of the table are lost, so the indexes declared by the snapshot struct are recreated afterwards
+func alterColumns(tx *gorm.DB, table string, model interface{}, indexes []string, fields ...string) error {
+	m := tx.Table(table).Migrator()
+	for _, f := range fields {
+		if err := m.AlterColumn(model, f); err != nil {
+			return err
+		}
+	}
+	for _, idx := range indexes {
+		if m.HasIndex(model, idx) {
+			continue
+		}
+		if err := m.CreateIndex(model, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LatestSchemaVersion returns the metadata schema version supported by the current binary
+func LatestSchemaVersion() uint64 {
+	return migrations[len(migrations)-1].Version
+}
+
+func (d *Database) SchemaMigrationTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(SchemaMigration{}).Name())
+}
+
+// Migrate applies the pending up-migrations in order, each migration and its version record are committed in the same transaction.
+// The metadata database migrated by a newer binary is refused, because the old binary cannot understand the newer schema.
+// The mysql ddl commits implicitly and cannot be rolled back, so the migrations must be idempotent to be re-applied after a failure.
+func (d *Database) Migrate(ctx context.Context) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err := d.DB.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("migrate table [%s] failed: %v", d.SchemaMigrationTableName(ctx), err)
+	}
+
+	current, err := d.currentSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("the metadata database schema version [%d] is newer than the version [%d] supported by the current tidba, please upgrade tidba", current, LatestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := d.DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version: m.Version,
+				Name:    m.Name,
+			}).Error
+		}); err != nil {
+			return fmt.Errorf("apply the metadata schema migration [%d: %s] failed: %v", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus returns the applied status of all migrations known by the current binary,
+// and the migrations applied by a newer binary are appended at the end
+func (d *Database) MigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var applied []*SchemaMigration
+	if err := d.DB.Model(&SchemaMigration{}).Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("list table [%s] record failed: %v", d.SchemaMigrationTableName(ctx), err)
+	}
+	appliedMap := make(map[uint64]*SchemaMigration)
+	for _, a := range applied {
+		appliedMap[a.Version] = a
+	}
+
+	var status []*MigrationStatus
+	for _, m := range migrations {
+		s := &MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := appliedMap[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+		}
+		status = append(status, s)
+	}
+	for _, a := range applied {
+		if a.Version > LatestSchemaVersion() {
+			status = append(status, &MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt})
+		}
+	}
+	return status, nil
+}
+
+func (d *Database) currentSchemaVersion() (uint64, error) {
+	var version uint64
+	if err := d.DB.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("get the metadata schema version failed: %v", err)
+	}
+	return version, nil
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -33,11 +34,21 @@ type Database struct {
 }
 
 func NewDatabase(dbPath string) (*Database, error) {
-	sqlitedb, err := gorm.Open(sqlite.Open(fmt.Sprintf("%s/tidba.db", dbPath)), &gorm.Config{})
+	return openDatabase("sqlite", sqlite.Open(fmt.Sprintf("%s/tidba.db", dbPath)))
+}
+
+// NewMySQLDatabase opens the metadata database stored in the remote mysql / tidb schema, which is shared by the tidba of multiple hosts.
+// The metadata tables and the crud operations are the same as the local sqlite, only the gorm dialector is different
+func NewMySQLDatabase(dsn string) (*Database, error) {
+	return openDatabase("mysql", mysql.Open(dsn))
+}
+
+func openDatabase(dbType string, dialector gorm.Dialector) (*Database, error) {
+	gormdb, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("open the %s metadata database error: [%s]", dbType, err)
 	}
-	sqlDB, err := sqlitedb.DB()
+	sqlDB, err := gormdb.DB()
 	if err != nil {
 		return nil, err
 	}
@@ -50,20 +61,14 @@ func NewDatabase(dbPath string) (*Database, error) {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("ping the sqlite database error: [%s]", err)
+		return nil, fmt.Errorf("ping the %s metadata database error: [%s]", dbType, err)
 	}
 
-	if err := sqlitedb.AutoMigrate(
-		&Cluster{},
-		&Inspect{},
-		&ResourceGroup{},
-		&SqlBinding{},
-		&License{},
-	); err != nil {
-		return nil, fmt.Errorf("migrate the sqlite table error: [%s]", err)
+	db := &Database{DB: gormdb}
+	if err := db.Migrate(context.Background()); err != nil {
+		return nil, fmt.Errorf("migrate the %s metadata table error: [%s]", dbType, err)
 	}
-
-	return &Database{DB: sqlitedb}, nil
+	return db, nil
 }
 
 func (d *Database) GetDatabase() interface{} {
@@ -99,6 +104,8 @@ func (d *Database) CreateCluster(ctx context.Context, data *Cluster) (*Cluster,
 	return data, nil
 }
 
+// DeleteCluster deletes the cluster and its inspect, resource group, sql binding, history and inspection run records in one
+// transaction, the operation audits are kept
 func (d *Database) DeleteCluster(ctx context.Context, clusterName string) (*Cluster, error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -109,10 +116,28 @@ func (d *Database) DeleteCluster(ctx context.Context, clusterName string) (*Clus
 		if err != nil {
 			return fmt.Errorf("get table [%s] record failed: %v", d.ClusterTableName(ctx), err)
 		}
+		if data.ID == 0 {
+			return nil
+		}
 		err = tx.Where("cluster_name = ?", clusterName).Delete(&Cluster{}).Error
 		if err != nil {
 			return fmt.Errorf("delete table [%s] record failed: %v", d.ClusterTableName(ctx), err)
 		}
+		if err := tx.Where("cluster_name = ?", clusterName).Delete(&Inspect{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.InspectTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", clusterName).Delete(&ResourceGroup{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.ResourceGroupTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", clusterName).Delete(&SqlBinding{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.SqlBindingTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", clusterName).Delete(&History{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.HistoryTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", clusterName).Delete(&InspectRun{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.InspectRunTableName(ctx), err)
+		}
 		return nil
 	}); err != nil {
 		return data, err
@@ -120,6 +145,50 @@ func (d *Database) DeleteCluster(ctx context.Context, clusterName string) (*Clus
 	return data, nil
 }
 
+// ImportCluster writes the cluster and its inspect, resource group and sql binding records in one transaction, the existed
+// inspect, resource group and sql binding records of the cluster are deleted before, so that the overwritten cluster does not
+// keep the stale records
+func (d *Database) ImportCluster(ctx context.Context, c *Cluster, inspect *Inspect, rg *ResourceGroup, bindings []*SqlBinding) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cluster_name = ?", c.ClusterName).Delete(&Inspect{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.InspectTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", c.ClusterName).Delete(&ResourceGroup{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.ResourceGroupTableName(ctx), err)
+		}
+		if err := tx.Where("cluster_name = ?", c.ClusterName).Delete(&SqlBinding{}).Error; err != nil {
+			return fmt.Errorf("delete table [%s] record failed: %v", d.SqlBindingTableName(ctx), err)
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cluster_name"}},
+			UpdateAll: true,
+		}).Create(c).Error
+		if err != nil {
+			return fmt.Errorf("create table [%s] record failed: %v", d.ClusterTableName(ctx), err)
+		}
+		if inspect != nil {
+			if err := tx.Create(inspect).Error; err != nil {
+				return fmt.Errorf("create table [%s] record failed: %v", d.InspectTableName(ctx), err)
+			}
+		}
+		if rg != nil {
+			if err := tx.Create(rg).Error; err != nil {
+				return fmt.Errorf("create table [%s] record failed: %v", d.ResourceGroupTableName(ctx), err)
+			}
+		}
+		for _, b := range bindings {
+			if err := tx.Create(b).Error; err != nil {
+				return fmt.Errorf("create table [%s] record failed: %v", d.SqlBindingTableName(ctx), err)
+			}
+		}
+		return nil
+	})
+}
+
 func (d *Database) UpdateCluster(ctx context.Context, clusterName string, updates map[string]interface{}) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -161,6 +230,35 @@ func (d *Database) ListCluster(ctx context.Context, page uint64, pageSize uint64
 	return dataS, nil
 }
 
+// RotateClusterPassword rewrites the password of every cluster record in a single transaction
+func (d *Database) RotateClusterPassword(ctx context.Context, rotate func(password string) (string, error)) ([]*Cluster, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var dataS []*Cluster
+	if err := d.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Cluster{}).Find(&dataS).Error
+		if err != nil {
+			return fmt.Errorf("list table [%s] record failed: %v", d.ClusterTableName(ctx), err)
+		}
+		for _, c := range dataS {
+			passwd, err := rotate(c.DbPassword)
+			if err != nil {
+				return fmt.Errorf("rotate cluster_name [%s] password failed: %v", c.ClusterName, err)
+			}
+			err = tx.Model(&Cluster{}).Where("cluster_name = ?", c.ClusterName).Update("db_password", passwd).Error
+			if err != nil {
+				return fmt.Errorf("update table [%s] record failed: %v", d.ClusterTableName(ctx), err)
+			}
+			c.DbPassword = passwd
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return dataS, nil
+}
+
 func (d *Database) InspectTableName(ctx context.Context) string {
 	return d.DB.NamingStrategy.TableName(reflect.TypeOf(Inspect{}).Name())
 }
@@ -396,3 +494,314 @@ func (d *Database) UpdateLicense(ctx context.Context, macAddr string, updates ma
 	}
 	return nil
 }
+
+func (d *Database) AuditTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(Audit{}).Name())
+}
+
+func (d *Database) CreateAudit(ctx context.Context, data *Audit) (*Audit, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Create(data).Error
+	if err != nil {
+		return nil, fmt.Errorf("create table [%s] record failed: %v", d.AuditTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) UpdateAudit(ctx context.Context, id uint64, updates map[string]interface{}) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Model(&Audit{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("update table [%s] record failed: %v", d.AuditTableName(ctx), err)
+	}
+	return nil
+}
+
+func (d *Database) GetAudit(ctx context.Context, id uint64) (*Audit, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data *Audit
+	err := d.DB.Model(&Audit{}).Where("id = ?", id).Find(&data).Limit(1).Error
+	if err != nil {
+		return nil, fmt.Errorf("get table [%s] record failed: %v", d.AuditTableName(ctx), err)
+	}
+	return data, nil
+}
+
+// FindAudit returns the latest audit records in descending order of the start time, the empty filter is ignored,
+// and the command filter matches the command and its subcommands, e.g. kill matches kill sql and kill user
+func (d *Database) FindAudit(ctx context.Context, clusterName, command string, startTime, endTime time.Time, limit int) ([]*Audit, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	query := d.DB.Model(&Audit{})
+	if clusterName != "" {
+		query = query.Where("cluster_name = ?", clusterName)
+	}
+	if command != "" {
+		query = query.Where("(command = ? OR command LIKE ?)", command, command+" %")
+	}
+	if !startTime.IsZero() {
+		query = query.Where("start_time >= ?", startTime)
+	}
+	if !endTime.IsZero() {
+		query = query.Where("start_time <= ?", endTime)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var data []*Audit
+	err := query.Order("start_time DESC").Order("id DESC").Find(&data).Error
+	if err != nil {
+		return nil, fmt.Errorf("find table [%s] record failed: %v", d.AuditTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) HistoryTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(History{}).Name())
+}
+
+func (d *Database) CreateHistory(ctx context.Context, data *History) (*History, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Create(data).Error
+	if err != nil {
+		return nil, fmt.Errorf("create table [%s] record failed: %v", d.HistoryTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) GetHistory(ctx context.Context, id uint64) (*History, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data *History
+	err := d.DB.Model(&History{}).Where("id = ?", id).Find(&data).Limit(1).Error
+	if err != nil {
+		return nil, fmt.Errorf("get table [%s] record failed: %v", d.HistoryTableName(ctx), err)
+	}
+	return data, nil
+}
+
+// FindHistory returns the latest history records in descending order of the id, the empty filter is ignored,
+// and the grep filter matches the statement containing the text
+func (d *Database) FindHistory(ctx context.Context, clusterName, grep string, limit int) ([]*History, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	query := d.DB.Model(&History{})
+	if clusterName != "" {
+		query = query.Where("cluster_name = ?", clusterName)
+	}
+	if grep != "" {
+		query = query.Where("statement LIKE ?", "%"+grep+"%")
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var data []*History
+	err := query.Order("id DESC").Find(&data).Error
+	if err != nil {
+		return nil, fmt.Errorf("find table [%s] record failed: %v", d.HistoryTableName(ctx), err)
+	}
+	return data, nil
+}
+
+// PruneHistory deletes the history records older than the latest keep records, so that the history table does not grow forever
+func (d *Database) PruneHistory(ctx context.Context, keep int) (int64, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var ids []uint64
+	err := d.DB.Model(&History{}).Order("id DESC").Offset(keep).Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("find table [%s] record failed: %v", d.HistoryTableName(ctx), err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res := d.DB.Where("id <= ?", ids[0]).Delete(&History{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("delete table [%s] record failed: %v", d.HistoryTableName(ctx), res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+func (d *Database) SnippetTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(Snippet{}).Name())
+}
+
+func (d *Database) CreateSnippet(ctx context.Context, data *Snippet) (*Snippet, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Create(data).Error
+	if err != nil {
+		return nil, fmt.Errorf("create table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) GetSnippet(ctx context.Context, snippetName string) (*Snippet, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data *Snippet
+	err := d.DB.Model(&Snippet{}).Where("snippet_name = ?", snippetName).Find(&data).Limit(1).Error
+	if err != nil {
+		return nil, fmt.Errorf("get table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) ListSnippet(ctx context.Context) ([]*Snippet, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data []*Snippet
+	err := d.DB.Model(&Snippet{}).Order("snippet_name").Find(&data).Error
+	if err != nil {
+		return nil, fmt.Errorf("find table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) DeleteSnippet(ctx context.Context, snippetName string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Where("snippet_name = ?", snippetName).Delete(&Snippet{}).Error
+	if err != nil {
+		return fmt.Errorf("delete table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+	}
+	return nil
+}
+
+func (d *Database) BuiltinRevisionTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(BuiltinRevision{}).Name())
+}
+
+// GetBuiltinRevision returns the revision of the builtin records seeded, the revision is 0 if the records are never seeded
+func (d *Database) GetBuiltinRevision(ctx context.Context, name string) (uint64, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data *BuiltinRevision
+	err := d.DB.Model(&BuiltinRevision{}).Where("name = ?", name).Find(&data).Limit(1).Error
+	if err != nil {
+		return 0, fmt.Errorf("get table [%s] record failed: %v", d.BuiltinRevisionTableName(ctx), err)
+	}
+	return data.Revision, nil
+}
+
+// SaveBuiltinSnippets seeds the builtin snippets of the revision if the seeded revision is older, the missing builtin snippets
+// are created and the existing builtin snippets are refreshed. The snippet created by the user with the builtin name is kept
+// as the user snippet renamed to user-{name}-{id}, so that it does not shadow the builtin snippet
+func (d *Database) SaveBuiltinSnippets(ctx context.Context, revision uint64, data []*Snippet) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var rev *BuiltinRevision
+		err := tx.Model(&BuiltinRevision{}).Where("name = ?", BuiltinSnippets).Find(&rev).Limit(1).Error
+		if err != nil {
+			return fmt.Errorf("get table [%s] record failed: %v", d.BuiltinRevisionTableName(ctx), err)
+		}
+		// the builtin snippets are seeded by the same or the newer tidba concurrently
+		if rev.Revision >= revision {
+			return nil
+		}
+
+		for _, s := range data {
+			var exist *Snippet
+			err := tx.Model(&Snippet{}).Where("snippet_name = ?", s.SnippetName).Find(&exist).Limit(1).Error
+			if err != nil {
+				return fmt.Errorf("get table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+			}
+			switch {
+			case exist.ID == 0:
+				if err := tx.Create(s).Error; err != nil {
+					return fmt.Errorf("create table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+				}
+			case !exist.Builtin:
+				err := tx.Model(&Snippet{}).Where("id = ?", exist.ID).Update("snippet_name", fmt.Sprintf("user-%s-%d", exist.SnippetName, exist.ID)).Error
+				if err != nil {
+					return fmt.Errorf("update table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+				}
+				if err := tx.Create(s).Error; err != nil {
+					return fmt.Errorf("create table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+				}
+			case exist.Content != s.Content || exist.Description != s.Description || exist.Params != s.Params:
+				err := tx.Model(&Snippet{}).Where("id = ?", exist.ID).Updates(map[string]interface{}{
+					"description": s.Description,
+					"content":     s.Content,
+					"params":      s.Params,
+				}).Error
+				if err != nil {
+					return fmt.Errorf("update table [%s] record failed: %v", d.SnippetTableName(ctx), err)
+				}
+			}
+		}
+
+		if rev.ID == 0 {
+			err = tx.Create(&BuiltinRevision{Name: BuiltinSnippets, Revision: revision}).Error
+		} else {
+			err = tx.Model(&BuiltinRevision{}).Where("id = ?", rev.ID).Update("revision", revision).Error
+		}
+		if err != nil {
+			return fmt.Errorf("save table [%s] record failed: %v", d.BuiltinRevisionTableName(ctx), err)
+		}
+		return nil
+	})
+}
+
+func (d *Database) InspectRunTableName(ctx context.Context) string {
+	return d.DB.NamingStrategy.TableName(reflect.TypeOf(InspectRun{}).Name())
+}
+
+func (d *Database) CreateInspectRun(ctx context.Context, data *InspectRun) (*InspectRun, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	err := d.DB.Create(data).Error
+	if err != nil {
+		return nil, fmt.Errorf("create table [%s] record failed: %v", d.InspectRunTableName(ctx), err)
+	}
+	return data, nil
+}
+
+func (d *Database) GetInspectRun(ctx context.Context, id uint64) (*InspectRun, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var data *InspectRun
+	err := d.DB.Model(&InspectRun{}).Where("id = ?", id).Find(&data).Limit(1).Error
+	if err != nil {
+		return nil, fmt.Errorf("get table [%s] record failed: %v", d.InspectRunTableName(ctx), err)
+	}
+	return data, nil
+}
+
+// FindInspectRun returns the latest inspection runs in descending order of the id without the report content,
+// the empty cluster name is ignored
+func (d *Database) FindInspectRun(ctx context.Context, clusterName string, limit int) ([]*InspectRun, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	query := d.DB.Model(&InspectRun{}).Omit("report")
+	if clusterName != "" {
+		query = query.Where("cluster_name = ?", clusterName)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var data []*InspectRun
+	err := query.Order("id DESC").Find(&data).Error
+	if err != nil {
+		return nil, fmt.Errorf("find table [%s] record failed: %v", d.InspectRunTableName(ctx), err)
+	}
+	return data, nil
+}
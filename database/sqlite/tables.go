@@ -17,7 +17,10 @@ package sqlite
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
+
+	"github.com/wentaojin/tidba/utils/secret"
 )
 
 type Entity struct {
@@ -30,18 +33,60 @@ type Cluster struct {
 	ID          uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
 	ClusterName string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_clus_cluster_name;comment:name of cluster" json:"clusterName"`
 	DbUser      string `gorm:"not null;type:varchar(100);comment:username of database" json:"dbUser"`
-	DbPassword  string `gorm:"not null;type:varchar(100);comment:password of database" json:"dbPassword"`
-	DbHost      string `gorm:"type:varchar(30);comment:host of database" json:"dbHost"`
-	DbPort      uint64 `gorm:"type:int;comment:port of database" json:"dbPort"`
+	DbPassword  string `gorm:"not null;type:varchar(500);comment:encrypted password of database" json:"dbPassword"`
+	// DbPasswordProvider references the password source outside tidba, e.g. env:{name}, file:{path}, exec:{command}, takes precedence over DbPassword
+	DbPasswordProvider string `gorm:"type:varchar(500);comment:password provider of database" json:"dbPasswordProvider"`
+	DbHost             string `gorm:"type:varchar(30);comment:host of database" json:"dbHost"`
+	DbPort             uint64 `gorm:"type:int;comment:port of database" json:"dbPort"`
+	// DbEndpoints is the ordered failover tidb endpoints {host}:{port} separated by comma tried after DbHost:DbPort,
+	// or tiup which means all tidb instances of the tiup cluster topology
+	DbEndpoints string `gorm:"type:varchar(1000);comment:failover endpoints of database" json:"dbEndpoints"`
 	DbCharset   string `gorm:"type:varchar(30);comment:charset of database" json:"dbCharset"`
-	ConnParams  string `gorm:"type:varchar(60);comment:connect params of database" json:"connParams"`
-	Path        string `gorm:"not null;type:varchar(120);comment:metadata of cluster" json:"path"`
-	PrivateKey  string `gorm:"not null;type:varchar(120);comment:metadata of cluster" json:"privateKey"`
+	ConnParams  string `gorm:"type:varchar(500);comment:connect params of database" json:"connParams"`
+	// the tls client connection of database, the certificate files are located on the tidba host
+	TlsCaCert     string `gorm:"type:varchar(500);comment:tls ca cert file of database" json:"tlsCaCert"`
+	TlsClientCert string `gorm:"type:varchar(500);comment:tls client cert file of database" json:"tlsClientCert"`
+	TlsClientKey  string `gorm:"type:varchar(500);comment:tls client key file of database" json:"tlsClientKey"`
+	TlsServerName string `gorm:"type:varchar(300);comment:tls server name of database" json:"tlsServerName"`
+	TlsSkipVerify bool   `gorm:"not null;default:false;comment:tls skip verify of database" json:"tlsSkipVerify"`
+	Path          string `gorm:"not null;type:varchar(120);comment:metadata of cluster" json:"path"`
+	PrivateKey    string `gorm:"not null;type:varchar(120);comment:metadata of cluster" json:"privateKey"`
+	// Groups is the cluster group names separated by comma, used to select the clusters of the fan-out execution
+	Groups string `gorm:"type:varchar(500);comment:groups of cluster" json:"groups"`
+	// SqlPolicy is the statement classes read / write / ddl / admin separated by comma allowed in the interactive query mode, read only by default
+	SqlPolicy string `gorm:"type:varchar(100);comment:sql policy of cluster" json:"sqlPolicy"`
 	*Entity
 }
 
+// GroupNames returns the cluster group names
+func (c *Cluster) GroupNames() []string {
+	var groups []string
+	for _, g := range strings.Split(c.Groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// TlsEnabled returns whether the database connection uses tls
+func (c *Cluster) TlsEnabled() bool {
+	return c.TlsCaCert != "" || c.TlsClientCert != "" || c.TlsSkipVerify
+}
+
+// MaskedPassword returns the displayed password, the provider spec is not sensitive and returned as is
+func (c *Cluster) MaskedPassword() string {
+	if c.DbPasswordProvider != "" {
+		return c.DbPasswordProvider
+	}
+	return secret.Mask(c.DbPassword)
+}
+
+// String returns the cluster json content, the password is always masked
 func (c *Cluster) String() string {
-	val, _ := json.MarshalIndent(c, "", " ")
+	masked := *c
+	masked.DbPassword = secret.Mask(c.DbPassword)
+	val, _ := json.MarshalIndent(&masked, "", " ")
 	return string(val)
 }
 
@@ -69,8 +114,8 @@ type SqlBinding struct {
 	ClusterName  string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:name of cluster" json:"clusterName"`
 	SchemaName   string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:schema name of sql digest" json:"schemaName"`
 	SqlDigest    string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_cluster_complex;comment:sql digest" json:"sqlDigest"`
-	DigestText   string `gorm:"not null;type:varchar(120);comment:sql digest text" json:"digestText"`
-	OptimizeText string `gorm:"not null;type:varchar(120);comment:optimize sql digest text" json:"OptimizeText"`
+	DigestText   string `gorm:"not null;type:text;comment:sql digest text" json:"digestText"`
+	OptimizeText string `gorm:"not null;type:text;comment:optimize sql digest text" json:"OptimizeText"`
 	*Entity
 }
 
@@ -83,7 +128,7 @@ type License struct {
 	ID         uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
 	Username   string `gorm:"not null;type:varchar(120);comment:username" json:"username"`
 	MacAddress string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_mac_address;comment:mac address" json:"macAddress"`
-	ExpireTime string `gorm:"not null;type:int;comment:license expire time" json:"expireTime"`
+	ExpireTime string `gorm:"not null;type:varchar(30);comment:license expire time" json:"expireTime"`
 	License    string `gorm:"not null;type:varchar(120);comment:license" json:"License"`
 	*Entity
 }
@@ -92,3 +137,100 @@ func (i *License) String() string {
 	val, _ := json.MarshalIndent(i, "", " ")
 	return string(val)
 }
+
+// Audit records the mutating operation executed on the cluster, e.g. kill session, runaway watch and sql binding
+type Audit struct {
+	ID          uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
+	OsUser      string `gorm:"not null;type:varchar(120);comment:os user of operation" json:"osUser"`
+	ClusterName string `gorm:"not null;type:varchar(120);index:idx_audit_cluster_name;comment:name of cluster" json:"clusterName"`
+	Command     string `gorm:"not null;type:varchar(120);comment:command of operation" json:"command"`
+	CommandLine string `gorm:"not null;type:text;comment:command line of operation" json:"commandLine"`
+	// Statements and Objects are separated by newline
+	Statements string     `gorm:"type:mediumtext;comment:statements issued by operation" json:"statements"`
+	Objects    string     `gorm:"type:mediumtext;comment:objects affected by operation" json:"objects"`
+	StartTime  time.Time  `gorm:"not null;index:idx_audit_start_time;comment:start time of operation" json:"startTime"`
+	EndTime    *time.Time `gorm:"comment:end time of operation" json:"endTime"`
+	Status     string     `gorm:"not null;type:varchar(30);comment:status of operation" json:"status"`
+	Error      string     `gorm:"type:text;comment:error of operation" json:"error"`
+	*Entity
+}
+
+func (i *Audit) String() string {
+	val, _ := json.MarshalIndent(i, "", " ")
+	return string(val)
+}
+
+// History records the input executed by the interactive command line, the sql statement or the tidba command
+type History struct {
+	ID          uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
+	ClusterName string `gorm:"not null;type:varchar(120);index:idx_hist_cluster_name;comment:name of cluster" json:"clusterName"`
+	DbName      string `gorm:"type:varchar(120);comment:database of statement" json:"dbName"`
+	Statement   string `gorm:"not null;type:mediumtext;comment:statement text" json:"statement"`
+	// Delimiter is empty for the tidba command
+	Delimiter string  `gorm:"type:varchar(30);comment:delimiter of sql statement" json:"delimiter"`
+	Elapsed   float64 `gorm:"not null;default:0;comment:elapsed seconds of statement" json:"elapsed"`
+	RowCount  int64   `gorm:"not null;default:0;comment:row count of statement result" json:"rowCount"`
+	Error     string  `gorm:"type:text;comment:error of statement" json:"error"`
+	*Entity
+}
+
+func (i *History) String() string {
+	val, _ := json.MarshalIndent(i, "", " ")
+	return string(val)
+}
+
+// Snippet stores the saved query, the placeholders ${name} of the content are replaced by the params when the snippet is run
+type Snippet struct {
+	ID          uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
+	SnippetName string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_snip_snippet_name;comment:name of snippet" json:"snippetName"`
+	Description string `gorm:"type:varchar(1000);comment:description of snippet" json:"description"`
+	Content     string `gorm:"not null;type:mediumtext;comment:sql content of snippet" json:"content"`
+	// Params is the json array of the placeholder definitions, see snippet.Param
+	Params  string `gorm:"type:text;comment:params of snippet" json:"params"`
+	Builtin bool   `gorm:"not null;default:false;comment:builtin snippet seeded by tidba" json:"builtin"`
+	*Entity
+}
+
+func (i *Snippet) String() string {
+	val, _ := json.MarshalIndent(i, "", " ")
+	return string(val)
+}
+
+// InspectRun records the structured report of the inspection run, the runs are compared by [inspect diff]
+type InspectRun struct {
+	ID               uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
+	ClusterName      string `gorm:"not null;type:varchar(120);index:idx_insp_run_cluster_name;comment:name of cluster" json:"clusterName"`
+	InspectionTime   string `gorm:"not null;type:varchar(30);comment:time of inspection" json:"inspectionTime"`
+	InspectionWindow string `gorm:"type:varchar(120);comment:time window of inspection" json:"inspectionWindow"`
+	AbnormalCount    int64  `gorm:"not null;default:0;comment:count of abnormal summary items" json:"abnormalCount"`
+	ReportFile       string `gorm:"type:varchar(1000);comment:html report file of inspection" json:"reportFile"`
+	AbnormalFile     string `gorm:"type:varchar(1000);comment:excel abnormal file of inspection" json:"abnormalFile"`
+	// Report is the json of the structured inspection report, see inspect.Report
+	Report string `gorm:"not null;type:longtext;comment:json report of inspection" json:"report"`
+	*Entity
+}
+
+func (i *InspectRun) String() string {
+	val, _ := json.MarshalIndent(i, "", " ")
+	return string(val)
+}
+
+// BuiltinSnippets is the builtin revision name of the builtin snippets
+const BuiltinSnippets = "snippets"
+
+// BuiltinRevision records the revision of the builtin records seeded by tidba, e.g. the builtin snippets, the records are
+// seeded again only by the tidba of the newer revision, so that the tidba of the different versions sharing the metadata
+// database do not overwrite each other
+type BuiltinRevision struct {
+	ID       uint64 `gorm:"primarykey;autoIncrement;comment:id" json:"id"`
+	Name     string `gorm:"not null;type:varchar(120);uniqueIndex:uniq_buil_name;comment:name of builtin records" json:"name"`
+	Revision uint64 `gorm:"not null;default:0;comment:revision of builtin records" json:"revision"`
+	*Entity
+}
+
+// SchemaMigration records the applied metadata schema migrations, the max version is the current schema version
+type SchemaMigration struct {
+	Version   uint64    `gorm:"primarykey;autoIncrement:false;comment:schema version" json:"version"`
+	Name      string    `gorm:"not null;type:varchar(300);comment:migration name" json:"name"`
+	AppliedAt time.Time `gorm:"autoCreateTime;comment:migration applied time" json:"appliedAt"`
+}
@@ -14,6 +14,7 @@ require (
 	golang.org/x/crypto v0.32.0
 	golang.org/x/sync v0.11.0
 	golang.org/x/sys v0.30.0
+	gorm.io/driver/mysql v1.5.7
 	gorm.io/gorm v1.25.12
 )
 
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/mysql"
+	"github.com/wentaojin/tidba/database/sqlite"
+)
+
+const (
+	AuditStatusRunning = "running"
+	AuditStatusSuccess = "success"
+	AuditStatusFailed  = "failed"
+)
+
+// Auditor records the execution of the mutating command in the audit table, the statements executed on the cluster database
+// between AuditStart and Finish are collected by the database recorder
+type Auditor struct {
+	meta     *sqlite.Database
+	db       *mysql.Database
+	recorder *mysql.Recorder
+	audit    *sqlite.Audit
+}
+
+// AuditStart creates the running audit record before the command is executed, so that the record is left
+// even if the process exits abnormally. The cluster database connection failure is recorded as the failed operation
+func AuditStart(ctx context.Context, clusterName, command, commandLine string) (*Auditor, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	a := &Auditor{meta: metaDB.(*sqlite.Database)}
+
+	a.audit, err = a.meta.CreateAudit(ctx, &sqlite.Audit{
+		OsUser:      auditOsUser(),
+		ClusterName: clusterName,
+		Command:     command,
+		CommandLine: commandLine,
+		StartTime:   time.Now(),
+		Status:      AuditStatusRunning,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connDB, err := database.Connector.GetDatabase(clusterName)
+	if err != nil {
+		if ferr := a.Finish(ctx, err); ferr != nil {
+			return nil, fmt.Errorf("%v, and record the audit failed: %v", err, ferr)
+		}
+		return nil, err
+	}
+	a.db = connDB.(*mysql.Database)
+	a.recorder = a.db.StartRecorder()
+	return a, nil
+}
+
+// Finish records the collected statements, the affected objects and the outcome of the command
+func (a *Auditor) Finish(ctx context.Context, err error) error {
+	var statements, objects []string
+	if a.db != nil {
+		a.db.StopRecorder()
+		statements = a.recorder.Statements()
+		objects = a.recorder.Objects()
+	}
+
+	updates := map[string]interface{}{
+		"statements": strings.Join(statements, "\n"),
+		"objects":    strings.Join(objects, "\n"),
+		"end_time":   time.Now(),
+		"status":     AuditStatusSuccess,
+		"error":      "",
+	}
+	if err != nil {
+		updates["status"] = AuditStatusFailed
+		updates["error"] = err.Error()
+	}
+	return a.meta.UpdateAudit(ctx, a.audit.ID, updates)
+}
+
+// ListAudit returns the audit records filtered by the cluster name, the command and the start time range
+func ListAudit(ctx context.Context, clusterName, command, startTime, endTime string, limit int) ([]*sqlite.Audit, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var start, end time.Time
+	if startTime != "" {
+		if start, err = time.ParseInLocation("2006-01-02 15:04:05", startTime, time.Local); err != nil {
+			return nil, fmt.Errorf("the flag --start [%s] parse failed, the time format should be [2006-01-02 15:04:05]: %v", startTime, err)
+		}
+	}
+	if endTime != "" {
+		if end, err = time.ParseInLocation("2006-01-02 15:04:05", endTime, time.Local); err != nil {
+			return nil, fmt.Errorf("the flag --end [%s] parse failed, the time format should be [2006-01-02 15:04:05]: %v", endTime, err)
+		}
+	}
+	return metaDB.(*sqlite.Database).FindAudit(ctx, clusterName, command, start, end, limit)
+}
+
+func GetAudit(ctx context.Context, id uint64) (*sqlite.Audit, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	a, err := metaDB.(*sqlite.Database).GetAudit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.ID == 0 {
+		return nil, fmt.Errorf("the audit id [%d] not found, please run [audit list] to query the audit records", id)
+	}
+	return a, nil
+}
+
+func auditOsUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -53,7 +53,10 @@ func NewClusterLoginModel(clusterName string) ClusterLoginModel {
 }
 
 func (m ClusterLoginModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitLoginData(m.ctx, m.clusterName), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m ClusterLoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -81,10 +84,8 @@ func (m ClusterLoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Quit
 	default:
-		return m, tea.Batch(
-			m.spinner.Tick,
-			submitLoginData(m.ctx, m.clusterName), // submit login data
-		)
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
 	}
 }
 
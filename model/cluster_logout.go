@@ -44,7 +44,10 @@ func NewClusterLogoutModel(clusterName string) clusterLogoutModel {
 }
 
 func (m clusterLogoutModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitLogoutData(m.clusterName), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m clusterLogoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -67,10 +70,7 @@ func (m clusterLogoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		return m, tea.Batch(
-			cmd,
-			submitLogoutData(m.clusterName), // submit logout data
-		)
+		return m, cmd
 	}
 }
 
@@ -16,63 +16,196 @@ limitations under the License.
 package model
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
-func QueryResultFormatTableStyle(columns []string, results []map[string]string, sec ...float64) error {
-	t := table.NewWriter()
+const (
+	QueryResultFormatTable    = "table"
+	QueryResultFormatVertical = "vertical"
+	QueryResultFormatCSV      = "csv"
+	QueryResultFormatTSV      = "tsv"
+	QueryResultFormatJSON     = "json"
+	QueryResultFormatMarkdown = "markdown"
+)
 
-	var header table.Row
-	for _, c := range columns {
-		header = append(header, c)
-	}
-	t.AppendHeader(header)
-	t.AppendSeparator()
-	t.AppendRows(queryResultProcess(columns, results))
+// QueryResultFormats is the supported query result formats
+var QueryResultFormats = []string{QueryResultFormatTable, QueryResultFormatVertical, QueryResultFormatCSV, QueryResultFormatTSV, QueryResultFormatJSON, QueryResultFormatMarkdown}
+
+// QueryResultOptions controls the rendering of the query result
+type QueryResultOptions struct {
+	Format string
+	// MaxWidth is the maximum column width of the table, vertical and markdown format, 0 means unlimited.
+	// The csv, tsv and json format are the exported data and are never truncated
+	MaxWidth int
+	// Truncate truncates the value exceeding the MaxWidth with ..., otherwise the value is wrapped in the table format
+	Truncate bool
+	// Elapsed is the query elapsed seconds shown in the caption of the table and vertical format, negative means no caption
+	Elapsed float64
+}
+
+func QueryResultFormatTableStyle(columns []string, results []map[string]string, sec ...float64) error {
+	opts := &QueryResultOptions{Format: QueryResultFormatTable, Elapsed: -1}
 	if len(sec) > 0 {
-		t.SetCaption("%d rows in set (%.2f sec)\n", len(results), sec[0])
-		_, err := fmt.Fprintln(os.Stdout, t.Render())
-		if err != nil {
-			return err
-		}
-		return nil
+		opts.Elapsed = sec[0]
 	}
-	_, err := fmt.Fprintln(os.Stdout, t.Render()+"\n")
-	if err != nil {
-		return err
-	}
-	return nil
+	return WriteQueryResult(os.Stdout, columns, results, opts)
 }
 
 func QueryResultFormatWithoutTableStyle(columns []string, results []map[string]string, sec ...float64) error {
-	var bs []string
+	opts := &QueryResultOptions{Format: QueryResultFormatVertical, Elapsed: -1}
+	if len(sec) > 0 {
+		opts.Elapsed = sec[0]
+	}
+	return WriteQueryResult(os.Stdout, columns, results, opts)
+}
 
-	for i, res := range results {
-		var b strings.Builder
-		b.WriteString(fmt.Sprintf("*************************** %d. row ***************************\n", i+1))
+// WriteQueryResult renders the query result in the format, the NULL value is rendered as NULL, or null in the json format
+func WriteQueryResult(w io.Writer, columns []string, results []map[string]string, opts *QueryResultOptions) error {
+	var out string
+	switch opts.Format {
+	case QueryResultFormatTable, "":
+		t := table.NewWriter()
+		var header table.Row
 		for _, c := range columns {
-			for k, v := range res {
-				if k == c {
-					if v == "NULLABLE" {
-						b.WriteString(fmt.Sprintf("  %s: %v\n", c, "NULL"))
-					} else {
-						b.WriteString(fmt.Sprintf("  %s: %v\n", c, v))
-					}
+			header = append(header, c)
+		}
+		t.AppendHeader(header)
+		t.AppendSeparator()
+		t.AppendRows(queryResultRows(columns, results, opts))
+		if opts.MaxWidth > 0 && !opts.Truncate {
+			var configs []table.ColumnConfig
+			for i := range columns {
+				configs = append(configs, table.ColumnConfig{Number: i + 1, WidthMax: opts.MaxWidth})
+			}
+			t.SetColumnConfigs(configs)
+		}
+		if opts.Elapsed >= 0 {
+			t.SetCaption("%d rows in set (%.2f sec)\n", len(results), opts.Elapsed)
+			out = t.Render() + "\n"
+		} else {
+			out = t.Render() + "\n\n"
+		}
+	case QueryResultFormatVertical:
+		var bs []string
+		for i, row := range queryResultRows(columns, results, opts) {
+			var b strings.Builder
+			b.WriteString(fmt.Sprintf("*************************** %d. row ***************************\n", i+1))
+			for j, c := range columns {
+				if j < len(row) {
+					b.WriteString(fmt.Sprintf("  %s: %v\n", c, row[j]))
+				}
+			}
+			bs = append(bs, b.String())
+		}
+		if opts.Elapsed >= 0 {
+			bs = append(bs, fmt.Sprintf("%d rows in set (%.2f sec)\n", len(results), opts.Elapsed))
+		}
+		out = strings.Join(bs, "\n") + "\n"
+	case QueryResultFormatCSV, QueryResultFormatTSV:
+		var b strings.Builder
+		cw := csv.NewWriter(&b)
+		if opts.Format == QueryResultFormatTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, res := range results {
+			record := make([]string, 0, len(columns))
+			for _, c := range columns {
+				v := res[c]
+				if v == "NULLABLE" {
+					v = "NULL"
 				}
+				record = append(record, v)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
 			}
 		}
-		bs = append(bs, b.String())
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		out = b.String()
+	case QueryResultFormatJSON:
+		// the object keys keep the column order of the result
+		var b strings.Builder
+		b.WriteString("[")
+		for i, res := range results {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n  {")
+			for j, c := range columns {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				key, _ := json.Marshal(c)
+				b.Write(key)
+				b.WriteString(": ")
+				if v := res[c]; v == "NULLABLE" {
+					b.WriteString("null")
+				} else {
+					val, _ := json.Marshal(v)
+					b.Write(val)
+				}
+			}
+			b.WriteString("}")
+		}
+		if len(results) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("]\n")
+		out = b.String()
+	case QueryResultFormatMarkdown:
+		t := table.NewWriter()
+		var header table.Row
+		for _, c := range columns {
+			header = append(header, c)
+		}
+		t.AppendHeader(header)
+		t.AppendRows(queryResultRows(columns, results, &QueryResultOptions{MaxWidth: opts.MaxWidth, Truncate: opts.MaxWidth > 0}))
+		out = t.RenderMarkdown() + "\n\n"
+	default:
+		return fmt.Errorf("unknown query result format [%s], options: %s", opts.Format, strings.Join(QueryResultFormats, " / "))
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// queryResultRows returns the rows in the column order, the value exceeding the max width is truncated if required
+func queryResultRows(columns []string, results []map[string]string, opts *QueryResultOptions) []table.Row {
+	rows := queryResultProcess(columns, results)
+	if opts.MaxWidth <= 0 || !opts.Truncate {
+		return rows
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if s, ok := v.(string); ok {
+				row[i] = truncateQueryValue(s, opts.MaxWidth)
+			}
+		}
+	}
+	return rows
+}
+
+func truncateQueryValue(v string, width int) string {
+	runes := []rune(v)
+	if len(runes) <= width {
+		return v
 	}
-	bs = append(bs, fmt.Sprintf("%d rows in set (%.2f sec)\n", len(results), sec[0]))
-	_, err := fmt.Fprintln(os.Stdout, strings.Join(bs, "\n"))
-	if err != nil {
-		return err
+	if width <= 3 {
+		return string(runes[:width])
 	}
-	return nil
+	return string(runes[:width-3]) + "..."
 }
 
 func queryResultProcess(columns []string, results []map[string]string) []table.Row {
@@ -95,30 +228,3 @@ func queryResultProcess(columns []string, results []map[string]string) []table.R
 	}
 	return rows
 }
-
-func QueryResultFormatTableStyleWithRowsArray(columns []string, rows [][]interface{}) error {
-	t := table.NewWriter()
-
-	var header table.Row
-	for _, c := range columns {
-		header = append(header, c)
-	}
-	t.AppendHeader(header)
-	t.AppendSeparator()
-	var newRows []table.Row
-	for _, row := range rows {
-		var newRow table.Row
-		for _, r := range row[:len(columns)] {
-			newRow = append(newRow, r)
-		}
-		newRows = append(newRows, newRow)
-	}
-
-	t.AppendRows(newRows)
-
-	_, err := fmt.Fprintln(os.Stdout, t.Render()+"\n")
-	if err != nil {
-		return err
-	}
-	return nil
-}
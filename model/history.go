@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+)
+
+// CreateHistory records the input executed by the interactive command line
+func CreateHistory(ctx context.Context, h *sqlite.History) error {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return err
+	}
+	_, err = metaDB.(*sqlite.Database).CreateHistory(ctx, h)
+	return err
+}
+
+// ListHistory returns the latest history records filtered by the cluster name and the statement text
+func ListHistory(ctx context.Context, clusterName, grep string, limit int) ([]*sqlite.History, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	return metaDB.(*sqlite.Database).FindHistory(ctx, clusterName, grep, limit)
+}
+
+// PruneHistory keeps the latest history records and deletes the older records
+func PruneHistory(ctx context.Context, keep int) error {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return err
+	}
+	_, err = metaDB.(*sqlite.Database).PruneHistory(ctx, keep)
+	return err
+}
+
+func GetHistory(ctx context.Context, id uint64) (*sqlite.History, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	h, err := metaDB.(*sqlite.Database).GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h.ID == 0 {
+		return nil, fmt.Errorf("the history id [%d] not found, please run [\\history] to query the history records", id)
+	}
+	return h, nil
+}
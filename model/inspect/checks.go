@@ -0,0 +1,494 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/mysql"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/stringutil"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CheckModuleDevBestPractices   = "dev_best_practices"
+	CheckModuleStatsBestPractices = "stats_best_practices"
+
+	CheckSourceBuiltin = "builtin"
+	CheckSourceCustom  = "custom"
+
+	// DefaultCheckThreshold is the threshold of the check without the threshold expression, any abnormal object is abnormal
+	DefaultCheckThreshold = "rows > 0"
+)
+
+// customCheckSeverities maps the severity of the custom check to the rectification type of the report
+var customCheckSeverities = map[string]string{
+	"critical": "强烈建议整改",
+	"warning":  "建议整改",
+	"info":     "提示",
+}
+
+var (
+	checkIDRegexp        = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	checkVersionRegexp   = regexp.MustCompile(`^v?\d+(\.\d+){0,3}$`)
+	checkThresholdRegexp = regexp.MustCompile(`^\s*(rows|value)\s*(>=|<=|==|!=|>|<)\s*(-?\d+(?:\.\d+)?)\s*$`)
+	// checkSQLCommentRegexp matches the leading comments of the check sql
+	checkSQLCommentRegexp = regexp.MustCompile(`^\s*(/\*.*?\*/|--[^\n]*|#[^\n]*)`)
+	// checkSQLWriteRegexp matches the keywords changing the cluster state, the check sql containing the keyword is rejected even
+	// if the keyword is in the string literal or the quoted identifier
+	checkSQLWriteRegexp = regexp.MustCompile(`(?is)\b(INSERT|UPDATE|DELETE|REPLACE|CREATE|ALTER|DROP|TRUNCATE|RENAME|GRANT|REVOKE|SET|KILL|LOAD|IMPORT|CALL|DO|HANDLER|LOCK|UNLOCK|FLUSH|ADMIN|ANALYZE|SPLIT|BATCH|FLASHBACK|RECOVER|OUTFILE|DUMPFILE)\b`)
+)
+
+// checkSQLReadKeywords are the leading keywords of the read statements allowed by the custom check sql
+var checkSQLReadKeywords = []string{"SELECT", "WITH", "SHOW", "TABLE", "VALUES"}
+
+// InspectCheck is the check item of the inspection, the built-in and custom checks of the sql check modules
+type InspectCheck struct {
+	ID         string
+	Module     string
+	Source     string
+	Category   string
+	CheckItem  string
+	Severity   string
+	MinVersion string
+	MaxVersion string
+	Threshold  string
+	Enabled    bool
+}
+
+// checkThreshold is the parsed threshold expression, rows compares the number of the returned rows,
+// value compares the numeric value of each row and the matched rows are abnormal
+type checkThreshold struct {
+	Operand  string
+	Operator string
+	Value    float64
+}
+
+func parseCheckThreshold(expr string) (*checkThreshold, error) {
+	if strings.TrimSpace(expr) == "" {
+		expr = DefaultCheckThreshold
+	}
+	m := checkThresholdRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("invalid threshold expression [%s], the format is {rows | value} {> | >= | < | <= | == | !=} {number}, e.g. rows > 0 or value >= 100", expr)
+	}
+	v, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold expression [%s]: %v", expr, err)
+	}
+	return &checkThreshold{Operand: m[1], Operator: m[2], Value: v}, nil
+}
+
+func (t *checkThreshold) String() string {
+	return fmt.Sprintf("%s %s %s", t.Operand, t.Operator, strconv.FormatFloat(t.Value, 'f', -1, 64))
+}
+
+func (t *checkThreshold) match(v float64) bool {
+	switch t.Operator {
+	case ">":
+		return v > t.Value
+	case ">=":
+		return v >= t.Value
+	case "<":
+		return v < t.Value
+	case "<=":
+		return v <= t.Value
+	case "==":
+		return v == t.Value
+	default:
+		return v != t.Value
+	}
+}
+
+// Validate checks the module timeouts, the metric thresholds, the custom checks and the disabled checks of the inspect config, the config is rejected before it is saved
+func (i *InspectConfig) Validate() error {
+	if i == nil {
+		return nil
+	}
+	if err := validateModuleTimeouts(i.ModuleTimeoutSeconds, i.ModuleTimeouts); err != nil {
+		return err
+	}
+	if err := validateMetricThresholds(i.MetricThresholds); err != nil {
+		return err
+	}
+
+	ids := make(map[string]struct{})
+	for _, c := range DefaultDevBestPracticesInspItems() {
+		ids[c.CheckID] = struct{}{}
+	}
+	for _, c := range DefaultInspDatabaseStatisticsItems() {
+		ids[c.CheckID] = struct{}{}
+	}
+
+	for _, c := range i.CustomChecks {
+		if c == nil {
+			continue
+		}
+		if !checkIDRegexp.MatchString(c.ID) {
+			return fmt.Errorf("invalid custom check id [%s], the id is required and only contains letters, digits, '_', '-' and '.'", c.ID)
+		}
+		if _, ok := ids[c.ID]; ok {
+			return fmt.Errorf("the custom check id [%s] is duplicated with the other check, see [inspect checks]", c.ID)
+		}
+		ids[c.ID] = struct{}{}
+
+		if c.Module != "" && c.Module != CheckModuleDevBestPractices && c.Module != CheckModuleStatsBestPractices {
+			return fmt.Errorf("invalid custom check [%s] module [%s], options: %s / %s", c.ID, c.Module, CheckModuleDevBestPractices, CheckModuleStatsBestPractices)
+		}
+		if _, ok := customCheckSeverities[c.Severity]; !ok && c.Severity != "" && !isRectificationType(c.Severity) {
+			return fmt.Errorf("invalid custom check [%s] severity [%s], options: critical / warning / info", c.ID, c.Severity)
+		}
+		if strings.TrimSpace(c.SQL) == "" {
+			return fmt.Errorf("the custom check [%s] sql cannot be empty", c.ID)
+		}
+		if err := validateCheckSQL(c.SQL); err != nil {
+			return fmt.Errorf("the custom check [%s] %v", c.ID, err)
+		}
+		for _, v := range []string{c.MinVersion, c.MaxVersion} {
+			if v != "" && !checkVersionRegexp.MatchString(v) {
+				return fmt.Errorf("invalid custom check [%s] version [%s], the format is e.g. v6.5.0", c.ID, v)
+			}
+		}
+		if c.MinVersion != "" && c.MaxVersion != "" &&
+			stringutil.VersionOrdinal(strings.TrimPrefix(c.MinVersion, "v")) >= stringutil.VersionOrdinal(strings.TrimPrefix(c.MaxVersion, "v")) {
+			return fmt.Errorf("the custom check [%s] min_version [%s] must be less than max_version [%s]", c.ID, c.MinVersion, c.MaxVersion)
+		}
+		if _, err := parseCheckThreshold(c.Threshold); err != nil {
+			return fmt.Errorf("the custom check [%s] %v", c.ID, err)
+		}
+	}
+
+	for _, id := range i.DisabledChecks {
+		if _, ok := ids[id]; !ok {
+			return fmt.Errorf("the disabled check [%s] not found, see [inspect checks]", id)
+		}
+	}
+	return nil
+}
+
+// validateCheckSQL rejects the custom check sql that is not the single read statement, the custom checks are stored in the
+// metadata database which may be shared, and the check sql runs with the credentials of the inspector
+func validateCheckSQL(checkSql string) error {
+	text := strings.TrimSpace(checkSql)
+	for {
+		loc := checkSQLCommentRegexp.FindStringIndex(text)
+		if loc == nil {
+			break
+		}
+		text = strings.TrimSpace(text[loc[1]:])
+	}
+	text = strings.TrimSpace(strings.TrimRight(text, "; \t\r\n"))
+	if strings.Contains(text, ";") {
+		return fmt.Errorf("sql only supports the single statement, the statement separator ';' is not allowed")
+	}
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z')
+	})
+	if len(fields) == 0 || !stringutil.IsContainString(strings.ToUpper(fields[0]), checkSQLReadKeywords) {
+		return fmt.Errorf("sql only supports the read statement starting with %s", strings.Join(checkSQLReadKeywords, " / "))
+	}
+	if m := checkSQLWriteRegexp.FindString(text); m != "" {
+		return fmt.Errorf("sql only supports the read statement, the keyword [%s] is not allowed", strings.ToUpper(m))
+	}
+	return nil
+}
+
+func isRectificationType(severity string) bool {
+	for _, v := range customCheckSeverities {
+		if v == severity {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CustomCheck) checkItem() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.ID
+}
+
+func (c *CustomCheck) rectificationType() string {
+	if v, ok := customCheckSeverities[c.Severity]; ok {
+		return v
+	}
+	if c.Severity != "" {
+		return c.Severity
+	}
+	return customCheckSeverities["warning"]
+}
+
+func (c *CustomCheck) module() string {
+	if c.Module == "" {
+		return CheckModuleDevBestPractices
+	}
+	return c.Module
+}
+
+func (i *InspectConfig) isCheckDisabled(id string) bool {
+	return stringutil.IsContainString(id, i.DisabledChecks)
+}
+
+// devBestPracticesInspItems returns the enabled built-in and custom checks of the development best practices
+func (i *InspectConfig) devBestPracticesInspItems() []*InspDevBestPractices {
+	var items []*InspDevBestPractices
+	builtins := DefaultDevBestPracticesInspItems()
+	for _, c := range builtins {
+		if !i.isCheckDisabled(c.CheckID) {
+			items = append(items, c)
+		}
+	}
+	seq := len(builtins)
+	for _, c := range i.CustomChecks {
+		if c == nil || c.module() != CheckModuleDevBestPractices {
+			continue
+		}
+		seq++
+		if i.isCheckDisabled(c.ID) {
+			continue
+		}
+		category := c.Category
+		if category == "" {
+			category = "自定义"
+		}
+		items = append(items, &InspDevBestPractices{
+			CheckSeq:          seq,
+			CheckID:           c.ID,
+			CheckItem:         c.checkItem(),
+			CheckCategory:     category,
+			RectificationType: c.rectificationType(),
+			CheckType:         "自定义",
+			BestPracticeDesc:  c.Remediation,
+			CheckSql:          c.SQL,
+			MinVersion:        c.MinVersion,
+			MaxVersion:        c.MaxVersion,
+			Threshold:         c.Threshold,
+		})
+	}
+	return items
+}
+
+// databaseStatisticsInspItems returns the enabled built-in and custom checks of the statistics best practices,
+// the remediation of the custom check is shown as the check standard
+func (i *InspectConfig) databaseStatisticsInspItems() []*InspDatabaseStatistics {
+	var items []*InspDatabaseStatistics
+	builtins := DefaultInspDatabaseStatisticsItems()
+	for _, c := range builtins {
+		if !i.isCheckDisabled(c.CheckID) {
+			items = append(items, c)
+		}
+	}
+	seq := len(builtins)
+	for _, c := range i.CustomChecks {
+		if c == nil || c.module() != CheckModuleStatsBestPractices {
+			continue
+		}
+		seq++
+		if i.isCheckDisabled(c.ID) {
+			continue
+		}
+		items = append(items, &InspDatabaseStatistics{
+			CheckSeq:      seq,
+			CheckID:       c.ID,
+			CheckItem:     c.checkItem(),
+			CheckStandard: c.Remediation,
+			CheckSql:      c.SQL,
+			MinVersion:    c.MinVersion,
+			MaxVersion:    c.MaxVersion,
+			Threshold:     c.Threshold,
+			Severity:      c.rectificationType(),
+		})
+	}
+	return items
+}
+
+// ListInspectChecks returns the built-in and custom checks of the sql check modules, the nil config returns the built-in checks
+func ListInspectChecks(cfg *InspectConfig) []*InspectCheck {
+	if cfg == nil {
+		cfg = &InspectConfig{}
+	}
+	var checks []*InspectCheck
+	for _, c := range DefaultDevBestPracticesInspItems() {
+		checks = append(checks, &InspectCheck{
+			ID:         c.CheckID,
+			Module:     CheckModuleDevBestPractices,
+			Source:     CheckSourceBuiltin,
+			Category:   c.CheckCategory,
+			CheckItem:  c.CheckItem,
+			Severity:   c.RectificationType,
+			MinVersion: c.MinVersion,
+			MaxVersion: c.MaxVersion,
+			Threshold:  DefaultCheckThreshold,
+			Enabled:    !cfg.isCheckDisabled(c.CheckID),
+		})
+	}
+	for _, c := range DefaultInspDatabaseStatisticsItems() {
+		checks = append(checks, &InspectCheck{
+			ID:         c.CheckID,
+			Module:     CheckModuleStatsBestPractices,
+			Source:     CheckSourceBuiltin,
+			CheckItem:  c.CheckItem,
+			MinVersion: c.MinVersion,
+			MaxVersion: c.MaxVersion,
+			Threshold:  DefaultCheckThreshold,
+			Enabled:    !cfg.isCheckDisabled(c.CheckID),
+		})
+	}
+	for _, c := range cfg.CustomChecks {
+		if c == nil {
+			continue
+		}
+		threshold := c.Threshold
+		if threshold == "" {
+			threshold = DefaultCheckThreshold
+		}
+		checks = append(checks, &InspectCheck{
+			ID:         c.ID,
+			Module:     c.module(),
+			Source:     CheckSourceCustom,
+			Category:   c.Category,
+			CheckItem:  c.checkItem(),
+			Severity:   c.rectificationType(),
+			MinVersion: c.MinVersion,
+			MaxVersion: c.MaxVersion,
+			Threshold:  threshold,
+			Enabled:    !cfg.isCheckDisabled(c.ID),
+		})
+	}
+	return checks
+}
+
+// QueryInspectConfig returns the inspect config of the cluster, the nil config means the config is not created
+func QueryInspectConfig(ctx context.Context, clusterName string) (*InspectConfig, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	c, err := db.(*sqlite.Database).GetInspect(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	var data *InspectConfig
+	if err := yaml.Unmarshal([]byte(c.InspectConfig), &data); err != nil {
+		return nil, fmt.Errorf("unmarshal inspect config: %v", err)
+	}
+	return data, nil
+}
+
+// clusterVersion returns the tidb version of the inspected cluster, e.g. 6.5.6
+func (i *Insepctor) clusterVersion() (string, error) {
+	database := i.connector.(*mysql.Database)
+
+	_, res, err := database.GeneralQuery(i.ctx, `select version() AS VERSION`)
+	if err != nil {
+		return "", err
+	}
+	/*
+		select version();
+		+--------------------+
+		| version()          |
+		+--------------------+
+		| 5.7.25-TiDB-v6.5.6 |
+		+--------------------+
+		1 row in set (0.00 sec)
+	*/
+	vers := strings.Split(res[0]["VERSION"], "-")
+
+	// 适配平凯数据库版本 8.0.11-TiDB-v7.1.8-5.2
+	var version string
+	if len(vers) > 3 {
+		tmpVers := strings.Split(strings.TrimPrefix(vers[len(vers)-2], "v"), ".")
+		version = fmt.Sprintf("%s.%s", tmpVers[len(tmpVers)-1], vers[len(vers)-1])
+	} else {
+		version = strings.TrimPrefix(vers[len(vers)-1], "v")
+	}
+	return version, nil
+}
+
+// sqlCheckVerdict is the verdict of the sql check, the results are the abnormal objects returned by the check sql
+type sqlCheckVerdict struct {
+	skipDetail string
+	abnormal   bool
+	results    []string
+}
+
+// runSQLCheck executes the check sql inside the read only transaction and returns the verdict of the threshold,
+// the check out of the version range is skipped and the skipped detail is returned instead
+func (i *Insepctor) runSQLCheck(version, checkID, checkSql, minVersion, maxVersion, versionDetail, threshold string) (*sqlCheckVerdict, error) {
+	vo := stringutil.VersionOrdinal(version)
+	if (minVersion != "" && vo < stringutil.VersionOrdinal(strings.TrimPrefix(minVersion, "v"))) ||
+		(maxVersion != "" && vo >= stringutil.VersionOrdinal(strings.TrimPrefix(maxVersion, "v"))) {
+		if versionDetail != "" {
+			return &sqlCheckVerdict{skipDetail: fmt.Sprintf(versionDetail, version)}, nil
+		}
+		return &sqlCheckVerdict{skipDetail: fmt.Sprintf("数据库版本 [%v] 不在检查项适用版本范围 [%s, %s)，跳过检查", version, minVersion, maxVersion)}, nil
+	}
+
+	t, err := parseCheckThreshold(threshold)
+	if err != nil {
+		return nil, fmt.Errorf("the check [%s] %v", checkID, err)
+	}
+
+	// the check sql runs inside the read only transaction, the write slipped through the validation is refused and rolled back
+	_, res, err := i.connector.(*mysql.Database).ReadOnlyQuery(i.ctx, checkSql)
+	if err != nil {
+		return nil, fmt.Errorf("the check [%s] query failed: %v", checkID, err)
+	}
+	v := &sqlCheckVerdict{}
+
+	if t.Operand == "rows" {
+		if !t.match(float64(len(res))) {
+			return v, nil
+		}
+		v.abnormal = true
+		for _, r := range res {
+			v.results = append(v.results, r["SQL_RESULT"])
+		}
+		// the threshold matching the empty result, e.g. rows == 0, has no abnormal object
+		if len(v.results) == 0 {
+			v.results = append(v.results, fmt.Sprintf("返回行数 %d 满足异常阈值 [%s]", len(res), t))
+		}
+		return v, nil
+	}
+
+	for _, r := range res {
+		val, hasValue := r["SQL_VALUE"]
+		if !hasValue {
+			val = r["SQL_RESULT"]
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("the check [%s] value [%s] is not a number, the threshold [%s] requires the numeric column SQL_VALUE or SQL_RESULT", checkID, val, threshold)
+		}
+		if !t.match(f) {
+			continue
+		}
+		v.abnormal = true
+		if hasValue {
+			v.results = append(v.results, fmt.Sprintf("%s: %s", r["SQL_RESULT"], val))
+		} else {
+			v.results = append(v.results, val)
+		}
+	}
+	return v, nil
+}
@@ -16,38 +16,24 @@ limitations under the License.
 package inspect
 
 import (
-	"gopkg.in/yaml.v3"
-)
-
-const (
-	// size: seconds
-	DefaultPdComponentRegionHeartbeatHandleLatencyUnderline = 0.030
-	DefaultPdComponentHandleReuqestDurationUnderline        = 0.030
-	DefaultPdComponentWalFsyncDurationUnderline             = 0.015
-
-	// size: seconds
-	DefaultTiDBComponentCommitTokenWaitDurationUnderline = 0.015
-
-	// interger
-	DefaultTiKVComponentSchedulerDiscardRatioUnderline = 0
-
-	// size: seconds
-	DefaultTiKVComponentWriteDiskLatencyUnderline = 0.015
-	DefaultTiKVComponentReadDiskLatencyUnderline  = 0.015
+	"fmt"
 
-	// size: seconds
-	DefaultHostDiskWriteLatencyUnderline = 0.010
-	DefaultHostDiskReadLatencyUnderline  = 0.010
+	"gopkg.in/yaml.v3"
 )
 
 type InspectConfig struct {
-	WindowMinutes    int                    `yaml:"window_minutes" json:"window_minutes"`
-	HostIPs          []string               `yaml:"host_ips" json:"host_ips"`
-	VariablesParams  map[string]interface{} `yaml:"variables_params" json:"variables_params"`
-	TiDBConfigParams map[string]interface{} `yaml:"tidb_config_params" json:"tidb_config_params"`
-	PDConfigParams   map[string]interface{} `yaml:"pd_config_params" json:"pd_config_params"`
-	TiKVConfigParams map[string]interface{} `yaml:"tikv_config_params" json:"tikv_config_params"`
-	Modules          *Modules               `yaml:"modules" json:"modules"`
+	WindowMinutes        int                         `yaml:"window_minutes" json:"window_minutes"`
+	HostIPs              []string                    `yaml:"host_ips" json:"host_ips"`
+	VariablesParams      map[string]interface{}      `yaml:"variables_params" json:"variables_params"`
+	TiDBConfigParams     map[string]interface{}      `yaml:"tidb_config_params" json:"tidb_config_params"`
+	PDConfigParams       map[string]interface{}      `yaml:"pd_config_params" json:"pd_config_params"`
+	TiKVConfigParams     map[string]interface{}      `yaml:"tikv_config_params" json:"tikv_config_params"`
+	Modules              *Modules                    `yaml:"modules" json:"modules"`
+	ModuleTimeoutSeconds int                         `yaml:"module_timeout_seconds" json:"module_timeout_seconds"`
+	ModuleTimeouts       map[string]int              `yaml:"module_timeouts" json:"module_timeouts"`
+	MetricThresholds     map[string]*MetricThreshold `yaml:"metric_thresholds" json:"metric_thresholds"`
+	CustomChecks         []*CustomCheck              `yaml:"custom_checks" json:"custom_checks"`
+	DisabledChecks       []string                    `yaml:"disabled_checks" json:"disabled_checks"`
 }
 
 type Modules struct {
@@ -72,6 +58,22 @@ type Modules struct {
 	CheckSQLOrderByPlans       bool `yaml:"check_sql_order_by_plans" json:"check_sql_order_by_plans"`
 }
 
+// CustomCheck is the user defined sql check item, it is executed by the inspection engine together with the built-in checks
+// of the module. The sql returns the abnormal objects in the column SQL_RESULT, and the optional numeric column SQL_VALUE
+// compared by the threshold expression, e.g. rows > 0 (the default) or value >= 100, see [inspect checks]
+type CustomCheck struct {
+	ID          string `yaml:"id" json:"id"`
+	Name        string `yaml:"name" json:"name"`
+	Module      string `yaml:"module" json:"module"`
+	Category    string `yaml:"category" json:"category"`
+	Severity    string `yaml:"severity" json:"severity"`
+	SQL         string `yaml:"sql" json:"sql"`
+	MinVersion  string `yaml:"min_version" json:"min_version"`
+	MaxVersion  string `yaml:"max_version" json:"max_version"`
+	Threshold   string `yaml:"threshold" json:"threshold"`
+	Remediation string `yaml:"remediation" json:"remediation"`
+}
+
 func DefaultInspectConfigTemplate() *InspectConfig {
 	return &InspectConfig{
 		WindowMinutes: 720,
@@ -151,7 +153,21 @@ func DefaultInspectConfigTemplate() *InspectConfig {
 			CheckSQLOrderByExecutions:  true,
 			CheckSQLOrderByPlans:       true,
 		},
+		ModuleTimeoutSeconds: int(DefaultInspectModuleTimeout.Seconds()),
+		ModuleTimeouts:       map[string]int{},
+		MetricThresholds:     DefaultMetricThresholds(),
+		CustomChecks:         []*CustomCheck{},
+		DisabledChecks:       []string{},
+	}
+}
+
+// ParseInspectConfig decodes the yaml text of the inspection config
+func ParseInspectConfig(content string) (*InspectConfig, error) {
+	var data *InspectConfig
+	if err := yaml.Unmarshal([]byte(content), &data); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
 	}
+	return data, nil
 }
 
 func (i *InspectConfig) String() string {
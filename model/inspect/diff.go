@@ -0,0 +1,427 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package inspect
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	InspectDiffNewlyFailed = "NEWLY FAILED"
+	InspectDiffRecovered   = "RECOVERED"
+	InspectDiffChanged     = "CHANGED"
+	InspectDiffMetricDelta = "METRIC DELTA"
+	// InspectDiffNotCollected is the module not collected by one of the runs, the module failed, timed out or was not enabled,
+	// the sections of the module are not compared
+	InspectDiffNotCollected = "NOT COLLECTED"
+)
+
+// DefaultInspectDiffTolerance is the default tolerance of the metric delta in percent
+const DefaultInspectDiffTolerance = 20.0
+
+// InspectDiff is the difference between two inspection runs, the before run is compared with the after run
+type InspectDiff struct {
+	Before    *InspectDiffRun
+	After     *InspectDiffRun
+	Tolerance float64
+	Items     []*InspectDiffItem
+}
+
+type InspectDiffRun struct {
+	ID               uint64
+	ClusterName      string
+	InspectionTime   string
+	InspectionWindow string
+}
+
+// InspectDiffItem is the check or the metric changed between two inspection runs
+type InspectDiffItem struct {
+	Category string
+	Item     string
+	Instance string
+	Change   string
+	Before   string
+	After    string
+	Delta    string
+}
+
+// metricNumberRegexp matches the leading number of the metric value, e.g. 12.5ms, 85.2%, 3.2GB
+var metricNumberRegexp = regexp.MustCompile(`^\s*(-?[0-9]+(\.[0-9]+)?)`)
+
+// windowSuffixRegexp matches the inspection window suffix of the software category, e.g. QPS 峰值（12.00H）
+var windowSuffixRegexp = regexp.MustCompile(`（[0-9.]+H）$`)
+
+type diffKey struct {
+	item     string
+	instance string
+}
+
+type diffCheck struct {
+	failed bool
+	value  string
+}
+
+type inspectDiffer struct {
+	tolerance float64
+	before    *Report
+	after     *Report
+	items     []*InspectDiffItem
+}
+
+// DiffInspectReport compares the checks, the variables and configs and the metrics of two inspection reports, the check newly
+// failed or recovered, the changed variable or config value and the metric changed beyond the tolerance percent are returned,
+// the module not collected by either run is reported as not collected and its sections are not compared
+func DiffInspectReport(before, after *Report, tolerance float64) []*InspectDiffItem {
+	d := &inspectDiffer{tolerance: tolerance, before: before, after: after}
+
+	d.summary(summaryChecks(before), summaryChecks(after))
+	d.checks("cluster", "check_tidb_overview", clusterChecks(before), clusterChecks(after))
+	d.checks("dev practice", "check_dev_best_practices", devPracticeChecks(before), devPracticeChecks(after))
+	d.checks("statistics", "check_stats_best_practices", statisticsChecks(before), statisticsChecks(after))
+	d.checks("system config", "check_sys_config", systemConfigChecks(before), systemConfigChecks(after))
+	d.checks("dmesg", "check_dmesg_logs", dmesgChecks(before), dmesgChecks(after))
+	d.checks("error log", "check_db_error_logs", errorLogChecks(before), errorLogChecks(after))
+
+	d.params("variable", "check_db_params", variableParams(before), variableParams(after))
+	d.params("config", "check_db_params", configParams(before), configParams(after))
+
+	d.metrics("software", "check_software_info", softwareMetrics(before), softwareMetrics(after))
+	d.metrics("pd metric", "check_pd_performance", pdMetrics(before), pdMetrics(after))
+	d.metrics("tidb metric", "check_tidb_performance", tidbMetrics(before), tidbMetrics(after))
+	d.metrics("tikv metric", "check_tikv_performance", tikvMetrics(before), tikvMetrics(after))
+	return d.items
+}
+
+// collected returns whether the module is collected by both runs
+func (d *inspectDiffer) collected(module string) bool {
+	b, _ := moduleCollected(d.before, module)
+	a, _ := moduleCollected(d.after, module)
+	return b && a
+}
+
+// summary compares the summary items, the item of the module not collected by either run is reported as not collected
+// instead of newly failed or recovered
+func (d *inspectDiffer) summary(before, after map[diffKey]diffCheck) {
+	modules := make(map[string]string)
+	for _, m := range inspectModules() {
+		modules[m.summary] = m.name
+	}
+	for _, k := range diffKeys(before, after) {
+		module, ok := modules[k.item]
+		if !ok || d.collected(module) {
+			d.check("summary", k, before[k], after[k])
+			continue
+		}
+		// the run collecting the module shows the summary result
+		bstatus, astatus := before[k].value, after[k].value
+		if ok, status := moduleCollected(d.before, module); !ok {
+			bstatus = status
+		}
+		if ok, status := moduleCollected(d.after, module); !ok {
+			astatus = status
+		}
+		d.items = append(d.items, &InspectDiffItem{Category: "summary", Item: k.item, Instance: module, Change: InspectDiffNotCollected, Before: bstatus, After: astatus})
+	}
+}
+
+// checks compares the check results, the check missing in the run collecting the module is regarded as passed
+func (d *inspectDiffer) checks(category, module string, before, after map[diffKey]diffCheck) {
+	if !d.collected(module) {
+		return
+	}
+	for _, k := range diffKeys(before, after) {
+		d.check(category, k, before[k], after[k])
+	}
+}
+
+func (d *inspectDiffer) check(category string, k diffKey, b, a diffCheck) {
+	var change string
+	switch {
+	case !b.failed && a.failed:
+		change = InspectDiffNewlyFailed
+	case b.failed && !a.failed:
+		change = InspectDiffRecovered
+	default:
+		return
+	}
+	d.items = append(d.items, &InspectDiffItem{Category: category, Item: k.item, Instance: k.instance, Change: change, Before: b.value, After: a.value})
+}
+
+// params compares the variables and configs, the standard state change is reported as newly failed or recovered,
+// otherwise the current value change is reported as changed
+func (d *inspectDiffer) params(category, module string, before, after map[diffKey]diffCheck) {
+	if !d.collected(module) {
+		return
+	}
+	for _, k := range diffKeys(before, after) {
+		b, bok := before[k]
+		a, aok := after[k]
+		var change string
+		switch {
+		case bok && aok && !b.failed && a.failed:
+			change = InspectDiffNewlyFailed
+		case bok && aok && b.failed && !a.failed:
+			change = InspectDiffRecovered
+		case !bok || !aok || b.value != a.value:
+			change = InspectDiffChanged
+		default:
+			continue
+		}
+		d.items = append(d.items, &InspectDiffItem{Category: category, Item: k.item, Instance: k.instance, Change: change, Before: b.value, After: a.value})
+	}
+}
+
+// metrics compares the metric values, the numeric value changed beyond the tolerance percent is reported as the metric delta,
+// and the non-numeric value change is reported as changed
+func (d *inspectDiffer) metrics(category, module string, before, after map[diffKey]string) {
+	if !d.collected(module) {
+		return
+	}
+	for _, k := range diffKeys(before, after) {
+		b, bok := before[k]
+		a, aok := after[k]
+		if !bok || !aok || b == a {
+			continue
+		}
+		bv, berr := metricNumber(b)
+		av, aerr := metricNumber(a)
+		if berr != nil || aerr != nil {
+			d.items = append(d.items, &InspectDiffItem{Category: category, Item: k.item, Instance: k.instance, Change: InspectDiffChanged, Before: b, After: a})
+			continue
+		}
+
+		var delta float64
+		switch {
+		case bv == av:
+			continue
+		case bv == 0:
+			delta = math.Inf(int(math.Copysign(1, av)))
+		default:
+			delta = (av - bv) / math.Abs(bv) * 100
+		}
+		if math.Abs(delta) <= d.tolerance {
+			continue
+		}
+		d.items = append(d.items, &InspectDiffItem{Category: category, Item: k.item, Instance: k.instance, Change: InspectDiffMetricDelta, Before: b, After: a, Delta: formatDelta(delta)})
+	}
+}
+
+// moduleCollected returns whether the module is collected by the run and the status of the module not collected, the report
+// without the enabled modules is saved by the old version, only the module errors of the report are known
+func moduleCollected(r *Report, module string) (bool, string) {
+	if r.ReportDetail == nil {
+		return false, "未采集"
+	}
+	for _, e := range r.ModuleErrors {
+		if e.Module == module {
+			return false, fmt.Sprintf("执行失败：%s", e.Error)
+		}
+	}
+	if r.EnabledModules == nil {
+		return true, ""
+	}
+	for _, m := range r.EnabledModules {
+		if m == module {
+			return true, ""
+		}
+	}
+	return false, "未启用"
+}
+
+func diffKeys[T any](before, after map[diffKey]T) []diffKey {
+	var keys []diffKey
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].item != keys[j].item {
+			return keys[i].item < keys[j].item
+		}
+		return keys[i].instance < keys[j].instance
+	})
+	return keys
+}
+
+func metricNumber(v string) (float64, error) {
+	m := metricNumberRegexp.FindStringSubmatch(v)
+	if m == nil {
+		return 0, fmt.Errorf("the value [%s] is not numeric", v)
+	}
+	return strconv.ParseFloat(m[1], 64)
+}
+
+func formatDelta(delta float64) string {
+	switch {
+	case math.IsInf(delta, 1):
+		return "+inf"
+	case math.IsInf(delta, -1):
+		return "-inf"
+	default:
+		return fmt.Sprintf("%+.2f%%", delta)
+	}
+}
+
+func summaryChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	if r.ReportSummary == nil {
+		return m
+	}
+	for _, s := range r.InspectSummary {
+		m[diffKey{item: s.SummaryName}] = diffCheck{failed: s.IsPanic, value: s.SummaryResult}
+	}
+	return m
+}
+
+func clusterChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.ClusterSummarys {
+		m[diffKey{item: s.CheckItem}] = diffCheck{failed: s.CheckResult != "正常", value: s.CheckResult}
+	}
+	return m
+}
+
+func devPracticeChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.DevBestPractices {
+		m[diffKey{item: s.CheckItem}] = diffCheck{failed: s.CheckResult != "正常", value: s.CheckResult}
+	}
+	return m
+}
+
+func statisticsChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.DatabaseStatistics {
+		m[diffKey{item: s.CheckItem}] = diffCheck{failed: s.CheckResult != "正常", value: s.CheckResult}
+	}
+	return m
+}
+
+// systemConfigChecks returns the hosts with the abnormal system config, the host not listed is regarded as passed
+func systemConfigChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.SystemConfigOutputs {
+		m[diffKey{item: "系统配置", instance: s.IpAddress}] = diffCheck{failed: true, value: "异常"}
+	}
+	return m
+}
+
+func dmesgChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.SystemDmesgs {
+		m[diffKey{item: "dmesg", instance: s.IpAddress}] = diffCheck{failed: s.AbnormalStatus != "正常", value: s.AbnormalStatus}
+	}
+	return m
+}
+
+func errorLogChecks(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.DatabaseErrorCounts {
+		if s.ErrorCount != "" {
+			m[diffKey{item: "错误日志", instance: s.InstAddress}] = diffCheck{failed: true, value: "异常"}
+		}
+	}
+	return m
+}
+
+func variableParams(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.DatabaseVaribales {
+		m[diffKey{item: s.ParamName, instance: s.Component}] = diffCheck{failed: s.IsStandard == "否", value: s.CurrentValue}
+	}
+	return m
+}
+
+func configParams(r *Report) map[diffKey]diffCheck {
+	m := make(map[diffKey]diffCheck)
+	for _, s := range r.DatabaseConfigs {
+		m[diffKey{item: s.ParamName, instance: s.Instance}] = diffCheck{failed: s.IsStandard == "否", value: s.CurrentValue}
+	}
+	return m
+}
+
+// softwareMetrics returns the software values, the inspection window suffix of the category is removed so that
+// the runs with the different windows are compared
+func softwareMetrics(r *Report) map[diffKey]string {
+	m := make(map[diffKey]string)
+	for _, s := range r.BasicSoftwares {
+		m[diffKey{item: windowSuffixRegexp.ReplaceAllString(s.Category, "")}] = s.Value
+	}
+	return m
+}
+
+func pdMetrics(r *Report) map[diffKey]string {
+	m := make(map[diffKey]string)
+	for _, s := range r.PerformanceStatisticsByPds {
+		m[diffKey{item: s.MonitoringItems + " (avg)", instance: s.PDInstance}] = s.AvgMetrics
+		m[diffKey{item: s.MonitoringItems + " (max)", instance: s.PDInstance}] = s.MaxMetrics
+	}
+	return m
+}
+
+func tidbMetrics(r *Report) map[diffKey]string {
+	m := make(map[diffKey]string)
+	for _, s := range r.PerformanceStatisticsByTidbs {
+		m[diffKey{item: s.MonitoringItems + " (avg)", instance: s.TiDBInstance}] = s.AvgMetrics
+		m[diffKey{item: s.MonitoringItems + " (max)", instance: s.TiDBInstance}] = s.MaxMetrics
+	}
+	return m
+}
+
+func tikvMetrics(r *Report) map[diffKey]string {
+	m := make(map[diffKey]string)
+	for _, s := range r.PerformanceStatisticsByTikvs {
+		m[diffKey{item: s.MonitoringItems + " (avg)", instance: s.TiKVInstance}] = s.AvgMetrics
+		m[diffKey{item: s.MonitoringItems + " (max)", instance: s.TiKVInstance}] = s.MaxMetrics
+	}
+	return m
+}
+
+// GenInspectDiffReport writes the html diff report of two inspection runs
+func GenInspectDiffReport(d *InspectDiff, file *os.File) error {
+	tpl := template.New("inspection_diff")
+
+	tf, err := tpl.ParseFS(fs, "template/*.html")
+	if err != nil {
+		return fmt.Errorf("template parse FS failed: %v", err)
+	}
+
+	if err = tf.ExecuteTemplate(file, "report_header", nil); err != nil {
+		return fmt.Errorf("template FS Execute [report_header] template HTML failed: %v", err)
+	}
+
+	if err = tf.ExecuteTemplate(file, "report_diff", d); err != nil {
+		return fmt.Errorf("template FS Execute [report_diff] template HTML failed: %v", err)
+	}
+
+	if err = tf.ExecuteTemplate(file, "report_footer", nil); err != nil {
+		return fmt.Errorf("template FS Execute [report_footer] template HTML failed: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+)
+
+// SaveInspectRun persists the structured report of the inspection run, the returned run id is used by [inspect history show] and [inspect diff]
+func SaveInspectRun(ctx context.Context, clusterName string, r *Report, reportFile, abnormalFile string) (*sqlite.InspectRun, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	content, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal inspect report: %v", err)
+	}
+
+	var abnormalCount int64
+	if r.ReportSummary != nil {
+		for _, s := range r.InspectSummary {
+			if s.IsPanic {
+				abnormalCount++
+			}
+		}
+	}
+	run := &sqlite.InspectRun{
+		ClusterName:   clusterName,
+		AbnormalCount: abnormalCount,
+		ReportFile:    reportFile,
+		AbnormalFile:  abnormalFile,
+		Report:        string(content),
+	}
+	if r.ReportBody != nil {
+		run.InspectionTime = r.InspectionTime
+		run.InspectionWindow = r.InspectionWindow
+	}
+	return db.(*sqlite.Database).CreateInspectRun(ctx, run)
+}
+
+// ListInspectRun returns the latest inspection runs without the report content, the empty cluster name returns the runs of all clusters
+func ListInspectRun(ctx context.Context, clusterName string, limit int) ([]*sqlite.InspectRun, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	return db.(*sqlite.Database).FindInspectRun(ctx, clusterName, limit)
+}
+
+// GetInspectRun returns the inspection run and its structured report
+func GetInspectRun(ctx context.Context, id uint64) (*sqlite.InspectRun, *Report, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	run, err := db.(*sqlite.Database).GetInspectRun(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if run.ID == 0 {
+		return nil, nil, fmt.Errorf("the inspection run id [%d] not found, please run [inspect history list] to query the inspection runs", id)
+	}
+	r := &Report{}
+	if err := json.Unmarshal([]byte(run.Report), r); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal the inspection run [%d] report: %v", id, err)
+	}
+	if r.ReportBody == nil {
+		r.ReportBody = &ReportBody{}
+	}
+	if r.ReportSummary == nil {
+		r.ReportSummary = &ReportSummary{}
+	}
+	if r.ReportDetail == nil {
+		r.ReportDetail = &ReportDetail{}
+	}
+	if r.ReportAbnormal == nil {
+		r.ReportAbnormal = &ReportAbnormal{}
+	}
+	return run, r, nil
+}
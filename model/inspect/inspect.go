@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -120,9 +121,55 @@ func NewInspector(ctx context.Context, clusterPath, clusterName, sshDir string,
 	}, nil
 }
 
-func (i *Insepctor) GenInspectionWindow() {
-	i.endTime = time.Now().UTC()
-	i.startTime = i.endTime.Add(-time.Duration(i.inspConfig.WindowMinutes/60) * time.Hour)
+// GenInspectionWindow generates the time range of the inspection, the explicit range of start and end takes precedence over
+// the nearly minutes, and the window_minutes of the inspection config ending now is used when neither is set. The time range
+// is used by all prometheus, statements summary and ng-monitoring queries of the inspection
+func (i *Insepctor) GenInspectionWindow(nearly int, start, end string) error {
+	switch {
+	case start != "" || end != "":
+		if start == "" || end == "" {
+			return fmt.Errorf("the inspection time range requires both the flag [--start] and flag [--end]")
+		}
+		layout := "2006-01-02 15:04:05"
+		startTime, err := time.ParseInLocation(layout, start, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid inspection start time [%s], the format is [%s]: %v", start, layout, err)
+		}
+		endTime, err := time.ParseInLocation(layout, end, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid inspection end time [%s], the format is [%s]: %v", end, layout, err)
+		}
+		if !endTime.After(startTime) {
+			return fmt.Errorf("the inspection end time [%s] must be later than the start time [%s]", end, start)
+		}
+		i.startTime, i.endTime = startTime, endTime
+	case nearly > 0:
+		i.endTime = time.Now()
+		i.startTime = i.endTime.Add(-time.Duration(nearly) * time.Minute)
+	default:
+		if i.inspConfig.WindowMinutes <= 0 {
+			return fmt.Errorf("invalid inspection config window_minutes [%d], it must be greater than 0", i.inspConfig.WindowMinutes)
+		}
+		i.endTime = time.Now()
+		i.startTime = i.endTime.Add(-time.Duration(i.inspConfig.WindowMinutes) * time.Minute)
+	}
+	return nil
+}
+
+// GetInspectionWindow returns the time range of the inspection
+func (i *Insepctor) GetInspectionWindow() (time.Time, time.Time) {
+	return i.startTime, i.endTime
+}
+
+// windowRange returns the prometheus range duration of the inspection window, e.g. 5400s, the query aggregated over the range
+// is evaluated at the end time only, so that the samples before the start time are not included
+func (i *Insepctor) windowRange() string {
+	return fmt.Sprintf("%ds", int64(i.endTime.Sub(i.startTime).Seconds()))
+}
+
+// windowHours returns the hours of the inspection window
+func (i *Insepctor) windowHours() float64 {
+	return i.endTime.Sub(i.startTime).Hours()
 }
 
 func (i *Insepctor) GenPDServerAPIPrefix() (string, error) {
@@ -608,7 +655,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			return true
 		},
 		func() error {
-			resp, err := request.Request(request.DefaultRequestMethodGet, fmt.Sprintf("%s/cluster", pdAPI), nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, fmt.Sprintf("%s/cluster", pdAPI), nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -640,7 +687,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -672,7 +719,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -706,7 +753,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -717,7 +764,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			}
 
 			bs = append(bs, &BasicSoftware{
-				Category: fmt.Sprintf("QPS 峰值（%.2fH）", float64(i.inspConfig.WindowMinutes/60)),
+				Category: fmt.Sprintf("QPS 峰值（%.2fH）", i.windowHours()),
 				Value:    value.Round(2).String(),
 			})
 			return nil
@@ -739,7 +786,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -750,7 +797,7 @@ func (i *Insepctor) InspClusterSoftware() ([]*BasicSoftware, error) {
 			}
 
 			bs = append(bs, &BasicSoftware{
-				Category: fmt.Sprintf("SQL duration P99 均值（%.2fH）", float64(i.inspConfig.WindowMinutes/60)),
+				Category: fmt.Sprintf("SQL duration P99 均值（%.2fH）", i.windowHours()),
 				Value:    fmt.Sprintf("%vms", value.Mul(decimal.NewFromInt(1000)).Round(2).String()),
 			})
 			return nil
@@ -1008,7 +1055,7 @@ HAVING
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -1022,7 +1069,7 @@ HAVING
 			if err != nil {
 				return err
 			}
-			resp, err = request.Request(request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err = request.Request(i.ctx, request.DefaultRequestMethodGet, prompReq, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -1110,7 +1157,7 @@ HAVING
 			if err != nil {
 				return err
 			}
-			resp, err := request.Request(request.DefaultRequestMethodGet, fmt.Sprintf("%s/schedulers", pdAPI), nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, fmt.Sprintf("%s/schedulers", pdAPI), nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -1237,72 +1284,31 @@ func (i *Insepctor) InspDevBestPractices() ([]*DevBestPractice, bool, []*InspDev
 		devAbnormalOutputs []*InspDevBestPracticesAbnormalOutput
 	)
 
-	database := i.connector.(*mysql.Database)
-
-	_, res, err := database.GeneralQuery(i.ctx, `select version() AS VERSION`)
+	version, err := i.clusterVersion()
 	if err != nil {
 		return nil, false, nil, err
 	}
-	/*
-		select version();
-		+--------------------+
-		| version()          |
-		+--------------------+
-		| 5.7.25-TiDB-v6.5.6 |
-		+--------------------+
-		1 row in set (0.00 sec)
-	*/
-	vers := strings.Split(res[0]["VERSION"], "-")
-
-	// 适配平凯数据库版本 8.0.11-TiDB-v7.1.8-5.2
-	var version string
-	if len(vers) > 3 {
-		tmpVers := strings.Split(strings.TrimPrefix(vers[len(vers)-2], "v"), ".")
-		version = fmt.Sprintf("%s.%s", tmpVers[len(tmpVers)-1], vers[len(vers)-1])
-	} else {
-		version = strings.TrimPrefix(vers[len(vers)-1], "v")
-	}
 
 	globalExceedFlag := false
-	for seq, dbp := range DefaultDevBestPracticesInspItems() {
-		if seq == 26 {
-			// JSON >= v6.5.0
-			if stringutil.VersionOrdinal(version) >= stringutil.VersionOrdinal("6.5.0") {
-				devBests = append(devBests, &DevBestPractice{
-					CheckItem:         dbp.CheckItem,
-					CheckCategory:     dbp.CheckCategory,
-					CorrectionSuggest: dbp.RectificationType,
-					BestPracticeDesc:  dbp.BestPracticeDesc,
-					CheckResult:       "正常",
-					AbnormalDetail:    fmt.Sprintf("数据库版本 [%v] 符合 JSON 数据类型启用最低要求", version),
-				})
-				continue
-			}
-		} else if seq == 27 {
-			// Partition >= v6.5.0
-			if stringutil.VersionOrdinal(version) >= stringutil.VersionOrdinal("6.5.0") {
-				devBests = append(devBests, &DevBestPractice{
-					CheckItem:         dbp.CheckItem,
-					CheckCategory:     dbp.CheckCategory,
-					CorrectionSuggest: dbp.RectificationType,
-					BestPracticeDesc:  dbp.BestPracticeDesc,
-					CheckResult:       "正常",
-					AbnormalDetail:    fmt.Sprintf("数据库版本 [%v] 符合分区表功能特性启用最低要求", version),
-				})
-				continue
-			}
-		}
-
-		_, res, err := database.GeneralQuery(i.ctx, dbp.CheckSql)
+	for _, dbp := range i.inspConfig.devBestPracticesInspItems() {
+		v, err := i.runSQLCheck(version, dbp.CheckID, dbp.CheckSql, dbp.MinVersion, dbp.MaxVersion, dbp.VersionDetail, dbp.Threshold)
 		if err != nil {
 			return nil, false, nil, err
 		}
-		var results []string
-		for _, r := range res {
-			results = append(results, r["SQL_RESULT"])
+		results := v.results
+		if v.skipDetail != "" {
+			devBests = append(devBests, &DevBestPractice{
+				CheckItem:         dbp.CheckItem,
+				CheckCategory:     dbp.CheckCategory,
+				CorrectionSuggest: dbp.RectificationType,
+				BestPracticeDesc:  dbp.BestPracticeDesc,
+				CheckResult:       "正常",
+				AbnormalDetail:    v.skipDetail,
+			})
+			continue
 		}
 
-		if len(results) == 0 {
+		if !v.abnormal {
 			devBests = append(devBests, &DevBestPractice{
 				CheckItem:         dbp.CheckItem,
 				CheckCategory:     dbp.CheckCategory,
@@ -1484,58 +1490,29 @@ func (i *Insepctor) InspDatabaseStatistics() ([]*DatabaseStatistics, bool, []*In
 		statsAbnormalOutputs []*InspDatabaseStatisticsAbnormalOutput
 	)
 
-	database := i.connector.(*mysql.Database)
-
-	_, res, err := database.GeneralQuery(i.ctx, `select version() AS VERSION`)
+	version, err := i.clusterVersion()
 	if err != nil {
 		return nil, false, nil, err
 	}
-	/*
-		select version();
-		+--------------------+
-		| version()          |
-		+--------------------+
-		| 5.7.25-TiDB-v6.5.6 |
-		+--------------------+
-		1 row in set (0.00 sec)
-	*/
-	vers := strings.Split(res[0]["VERSION"], "-")
-
-	// 适配平凯数据库版本 8.0.11-TiDB-v7.1.8-5.2
-	var version string
-	if len(vers) > 3 {
-		tmpVers := strings.Split(strings.TrimPrefix(vers[len(vers)-2], "v"), ".")
-		version = fmt.Sprintf("%s.%s", tmpVers[len(tmpVers)-1], vers[len(vers)-1])
-	} else {
-		version = strings.TrimPrefix(vers[len(vers)-1], "v")
-	}
 
 	globalExceedFlag := false
-	for seq, dbp := range DefaultInspDatabaseStatisticsItems() {
-		if seq == 10 {
-			// stats lock >= v6.5.0
-			if stringutil.VersionOrdinal(version) >= stringutil.VersionOrdinal("8.1.0") {
-				ds = append(ds, &DatabaseStatistics{
-					CheckItem:      dbp.CheckItem,
-					CheckStandard:  dbp.CheckStandard,
-					CheckResult:    "正常",
-					AbnormalDetail: fmt.Sprintf("数据库版本 [%v] 符合锁定统计信息功能启用最低要求", version),
-				})
-				continue
-			}
-		}
-
-		_, res, err := database.GeneralQuery(i.ctx, dbp.CheckSql)
+	for _, dbp := range i.inspConfig.databaseStatisticsInspItems() {
+		v, err := i.runSQLCheck(version, dbp.CheckID, dbp.CheckSql, dbp.MinVersion, dbp.MaxVersion, dbp.VersionDetail, dbp.Threshold)
 		if err != nil {
 			return nil, false, nil, err
 		}
-		var results []string
-
-		for _, r := range res {
-			results = append(results, r["SQL_RESULT"])
+		results := v.results
+		if v.skipDetail != "" {
+			ds = append(ds, &DatabaseStatistics{
+				CheckItem:      dbp.CheckItem,
+				CheckStandard:  dbp.CheckStandard,
+				CheckResult:    "正常",
+				AbnormalDetail: v.skipDetail,
+			})
+			continue
 		}
 
-		if len(results) == 0 {
+		if !v.abnormal {
 			ds = append(ds, &DatabaseStatistics{
 				CheckItem:      dbp.CheckItem,
 				CheckStandard:  dbp.CheckStandard,
@@ -1578,13 +1555,21 @@ func (i *Insepctor) InspDatabaseStatistics() ([]*DatabaseStatistics, bool, []*In
 				InspDatabaseStatistics: dbp,
 				AbnormalDetail:         strings.Join(results, "\n"),
 				AbnormalCounts:         abnormalCounts,
-				Comment:                "",
+				Comment:                dbp.Severity,
 			})
 		}
 	}
 	return ds, globalExceedFlag, statsAbnormalOutputs, nil
 }
 
+// diskLatencyThreshold returns the disk latency threshold name and value of the host, the host deploying tikv uses the tikv threshold
+func (i *Insepctor) diskLatencyThreshold(host string, tikvHosts map[string][]string, tikvMetric, hostMetric string) (string, *MetricThreshold) {
+	if _, ok := tikvHosts[host]; ok {
+		return tikvMetric, i.inspConfig.metricThreshold(tikvMetric)
+	}
+	return hostMetric, i.inspConfig.metricThreshold(hostMetric)
+}
+
 func (i *Insepctor) InspSystemConfig() ([]*SystemConfig, []*SystemConfigOutput, error) {
 	i.logger.Infof("+ Inspect system config practices")
 
@@ -1644,8 +1629,7 @@ func (i *Insepctor) InspSystemConfig() ([]*SystemConfig, []*SystemConfigOutput,
 			return true
 		},
 		func() error {
-			var bs strings.Builder
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, diskWriteApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, diskWriteApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -1656,19 +1640,21 @@ func (i *Insepctor) InspSystemConfig() ([]*SystemConfig, []*SystemConfigOutput,
 			}
 
 			for inst, devices := range avgVal {
+				host := strings.Split(inst, ":")[0]
+				name, threshold := i.diskLatencyThreshold(host, tikvDirMountPoints, MetricTiKVDiskWriteLatency, MetricHostDiskWriteLatency)
+
 				var deviceInfos []string
 				for d, val := range devices {
 					// seconds -> ms
-					if val.Mul(decimalMs).LessThan(decimal.NewFromFloat(DefaultHostDiskWriteLatencyUnderline * 1000)) {
-						deviceInfos = append(deviceInfos, fmt.Sprintf("- %s", d))
+					valMs := val.Mul(decimalMs)
+					if verdict := threshold.verdict(valMs.InexactFloat64()); verdict != "" {
+						deviceInfos = append(deviceInfos, fmt.Sprintf("- %s %vms %s", d, valMs.Round(2).String(), verdict))
 					}
 				}
 
 				if len(deviceInfos) > 0 {
-					bs.WriteString("检查以下主机磁盘平均写延迟超过 10ms:\n")
-					instSli := strings.Split(inst, ":")
-					bs.WriteString(strings.Join(deviceInfos, "\n"))
-					sysConfigOutputs[instSli[0]] = append(sysConfigOutputs[instSli[0]], bs.String())
+					sort.Strings(deviceInfos)
+					sysConfigOutputs[host] = append(sysConfigOutputs[host], fmt.Sprintf("检查以下主机磁盘平均写延迟超过阈值 [%s]:\n%s", threshold.describe(name), strings.Join(deviceInfos, "\n")))
 				}
 			}
 			return nil
@@ -1692,8 +1678,7 @@ func (i *Insepctor) InspSystemConfig() ([]*SystemConfig, []*SystemConfigOutput,
 			return true
 		},
 		func() error {
-			var bs strings.Builder
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, diskReadApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, diskReadApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -1704,19 +1689,21 @@ func (i *Insepctor) InspSystemConfig() ([]*SystemConfig, []*SystemConfigOutput,
 			}
 
 			for inst, devices := range avgVal {
+				host := strings.Split(inst, ":")[0]
+				name, threshold := i.diskLatencyThreshold(host, tikvDirMountPoints, MetricTiKVDiskReadLatency, MetricHostDiskReadLatency)
+
 				var deviceInfos []string
 				for d, val := range devices {
 					// seconds -> ms
-					if val.Mul(decimalMs).LessThan(decimal.NewFromFloat(DefaultHostDiskReadLatencyUnderline * 1000)) {
-						deviceInfos = append(deviceInfos, fmt.Sprintf("- %s", d))
+					valMs := val.Mul(decimalMs)
+					if verdict := threshold.verdict(valMs.InexactFloat64()); verdict != "" {
+						deviceInfos = append(deviceInfos, fmt.Sprintf("- %s %vms %s", d, valMs.Round(2).String(), verdict))
 					}
 				}
 
 				if len(deviceInfos) > 0 {
-					bs.WriteString("检查以下主机磁盘平均读延迟超过 10ms:\n")
-					instSli := strings.Split(inst, ":")
-					bs.WriteString(strings.Join(deviceInfos, "\n"))
-					sysConfigOutputs[instSli[0]] = append(sysConfigOutputs[instSli[0]], bs.String())
+					sort.Strings(deviceInfos)
+					sysConfigOutputs[host] = append(sysConfigOutputs[host], fmt.Sprintf("检查以下主机磁盘平均读延迟超过阈值 [%s]:\n%s", threshold.describe(name), strings.Join(deviceInfos, "\n")))
 				}
 			}
 			return nil
@@ -2393,6 +2380,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 		inspTasks []*task.StepDisplay
 	)
 	statusPortMapping := i.topo.GetClusterComponentStatusServicePortMapping()
+	decimalMs := decimal.NewFromInt(1000)
 
 	for _, host := range i.topo.GetClusterTopologyHostIps() {
 		// Construct the SSH command
@@ -2450,11 +2438,11 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 
 	i.logger.Infof("  - Inspect pd component cpu usage")
 
-	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(rate(process_cpu_seconds_total{job="pd"}[1m])[%dm:])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(rate(process_cpu_seconds_total{job="pd"}[1m])[%s:])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(rate(process_cpu_seconds_total{job="pd"}[1m])[%dm:])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(rate(process_cpu_seconds_total{job="pd"}[1m])[%s:])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -2468,7 +2456,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2478,7 +2466,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 				return err
 			}
 
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2528,7 +2516,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 			return true
 		},
 		func() error {
-			regionResp, err := request.Request(request.DefaultRequestMethodGet, regionApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			regionResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, regionApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2543,17 +2531,19 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 				return err
 			}
 
-			suggest := fmt.Sprintf("%2.fms", DefaultPdComponentRegionHeartbeatHandleLatencyUnderline*1000)
+			// seconds -> ms
+			threshold := i.inspConfig.metricThreshold(MetricPdRegionHeartbeatHandleLatency)
 			for inst, avg := range avgRegionVal {
-				if avg.GreaterThan(decimal.NewFromFloat(DefaultPdComponentRegionHeartbeatHandleLatencyUnderline)) {
+				avgMs := avg.Mul(decimalMs)
+				if verdict := threshold.verdict(avgMs.InexactFloat64()); verdict != "" {
 					psbp = append(psbp, &PerformanceStatisticsByPD{
 						PDInstance:      statusPortMapping[inst],
 						MonitoringItems: "99% region heartbeat handle latency",
-						AvgMetrics:      fmt.Sprintf(`%vms`, avg.Round(2).String()),
-						MaxMetrics:      fmt.Sprintf(`%vms`, maxRegionVal[inst].Round(2).String()),
-						ParamValue:      suggest,
-						SuggestValue:    fmt.Sprintf("应低于 %v", suggest),
-						Comment:         "经验延迟值",
+						AvgMetrics:      fmt.Sprintf(`%vms`, avgMs.Round(2).String()),
+						MaxMetrics:      fmt.Sprintf(`%vms`, maxRegionVal[inst].Mul(decimalMs).Round(2).String()),
+						ParamValue:      threshold.describe(MetricPdRegionHeartbeatHandleLatency),
+						SuggestValue:    fmt.Sprintf("应低于 %vms", formatThresholdValue(threshold.Warn)),
+						Comment:         threshold.comment(MetricPdRegionHeartbeatHandleLatency, verdict),
 					})
 				}
 			}
@@ -2579,7 +2569,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 			return true
 		},
 		func() error {
-			requestResp, err := request.Request(request.DefaultRequestMethodGet, requestApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			requestResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, requestApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2594,17 +2584,18 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 				return err
 			}
 
-			suggest := fmt.Sprintf("%2.fms", DefaultPdComponentHandleReuqestDurationUnderline*1000)
-
-			if avgRequestVal.GreaterThan(decimal.NewFromFloat(DefaultPdComponentHandleReuqestDurationUnderline)) {
+			// seconds -> ms
+			threshold := i.inspConfig.metricThreshold(MetricPdHandleRequestDuration)
+			avgMs := avgRequestVal.Mul(decimalMs)
+			if verdict := threshold.verdict(avgMs.InexactFloat64()); verdict != "" {
 				psbp = append(psbp, &PerformanceStatisticsByPD{
 					PDInstance:      i.topo.GetClusterComponentPDComponenetLeaderServiceAddress(),
 					MonitoringItems: "99% handle request duration",
-					AvgMetrics:      fmt.Sprintf(`%vms`, avgRequestVal.Round(2).String()),
-					MaxMetrics:      fmt.Sprintf(`%vms`, maxRequestVal.Round(2).String()),
-					ParamValue:      suggest,
-					SuggestValue:    fmt.Sprintf("应低于 %v", suggest),
-					Comment:         "经验延迟值",
+					AvgMetrics:      fmt.Sprintf(`%vms`, avgMs.Round(2).String()),
+					MaxMetrics:      fmt.Sprintf(`%vms`, maxRequestVal.Mul(decimalMs).Round(2).String()),
+					ParamValue:      threshold.describe(MetricPdHandleRequestDuration),
+					SuggestValue:    fmt.Sprintf("应低于 %vms", formatThresholdValue(threshold.Warn)),
+					Comment:         threshold.comment(MetricPdHandleRequestDuration, verdict),
 				})
 			}
 			return nil
@@ -2629,7 +2620,7 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 			return true
 		},
 		func() error {
-			walResp, err := request.Request(request.DefaultRequestMethodGet, walApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			walResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, walApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2644,17 +2635,19 @@ func (i *Insepctor) InspPerformanceStatisticsByPD() ([]*PerformanceStatisticsByP
 				return err
 			}
 
-			suggest := fmt.Sprintf("%2.fms", DefaultPdComponentWalFsyncDurationUnderline*1000)
+			// seconds -> ms
+			threshold := i.inspConfig.metricThreshold(MetricPdWalFsyncDuration)
 			for inst, avg := range avgWalVal {
-				if avg.GreaterThan(decimal.NewFromFloat(DefaultPdComponentWalFsyncDurationUnderline)) {
+				avgMs := avg.Mul(decimalMs)
+				if verdict := threshold.verdict(avgMs.InexactFloat64()); verdict != "" {
 					psbp = append(psbp, &PerformanceStatisticsByPD{
 						PDInstance:      statusPortMapping[inst],
 						MonitoringItems: "99% WAL fsync duration",
-						AvgMetrics:      fmt.Sprintf(`%vms`, avg.Round(2).String()),
-						MaxMetrics:      fmt.Sprintf(`%vms`, maxWalVal[inst].Round(2).String()),
-						ParamValue:      suggest,
-						SuggestValue:    fmt.Sprintf("应低于 %v", suggest),
-						Comment:         "经验延迟值",
+						AvgMetrics:      fmt.Sprintf(`%vms`, avgMs.Round(2).String()),
+						MaxMetrics:      fmt.Sprintf(`%vms`, maxWalVal[inst].Mul(decimalMs).Round(2).String()),
+						ParamValue:      threshold.describe(MetricPdWalFsyncDuration),
+						SuggestValue:    fmt.Sprintf("应低于 %vms", formatThresholdValue(threshold.Warn)),
+						Comment:         threshold.comment(MetricPdWalFsyncDuration, verdict),
 					})
 				}
 			}
@@ -2759,11 +2752,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tidb component cpu usage")
 
-	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(rate(process_cpu_seconds_total{job="tidb"}[1m])[%dm:])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(rate(process_cpu_seconds_total{job="tidb"}[1m])[%s:])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(rate(process_cpu_seconds_total{job="tidb"}[1m])[%dm:])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(rate(process_cpu_seconds_total{job="tidb"}[1m])[%s:])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -2777,7 +2770,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2785,7 +2778,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2839,11 +2832,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tidb component memory usage")
 
-	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(process_resident_memory_bytes{job="tidb"}[%dm])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`avg_over_time(process_resident_memory_bytes{job="tidb"}[%s])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(process_resident_memory_bytes{job="tidb"}[%dm])`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`max_over_time(process_resident_memory_bytes{job="tidb"}[%s])`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -2857,7 +2850,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2865,7 +2858,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2910,7 +2903,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			waitResp, err := request.Request(request.DefaultRequestMethodGet, waitApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			waitResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, waitApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -2926,17 +2919,17 @@ func (i *Insepctor) InspPerformanceStatisticsByTiDB() ([]*PerformanceStatisticsB
 
 			// nanoseconds to milliseconds
 			decimalMs := decimal.NewFromInt(1000000)
-			suggest := fmt.Sprintf("%2.fms", DefaultTiDBComponentCommitTokenWaitDurationUnderline*1000)
+			threshold := i.inspConfig.metricThreshold(MetricTiDBCommitTokenWaitDuration)
 			for inst, avg := range waitAvgVal {
-				if avg.DivRound(decimalMs, 2).GreaterThan(decimal.NewFromFloat(DefaultTiDBComponentCommitTokenWaitDurationUnderline)) {
+				if verdict := threshold.verdict(avg.DivRound(decimalMs, 2).InexactFloat64()); verdict != "" {
 					psbp = append(psbp, &PerformanceStatisticsByTiDB{
 						TiDBInstance:    statusPortMapping[inst],
 						MonitoringItems: "commit token wait duration",
 						AvgMetrics:      fmt.Sprintf(`%vms`, avg.DivRound(decimalMs, 2).String()),
 						MaxMetrics:      fmt.Sprintf(`%vms`, waitMaxVal[inst].DivRound(decimalMs, 2).String()),
-						ParamValue:      suggest,
-						SuggestValue:    fmt.Sprintf("应低于 %v", suggest),
-						Comment:         "经验延迟值",
+						ParamValue:      threshold.describe(MetricTiDBCommitTokenWaitDuration),
+						SuggestValue:    fmt.Sprintf("应低于 %vms", formatThresholdValue(threshold.Warn)),
+						Comment:         threshold.comment(MetricTiDBCommitTokenWaitDuration, verdict),
 					})
 				}
 			}
@@ -2980,11 +2973,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tikv component grpc cpu usage")
 
-	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"grpc_server.*"}[%dm]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"grpc_server.*"}[%s]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)(max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"grpc_server.*"}[1m])[%dm:]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err := i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)(max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"grpc_server.*"}[1m])[%s:]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -2998,7 +2991,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3006,7 +2999,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3043,11 +3036,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tikv component scheduler pool usage")
 
-	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"sched_.*"}[%dm]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"sched_.*"}[%s]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"sched_.*"}[1m])[%dm:]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"sched_.*"}[1m])[%s:]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -3061,7 +3054,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3069,7 +3062,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3106,11 +3099,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tikv component unified pool usage")
 
-	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"unified_read_po.*"}[%dm]) )`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"unified_read_po.*"}[%s]) )`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"unified_read_po.*"}[1m])[%dm:]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"unified_read_po.*"}[1m])[%s:]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -3124,7 +3117,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3132,7 +3125,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3169,11 +3162,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tikv component raft store pool usage")
 
-	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"raftstore_.*"}[%dm]) )`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"raftstore_.*"}[%s]) )`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"raftstore_.*"}[1m])[%dm:]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"raftstore_.*"}[1m])[%s:]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -3187,7 +3180,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3195,7 +3188,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3232,11 +3225,11 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 
 	i.logger.Infof("  - Inspect tikv component raft apply pool usage")
 
-	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"apply_.*"}[%dm]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	avgApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"apply_.*"}[%s]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
-	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"apply_.*"}[1m])[%dm:]))`, i.inspConfig.WindowMinutes), i.startTime, i.endTime)
+	maxApi, err = i.GenPrometheusAPIPrefix(fmt.Sprintf(`sum by(instance)( max_over_time(rate(tikv_thread_cpu_seconds_total{job="tikv", name=~"apply_.*"}[1m])[%s:]))`, i.windowRange()), i.endTime, i.endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -3250,7 +3243,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			avgResp, err := request.Request(request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			avgResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, avgApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3258,7 +3251,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			if err != nil {
 				return err
 			}
-			maxResp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			maxResp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3308,7 +3301,7 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 			return true
 		},
 		func() error {
-			resp, err := request.Request(request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+			resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, maxApi, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 			if err != nil {
 				return err
 			}
@@ -3323,17 +3316,18 @@ func (i *Insepctor) InspPerformanceStatisticsByTiKV() ([]*PerformanceStatisticsB
 				return err
 			}
 
-			discard := strconv.Itoa(DefaultTiKVComponentSchedulerDiscardRatioUnderline)
+			threshold := i.inspConfig.metricThreshold(MetricTiKVSchedulerDiscardRatio)
+			discard := formatThresholdValue(threshold.Warn)
 			for inst, val := range maxVal {
-				if val.GreaterThan(decimal.NewFromFloat(0)) {
+				if verdict := threshold.verdict(val.InexactFloat64()); verdict != "" {
 					psbp = append(psbp, &PerformanceStatisticsByTiKV{
 						TiKVInstance:    statusPortMapping[inst],
 						MonitoringItems: "scheduler discard ratio",
 						AvgMetrics:      fmt.Sprintf(`%v%%`, avgVal[inst].Round(2).String()),
 						MaxMetrics:      fmt.Sprintf(`%v%%`, val.Round(2).String()),
-						ParamValue:      discard,
-						SuggestValue:    fmt.Sprintf("应等于 %v，> %v 说明存在流控", discard, discard),
-						Comment:         "人为判断是否合理",
+						ParamValue:      threshold.describe(MetricTiKVSchedulerDiscardRatio),
+						SuggestValue:    fmt.Sprintf("应不超过 %v%%，> %v%% 说明存在流控", discard, discard),
+						Comment:         fmt.Sprintf("%s，人为判断是否合理", threshold.comment(MetricTiKVSchedulerDiscardRatio, verdict)),
 					})
 				}
 			}
@@ -3355,9 +3349,9 @@ func (i *Insepctor) InspSqlOrderedByElapsedTime() ([]*SqlOrderedByElapsedTime, e
 	_, res, err := db.GeneralQuery(i.ctx, fmt.Sprintf(`SELECT
   COALESCE(SUM(sum_latency)/1000000000, 0) AS SUM_LATENCY
 FROM information_schema.cluster_statements_summary_history a
-WHERE a.summary_begin_time <= NOW()
-  AND a.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
-  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.inspConfig.WindowMinutes))
+WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+  AND a.summary_end_time >= FROM_UNIXTIME(%d)
+  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -3395,13 +3389,13 @@ WHERE a.summary_begin_time <= NOW()
          DIGEST                     AS sql_digest,                 -- SQL Digest
          MIN(QUERY_SAMPLE_TEXT)     AS sql_text                    -- 示例 SQL 文本
       FROM information_schema.cluster_statements_summary_history a
-     WHERE a.summary_begin_time <= NOW()
-       AND a.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
+     WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+       AND a.summary_end_time >= FROM_UNIXTIME(%d)
        AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'
      GROUP BY DIGEST
   ) aaa
   ORDER BY total_latency_s DESC
-  LIMIT 10`, sumLatency.String(), i.inspConfig.WindowMinutes))
+  LIMIT 10`, sumLatency.String(), i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -3425,12 +3419,8 @@ WHERE a.summary_begin_time <= NOW()
 }
 
 func (i *Insepctor) InspSqlOrderedByComponentCpuTime(checkTidbCpu, checkTikVCpu bool) ([]*SqlOrderedByTiDBCpuTime, []*SqlOrderedByTiKVCpuTime, error) {
-	now := time.Now()
-
-	before := now.Add(-30 * time.Minute)
-
-	startSecs := before.Unix()
-	endSecs := now.Unix()
+	startSecs := i.startTime.Unix()
+	endSecs := i.endTime.Unix()
 
 	var (
 		tidbCpu []*SqlOrderedByTiDBCpuTime
@@ -3487,7 +3477,7 @@ func (i *Insepctor) InspSqlOrderedByTiDBCpuTime(startSecs, endSecs int64) ([]*Sq
 					return true
 				},
 				func() error {
-					resp, err := request.Request(request.DefaultRequestMethodGet, req, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+					resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, req, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 					if err != nil {
 						return err
 					}
@@ -3559,7 +3549,7 @@ func (i *Insepctor) InspSqlOrderedByTiKVCpuTime(startSecs, endSecs int64) ([]*Sq
 					return true
 				},
 				func() error {
-					resp, err := request.Request(request.DefaultRequestMethodGet, req, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
+					resp, err := request.Request(i.ctx, request.DefaultRequestMethodGet, req, nil, i.topo.ClusterMeta.TlsCaCert, i.topo.ClusterMeta.TlsClientCert, i.topo.ClusterMeta.TlsClientKey)
 					if err != nil {
 						return err
 					}
@@ -3604,9 +3594,9 @@ func (i *Insepctor) InspSqlOrderedByExecutions() ([]*SqlOrderedByExecution, erro
 	_, res, err := db.GeneralQuery(i.ctx, fmt.Sprintf(`SELECT
   COALESCE(SUM(sum_latency)/1000000000, 0) AS SUM_LATENCY
 FROM information_schema.cluster_statements_summary_history a
-WHERE a.summary_begin_time <= NOW()
-  AND a.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
-  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.inspConfig.WindowMinutes))
+WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+  AND a.summary_end_time >= FROM_UNIXTIME(%d)
+  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -3647,13 +3637,13 @@ WHERE a.summary_begin_time <= NOW()
           DIGEST                           AS sql_digest,
           MIN(QUERY_SAMPLE_TEXT)           AS sql_text
         FROM information_schema.cluster_statements_summary_history a
-       WHERE a.summary_begin_time <= NOW()
-         AND a.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
+       WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+         AND a.summary_end_time >= FROM_UNIXTIME(%d)
          AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'
        GROUP BY SAMPLE_USER, DIGEST, SCHEMA_NAME
   ) aaa
   WINDOW w AS (ORDER BY total_execs DESC)
-  LIMIT 10`, sumLatency.String(), i.inspConfig.WindowMinutes))
+  LIMIT 10`, sumLatency.String(), i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -3685,9 +3675,9 @@ func (i *Insepctor) InspSqlOrderedByPlans() ([]*SqlOrderedByPlan, error) {
 	_, res, err := db.GeneralQuery(i.ctx, fmt.Sprintf(`SELECT
   COALESCE(SUM(sum_latency)/1000000000, 0) AS SUM_LATENCY
 FROM information_schema.cluster_statements_summary_history a
-WHERE a.summary_begin_time <= NOW()
-  AND a.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
-  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.inspConfig.WindowMinutes))
+WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+  AND a.summary_end_time >= FROM_UNIXTIME(%d)
+  AND a.query_sample_text NOT LIKE '%%/*+ monitoring */%%'`, i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -3713,8 +3703,8 @@ FROM (
             WHERE sub_min.DIGEST = a.DIGEST
                 AND sub_min.sample_user = a.SAMPLE_USER
                 AND sub_min.schema_name = a.SCHEMA_NAME 
-                AND sub_min.summary_begin_time <= NOW()
-                AND sub_min.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
+                AND sub_min.summary_begin_time <= FROM_UNIXTIME(%d)
+                AND sub_min.summary_end_time >= FROM_UNIXTIME(%d)
                 AND sub_min.QUERY_SAMPLE_TEXT NOT LIKE '%%/*+ monitoring */%%'
             ORDER BY min_latency ASC
             LIMIT 1
@@ -3725,8 +3715,8 @@ FROM (
             WHERE sub_max.DIGEST = a.DIGEST
                 AND sub_max.sample_user = a.SAMPLE_USER
                 AND sub_max.schema_name = a.SCHEMA_NAME 
-                AND sub_max.summary_begin_time <= NOW()
-                AND sub_max.summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
+                AND sub_max.summary_begin_time <= FROM_UNIXTIME(%d)
+                AND sub_max.summary_end_time >= FROM_UNIXTIME(%d)
                 AND sub_max.QUERY_SAMPLE_TEXT NOT LIKE '%%/*+ monitoring */%%'
             ORDER BY max_latency DESC
             LIMIT 1
@@ -3737,8 +3727,8 @@ FROM (
         DIGEST AS sql_digest,
         MIN(QUERY_SAMPLE_TEXT) AS sql_text
     FROM information_schema.cluster_statements_summary_history a
-    WHERE a.summary_begin_time <= NOW()
-        AND summary_end_time >= DATE_ADD(NOW(), INTERVAL - %v MINUTE)
+    WHERE a.summary_begin_time <= FROM_UNIXTIME(%d)
+        AND summary_end_time >= FROM_UNIXTIME(%d)
         AND a.QUERY_SAMPLE_TEXT NOT LIKE '%%/*+ monitoring */%%'
     GROUP BY
         SAMPLE_USER,
@@ -3747,7 +3737,7 @@ FROM (
     HAVING COUNT(DISTINCT plan_digest) > 1
 ) aaa WINDOW w AS (
     ORDER BY plan_digest_counts DESC
-) LIMIT 10`, i.inspConfig.WindowMinutes, i.inspConfig.WindowMinutes, sumLatency.String(), i.inspConfig.WindowMinutes))
+) LIMIT 10`, i.endTime.Unix(), i.startTime.Unix(), i.endTime.Unix(), i.startTime.Unix(), sumLatency.String(), i.endTime.Unix(), i.startTime.Unix()))
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,6 @@ import (
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/database/sqlite"
 	"github.com/wentaojin/tidba/model"
-	"gopkg.in/yaml.v3"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -176,9 +175,12 @@ func YamlFileCreate(ctx context.Context, clusterName, file string) (string, erro
 
 func createInspect(ctx context.Context, clusterName, content string) error {
 	// validate required fields
-	var data *InspectConfig
-	if err := yaml.Unmarshal([]byte(content), &data); err != nil {
-		return fmt.Errorf("invalid YAML: %w", err)
+	data, err := ParseInspectConfig(content)
+	if err != nil {
+		return err
+	}
+	if err := data.Validate(); err != nil {
+		return err
 	}
 
 	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
@@ -54,7 +54,10 @@ func NewInspectDeleteModel(clusterName string) InspectDeleteModel {
 }
 
 func (m InspectDeleteModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitDelInspData(m.ctx, m.clusterName), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m InspectDeleteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -79,12 +82,8 @@ func (m InspectDeleteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Quit
 	default:
-		m.mode = model.BubblesModeDeleting
 		m.spinner, cmd = m.spinner.Update(msg)
-		return m, tea.Batch(
-			cmd,
-			submitDelInspData(m.ctx, m.clusterName), // submit delete data
-		)
+		return m, cmd
 	}
 }
 
@@ -55,7 +55,10 @@ func NewInspectQueryModel(clusterName string) InspectQueryModel {
 }
 
 func (m InspectQueryModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitListInspData(m.ctx, m.clusterName), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m InspectQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -81,10 +84,7 @@ func (m InspectQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		return m, tea.Batch(
-			cmd,
-			submitListInspData(m.ctx, m.clusterName), // submit list data
-		)
+		return m, cmd
 	}
 }
 
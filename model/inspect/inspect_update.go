@@ -219,6 +219,9 @@ func submitEditInspData(ctx context.Context, clusterName string, content string)
 		if err := yaml.Unmarshal([]byte(content), &data); err != nil {
 			return editInspResultMsg{data: data, err: fmt.Errorf("invalid YAML: %w", err)}
 		}
+		if err := data.Validate(); err != nil {
+			return editInspResultMsg{data: data, err: err}
+		}
 
 		db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
 		if err != nil {
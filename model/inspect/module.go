@@ -0,0 +1,429 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInspectModuleTimeout is the timeout of the inspection module without the module_timeout_seconds config
+const DefaultInspectModuleTimeout = 10 * time.Minute
+
+// inspectModuleCancelWait is how long the timed out module is waited to stop, the module context is cancelled at the timeout,
+// so that the database queries, the http requests and the ssh tasks of the module return the context error
+const inspectModuleCancelWait = 30 * time.Second
+
+// InspectModuleError is the failed or timed out inspection module, the report section of the module is missing
+type InspectModuleError struct {
+	Module      string
+	SummaryName string
+	Elapsed     string
+	Error       string
+}
+
+// inspectModule is the inspection module executed independently, the name is the yaml key of the config modules,
+// run returns the func applying the module results to the report, it is only called if the module succeeds
+type inspectModule struct {
+	name    string
+	summary string
+	enabled func(m *Modules) *bool
+	run     func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error)
+}
+
+// inspectModules returns the inspection modules in the report order
+func inspectModules() []*inspectModule {
+	return []*inspectModule{
+		{
+			name:    "check_hardware_info",
+			summary: "3.1 硬件基本信息",
+			enabled: func(m *Modules) *bool { return &m.CheckHardwareInfo },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspBasicHardwares()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.BasicHardwares = res }, nil
+			},
+		},
+		{
+			name:    "check_software_info",
+			summary: "3.2 软件基本信息",
+			enabled: func(m *Modules) *bool { return &m.CheckSoftwareInfo },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspClusterSoftware()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.BasicSoftwares = res }, nil
+			},
+		},
+		{
+			name:    "check_tidb_overview",
+			summary: "3.3 TiDB 集群总览",
+			enabled: func(m *Modules) *bool { return &m.CheckTidbOverview },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspClusterSummary()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.ClusterSummarys = res }, nil
+			},
+		},
+		{
+			name:    "check_dev_best_practices",
+			summary: "3.4 开发规范最佳实践",
+			enabled: func(m *Modules) *bool { return &m.CheckDevBestPractices },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, abnormalFlag, abnormalOutputs, err := i.InspDevBestPractices()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) {
+					rep.DevBestPractices = res
+					if abnormalFlag {
+						abnormal.DevAbnormals = abnormalOutputs
+					}
+				}, nil
+			},
+		},
+		{
+			name:    "check_db_params",
+			summary: "3.5 数据库参数最佳实践",
+			enabled: func(m *Modules) *bool { return &m.CheckDbParams },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				dbVariable, err := i.InspDatabaseVaribale()
+				if err != nil {
+					return nil, err
+				}
+				dbConfig, err := i.InspDatabaseConfig()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) {
+					rep.DatabaseVaribales = dbVariable
+					rep.DatabaseConfigs = dbConfig
+				}, nil
+			},
+		},
+		{
+			name:    "check_stats_best_practices",
+			summary: "3.6 统计信息最佳实践",
+			enabled: func(m *Modules) *bool { return &m.CheckStatsBestPractices },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, abnormalFlag, abnormalOutputs, err := i.InspDatabaseStatistics()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) {
+					rep.DatabaseStatistics = res
+					if abnormalFlag {
+						abnormal.StatsAbnormals = abnormalOutputs
+					}
+				}, nil
+			},
+		},
+		{
+			name:    "check_sys_config",
+			summary: "3.7 系统配置最佳实践",
+			enabled: func(m *Modules) *bool { return &m.CheckSysConfig },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				sysConfig, sysOutput, err := i.InspSystemConfig()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) {
+					rep.SystemConfigs = sysConfig
+					rep.SystemConfigOutputs = sysOutput
+				}, nil
+			},
+		},
+		{
+			name:    "check_crontab",
+			summary: "3.8 crontab 情况",
+			enabled: func(m *Modules) *bool { return &m.CheckCrontab },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspSystemCrontab()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SystemCrontabs = res }, nil
+			},
+		},
+		{
+			name:    "check_dmesg_logs",
+			summary: "3.9 dmesg 情况",
+			enabled: func(m *Modules) *bool { return &m.CheckDmesgLogs },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspSystemDmesg()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SystemDmesgs = res }, nil
+			},
+		},
+		{
+			name:    "check_db_error_logs",
+			summary: "3.10 数据库的错误日志统计",
+			enabled: func(m *Modules) *bool { return &m.CheckDbErrorLogs },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspDatabaseErrorCount()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.DatabaseErrorCounts = res }, nil
+			},
+		},
+		{
+			name:    "check_user_space",
+			summary: "3.11 用户对象占用空间分布",
+			enabled: func(m *Modules) *bool { return &m.CheckUserSpace },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				schemaSpace, err := i.InspDatabaseSchemaSpace()
+				if err != nil {
+					return nil, err
+				}
+				tableSpace, err := i.InspDatabaseTableSpaceTop()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) {
+					rep.DatabaseSchemaSpaces = schemaSpace
+					rep.DatabaseTableSpaceTops = tableSpace
+				}, nil
+			},
+		},
+		{
+			name:    "check_pd_performance",
+			summary: "4.1 Performance statistics by PD 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckPdPerformance },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspPerformanceStatisticsByPD()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.PerformanceStatisticsByPds = res }, nil
+			},
+		},
+		{
+			name:    "check_tidb_performance",
+			summary: "4.2 Performance statistics by TiDB 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckTidbPerformance },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspPerformanceStatisticsByTiDB()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.PerformanceStatisticsByTidbs = res }, nil
+			},
+		},
+		{
+			name:    "check_tikv_performance",
+			summary: "4.3 Performance statistics by TiKV 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckTikvPerformance },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspPerformanceStatisticsByTiKV()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.PerformanceStatisticsByTikvs = res }, nil
+			},
+		},
+		{
+			name:    "check_sql_order_by_elapsed_time",
+			summary: "5.1 SQL ordered by Elapsed Time 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckSQLOrderByElapsedTime },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspSqlOrderedByElapsedTime()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SqlOrderedByElapsedTimes = res }, nil
+			},
+		},
+		{
+			name:    "check_sql_order_by_tidb_cpu_time",
+			summary: "5.2 SQL ordered by TiDB CPU Time 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckSQLOrderByTidbCPUTime },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, _, err := i.InspSqlOrderedByComponentCpuTime(true, false)
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SqlOrderedByTiDBCpuTimes = res }, nil
+			},
+		},
+		{
+			name:    "check_sql_order_by_tikv_cpu_time",
+			summary: "5.3 SQL ordered by TiKV CPU Time 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckSQLOrderByTikvCPUTime },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				_, res, err := i.InspSqlOrderedByComponentCpuTime(false, true)
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SqlOrderedByTiKVCpuTimes = res }, nil
+			},
+		},
+		{
+			name:    "check_sql_order_by_executions",
+			summary: "5.4 SQL ordered by Executions 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckSQLOrderByExecutions },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspSqlOrderedByExecutions()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SqlOrderedByExecutions = res }, nil
+			},
+		},
+		{
+			name:    "check_sql_order_by_plans",
+			summary: "5.5 SQL ordered by Plans 检查",
+			enabled: func(m *Modules) *bool { return &m.CheckSQLOrderByPlans },
+			run: func(i *Insepctor) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+				res, err := i.InspSqlOrderedByPlans()
+				if err != nil {
+					return nil, err
+				}
+				return func(rep *ReportDetail, abnormal *ReportAbnormal) { rep.SqlOrderedByPlans = res }, nil
+			},
+		},
+	}
+}
+
+// InspectModuleNames returns the inspection module names in the report order, used by inspect start --modules / --skip-modules
+func InspectModuleNames() []string {
+	var names []string
+	for _, m := range inspectModules() {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// ValidateInspectModules checks the module names of inspect start --modules / --skip-modules and module_timeouts
+func ValidateInspectModules(names []string) error {
+	for _, n := range names {
+		found := false
+		for _, m := range InspectModuleNames() {
+			if m == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown inspection module [%s], options: %s", n, strings.Join(InspectModuleNames(), " / "))
+		}
+	}
+	return nil
+}
+
+// SelectModules overrides the enabled modules of the config, only the modules are enabled if the modules are specified,
+// and the skip modules are disabled
+func (i *InspectConfig) SelectModules(modules, skipModules []string) {
+	if i.Modules == nil {
+		i.Modules = DefaultInspectConfigTemplate().Modules
+	}
+	for _, m := range inspectModules() {
+		enabled := m.enabled(i.Modules)
+		if len(modules) > 0 {
+			*enabled = false
+			for _, n := range modules {
+				if n == m.name {
+					*enabled = true
+				}
+			}
+		}
+		for _, n := range skipModules {
+			if n == m.name {
+				*enabled = false
+			}
+		}
+	}
+}
+
+// moduleTimeout returns the timeout of the module, module_timeouts has the higher priority than module_timeout_seconds
+func (i *InspectConfig) moduleTimeout(name string) time.Duration {
+	if secs, ok := i.ModuleTimeouts[name]; ok && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if i.ModuleTimeoutSeconds > 0 {
+		return time.Duration(i.ModuleTimeoutSeconds) * time.Second
+	}
+	return DefaultInspectModuleTimeout
+}
+
+func validateModuleTimeouts(defaultSecs int, timeouts map[string]int) error {
+	if defaultSecs < 0 {
+		return fmt.Errorf("the module_timeout_seconds [%d] cannot be negative", defaultSecs)
+	}
+	var names []string
+	for name, secs := range timeouts {
+		if secs < 0 {
+			return fmt.Errorf("the module_timeouts [%s] timeout [%d] cannot be negative", name, secs)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return ValidateInspectModules(names)
+}
+
+// runModule runs the module with the timeout on a copy of the inspector bound to the module context, the panic of the module
+// is recovered as the module error. The module context is cancelled at the timeout and the module is waited to stop before
+// the next module runs, the module still running after the wait is abandoned and its results are discarded, the abandoned
+// ssh command is bounded by the ssh execute timeout
+func (i *Insepctor) runModule(m *inspectModule, timeout time.Duration) (func(rep *ReportDetail, abnormal *ReportAbnormal), error) {
+	ctx, cancel := context.WithTimeout(i.ctx, timeout)
+	defer cancel()
+
+	mi := *i
+	mi.ctx = ctx
+
+	type moduleResult struct {
+		apply func(rep *ReportDetail, abnormal *ReportAbnormal)
+		err   error
+	}
+	resultC := make(chan moduleResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				resultC <- moduleResult{err: fmt.Errorf("module panic: %v", r)}
+			}
+		}()
+		apply, err := m.run(&mi)
+		resultC <- moduleResult{apply: apply, err: err}
+	}()
+
+	select {
+	case r := <-resultC:
+		return r.apply, r.err
+	case <-ctx.Done():
+	}
+
+	err := ctx.Err()
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("module timeout after %v", timeout)
+	}
+	select {
+	case <-resultC:
+	case <-time.After(inspectModuleCancelWait):
+		i.logger.Warnf("the module [%s] does not stop in %v after it is cancelled, the module is abandoned", m.name, inspectModuleCancelWait)
+	}
+	return nil, err
+}
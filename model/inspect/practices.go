@@ -15,6 +15,8 @@ limitations under the License.
 */
 package inspect
 
+import "fmt"
+
 type InspDevBestPracticesAbnormalOutput struct {
 	*InspDevBestPractices
 	AbnormalDetail string
@@ -23,18 +25,25 @@ type InspDevBestPracticesAbnormalOutput struct {
 
 type InspDevBestPractices struct {
 	CheckSeq          int
+	CheckID           string
 	CheckItem         string
 	CheckCategory     string
 	RectificationType string
 	CheckType         string
 	BestPracticeDesc  string
 	CheckSql          string
+	// the check is only executed on the database version in the range [MinVersion, MaxVersion), the empty means unlimited,
+	// VersionDetail is the abnormal detail of the skipped check, formatted with the database version
+	MinVersion    string
+	MaxVersion    string
+	VersionDetail string
+	Threshold     string
 }
 
 func DefaultDevBestPracticesInspItems() []*InspDevBestPractices {
 	autoInc := NewAutoIncrement(0)
 
-	return []*InspDevBestPractices{
+	items := []*InspDevBestPractices{
 		{
 			CheckSeq:          autoInc.Next(),
 			CheckItem:         "无主键或唯一键",
@@ -476,6 +485,8 @@ WHERE
 			RectificationType: "建议整改",
 			CheckType:         "字段",
 			BestPracticeDesc:  "JSON 在 TiDB v6.5 之前为实验特性，不建议生产环境使用",
+			MaxVersion:        "6.5.0",
+			VersionDetail:     "数据库版本 [%v] 符合 JSON 数据类型启用最低要求",
 			CheckSql: `select concat( table_schema, '.' , table_name, '.' , COLUMN_NAME ) AS SQL_RESULT
 					   from INFORMATION_SCHEMA.COLUMNS
 					   where data_type ='json'
@@ -489,6 +500,8 @@ WHERE
 			RectificationType: "提示",
 			CheckType:         "表",
 			BestPracticeDesc:  "分区表功能与运维特性在 v6.5 之后逐渐 GA 与完善，尽量保证当前版本 >= v6.5",
+			MaxVersion:        "6.5.0",
+			VersionDetail:     "数据库版本 [%v] 符合分区表功能特性启用最低要求",
 			CheckSql: `select concat( table_schema, '.' , table_name) AS SQL_RESULT
 					   from ( select distinct table_schema, table_name
 							  from information_schema.PARTITIONS
@@ -497,4 +510,8 @@ WHERE
 							  and TABLE_SCHEMA not in ('mysql','PERFORMANCE_SCHEMA','INFORMATION_SCHEMA','METRICS_SCHEMA') ) t;`,
 		},
 	}
+	for _, item := range items {
+		item.CheckID = fmt.Sprintf("dev_%02d", item.CheckSeq)
+	}
+	return items
 }
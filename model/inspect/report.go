@@ -270,7 +270,7 @@ func GenClusterDevAndStatsAbnormalOutputExcel(fileName string, devPractices []*I
 	return true, f.Close()
 }
 
-func StartClusterInspect(ctx context.Context, clusterName string, l *printer.Logger, s, p *operator.SSHConnectionProps, gOpt *operator.Options) (*Report, error) {
+func StartClusterInspect(ctx context.Context, clusterName string, nearly int, start, end string, modules, skipModules []string, l *printer.Logger, s, p *operator.SSHConnectionProps, gOpt *operator.Options) (*Report, error) {
 	var (
 		inspCfg *InspectConfig
 	)
@@ -292,6 +292,10 @@ func StartClusterInspect(ctx context.Context, clusterName string, l *printer.Log
 	if inspCfg == nil {
 		return nil, fmt.Errorf("cluster [%v] inspection configuration not found, please run [cluster inspect create -c {clusterName}] to create it first", clusterName)
 	}
+	if err := inspCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid inspect config: %v", err)
+	}
+	inspCfg.SelectModules(modules, skipModules)
 
 	clusterCfg, err := sqlite.GetCluster(ctx, clusterName)
 	if err != nil {
@@ -305,198 +309,49 @@ func StartClusterInspect(ctx context.Context, clusterName string, l *printer.Log
 		return nil, err
 	}
 
-	insp.GenInspectionWindow()
+	if err := insp.GenInspectionWindow(nearly, start, end); err != nil {
+		return nil, err
+	}
+	startTime, endTime := insp.GetInspectionWindow()
+	l.Infof("+ Inspection window %s - %s", startTime.Format("2006-01-02 15:04:05"), endTime.Format("2006-01-02 15:04:05"))
 
 	if err := insp.InspClusterDatabaseVersion(); err != nil {
 		return nil, err
 	}
 
 	rep := &ReportDetail{}
-
-	if inspCfg.Modules.CheckHardwareInfo {
-		basicHardware, err := insp.InspBasicHardwares()
-		if err != nil {
-			return nil, err
-		}
-		rep.BasicHardwares = basicHardware
-	}
-
-	if inspCfg.Modules.CheckSoftwareInfo {
-		clusterSoftware, err := insp.InspClusterSoftware()
-		if err != nil {
-			return nil, err
-		}
-		rep.BasicSoftwares = clusterSoftware
-	}
+	reportAbnormal := &ReportAbnormal{}
 
 	rep.ClusterTopologys = insp.InspClusterTopology()
 
-	if inspCfg.Modules.CheckTidbOverview {
-		clusterSummary, err := insp.InspClusterSummary()
-		if err != nil {
-			return nil, err
-		}
-		rep.ClusterSummarys = clusterSummary
-	}
-
-	var (
-		devAbnormalFlag    bool
-		devAbnormalOutputs []*InspDevBestPracticesAbnormalOutput
-	)
-	if inspCfg.Modules.CheckDevBestPractices {
-		var (
-			devBestPractices []*DevBestPractice
-			err              error
-		)
-		devBestPractices, devAbnormalFlag, devAbnormalOutputs, err = insp.InspDevBestPractices()
-		if err != nil {
-			return nil, err
-		}
-		rep.DevBestPractices = devBestPractices
-	}
-
-	if insp.inspConfig.Modules.CheckDbParams {
-		dbVariable, err := insp.InspDatabaseVaribale()
-		if err != nil {
-			return nil, err
-		}
-		dbConfig, err := insp.InspDatabaseConfig()
-		if err != nil {
-			return nil, err
-		}
-		rep.DatabaseVaribales = dbVariable
-		rep.DatabaseConfigs = dbConfig
-	}
-
-	var (
-		statsAbnormalFlag    bool
-		statsAbnormalOutputs []*InspDatabaseStatisticsAbnormalOutput
-	)
-	if insp.inspConfig.Modules.CheckStatsBestPractices {
-		var (
-			dbStatis []*DatabaseStatistics
-			err      error
-		)
-		dbStatis, statsAbnormalFlag, statsAbnormalOutputs, err = insp.InspDatabaseStatistics()
-		if err != nil {
-			return nil, err
-		}
-		rep.DatabaseStatistics = dbStatis
-	}
-
-	if insp.inspConfig.Modules.CheckSysConfig {
-		sysConfig, sysOutput, err := insp.InspSystemConfig()
-		if err != nil {
-			return nil, err
-		}
-		rep.SystemConfigs = sysConfig
-		rep.SystemConfigOutputs = sysOutput
-	}
-
-	if inspCfg.Modules.CheckCrontab {
-		sysCron, err := insp.InspSystemCrontab()
-		if err != nil {
-			return nil, err
-		}
-		rep.SystemCrontabs = sysCron
-	}
-
-	if inspCfg.Modules.CheckDmesgLogs {
-		sysDmesg, err := insp.InspSystemDmesg()
-		if err != nil {
-			return nil, err
-		}
-		rep.SystemDmesgs = sysDmesg
-	}
-	if inspCfg.Modules.CheckDbErrorLogs {
-		dbErrCount, err := insp.InspDatabaseErrorCount()
-		if err != nil {
-			return nil, err
-		}
-		rep.DatabaseErrorCounts = dbErrCount
-	}
-
-	if inspCfg.Modules.CheckUserSpace {
-		schemaSpace, err := insp.InspDatabaseSchemaSpace()
-		if err != nil {
-			return nil, err
-		}
-		tableSpace, err := insp.InspDatabaseTableSpaceTop()
-		if err != nil {
-			return nil, err
-		}
-		rep.DatabaseSchemaSpaces = schemaSpace
-		rep.DatabaseTableSpaceTops = tableSpace
-	}
-
-	if inspCfg.Modules.CheckPdPerformance {
-		perfPd, err := insp.InspPerformanceStatisticsByPD()
-		if err != nil {
-			return nil, err
+	// the modules are executed independently, the failed module is recorded in the report and the inspection continues
+	for _, m := range inspectModules() {
+		if !*m.enabled(inspCfg.Modules) {
+			continue
 		}
-		rep.PerformanceStatisticsByPds = perfPd
-	}
-
-	if inspCfg.Modules.CheckTidbPerformance {
-		perfTidb, err := insp.InspPerformanceStatisticsByTiDB()
+		rep.EnabledModules = append(rep.EnabledModules, m.name)
+		moduleStart := time.Now()
+		apply, err := insp.runModule(m, inspCfg.moduleTimeout(m.name))
 		if err != nil {
-			return nil, err
+			l.Warnf("+ Inspect module [%s] failed, the inspection continues: %v", m.name, err)
+			rep.ModuleErrors = append(rep.ModuleErrors, &InspectModuleError{
+				Module:      m.name,
+				SummaryName: m.summary,
+				Elapsed:     time.Since(moduleStart).Round(time.Second).String(),
+				Error:       err.Error(),
+			})
+			continue
 		}
-		rep.PerformanceStatisticsByTidbs = perfTidb
-	}
-
-	if inspCfg.Modules.CheckTikvPerformance {
-		perfTikv, err := insp.InspPerformanceStatisticsByTiKV()
-		if err != nil {
-			return nil, err
-		}
-		rep.PerformanceStatisticsByTikvs = perfTikv
-	}
-
-	if inspCfg.Modules.CheckSQLOrderByElapsedTime {
-		sqlElapsed, err := insp.InspSqlOrderedByElapsedTime()
-		if err != nil {
-			return nil, err
-		}
-		rep.SqlOrderedByElapsedTimes = sqlElapsed
-	}
-
-	sqlTiDBCpu, sqlTikvCpu, err := insp.InspSqlOrderedByComponentCpuTime(inspCfg.Modules.CheckSQLOrderByTidbCPUTime, inspCfg.Modules.CheckSQLOrderByTikvCPUTime)
-	if err != nil {
-		return nil, err
-	}
-	rep.SqlOrderedByTiDBCpuTimes = sqlTiDBCpu
-	rep.SqlOrderedByTiKVCpuTimes = sqlTikvCpu
-
-	if inspCfg.Modules.CheckSQLOrderByExecutions {
-		sqlExecs, err := insp.InspSqlOrderedByExecutions()
-		if err != nil {
-			return nil, err
-		}
-		rep.SqlOrderedByExecutions = sqlExecs
-	}
-
-	if inspCfg.Modules.CheckSQLOrderByPlans {
-		sqlPlans, err := insp.InspSqlOrderedByPlans()
-		if err != nil {
-			return nil, err
-		}
-		rep.SqlOrderedByPlans = sqlPlans
-	}
-	rep.InspectionWindowHour = divideFloatAndFormat(float64(inspCfg.WindowMinutes), 60)
-
-	reportAbnormal := &ReportAbnormal{}
-	if devAbnormalFlag {
-		reportAbnormal.DevAbnormals = devAbnormalOutputs
-	}
-	if statsAbnormalFlag {
-		reportAbnormal.StatsAbnormals = statsAbnormalOutputs
+		apply(rep, reportAbnormal)
 	}
+	rep.InspectionWindowHour = divideFloatAndFormat(endTime.Sub(startTime).Minutes(), 60)
 
 	return &Report{
 		ReportBody: &ReportBody{
 			ClusterName:    clusterName,
 			InspectionTime: time.Now().Format("2006-01-02 15:04:05"),
+			InspectionWindow: fmt.Sprintf("%s - %s（%v 小时）",
+				startTime.Format("2006-01-02 15:04:05"), endTime.Format("2006-01-02 15:04:05"), rep.InspectionWindowHour),
 		},
 		ReportSummary:  GenReportSummary(rep),
 		ReportDetail:   rep,
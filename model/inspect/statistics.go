@@ -15,6 +15,8 @@ limitations under the License.
 */
 package inspect
 
+import "fmt"
+
 type InspDatabaseStatisticsAbnormalOutput struct {
 	*InspDatabaseStatistics
 	AbnormalDetail string
@@ -24,14 +26,21 @@ type InspDatabaseStatisticsAbnormalOutput struct {
 
 type InspDatabaseStatistics struct {
 	CheckSeq      int
+	CheckID       string
 	CheckItem     string
 	CheckStandard string
 	CheckSql      string
+	// the same as the version range and the threshold of InspDevBestPractices, Severity is only set by the custom check
+	MinVersion    string
+	MaxVersion    string
+	VersionDetail string
+	Threshold     string
+	Severity      string
 }
 
 func DefaultInspDatabaseStatisticsItems() []*InspDatabaseStatistics {
 	autoInc := NewAutoIncrement(0)
-	return []*InspDatabaseStatistics{
+	items := []*InspDatabaseStatistics{
 		{
 			CheckSeq:      autoInc.Next(),
 			CheckItem:     "是否存在统计信息收集失败的表",
@@ -215,10 +224,16 @@ ORDER BY info2.tb_schema, info2.tb_name`,
 			CheckSeq:      autoInc.Next(),
 			CheckItem:     "是否存在被锁定统计信息的表",
 			CheckStandard: "v6.5 的锁定统计信息不建议使用，建议 v8.1 版本以上使用",
+			MaxVersion:    "8.1.0",
+			VersionDetail: "数据库版本 [%v] 符合锁定统计信息功能启用最低要求",
 			CheckSql: `SELECT CONCAT(t.table_schema,'.',t.table_name) AS SQL_RESULT
 FROM mysql.stats_table_locked l 
 INNER JOIN information_schema.tables t 
 ON l.table_id = t.tidb_table_id`,
 		},
 	}
+	for _, item := range items {
+		item.CheckID = fmt.Sprintf("stats_%02d", item.CheckSeq)
+	}
+	return items
 }
@@ -31,9 +31,10 @@ type Report struct {
 }
 
 type ReportBody struct {
-	ClusterName    string
-	ClusterVersion string
-	InspectionTime string
+	ClusterName      string
+	ClusterVersion   string
+	InspectionTime   string
+	InspectionWindow string
 }
 
 func (rs *ReportBody) String() string {
@@ -51,7 +52,10 @@ func (rs *ReportSummary) String() string {
 }
 
 type ReportDetail struct {
-	InspectionWindowHour         float64
+	InspectionWindowHour float64
+	// EnabledModules is the modules enabled by the inspection, the module failed is also recorded in the module errors
+	EnabledModules               []string
+	ModuleErrors                 []*InspectModuleError
 	BasicHardwares               []*BasicHardware
 	BasicSoftwares               []*BasicSoftware
 	ClusterTopologys             []*ClusterTopology
@@ -178,6 +182,12 @@ func GenReportSummary(r *ReportDetail) *ReportSummary {
 			sm.IsPanic = true
 			sm.SummaryResult = fmt.Sprintf("告警（with %d error）", dbErrSummaryPanic)
 		}
+		for _, e := range r.ModuleErrors {
+			if s.SummaryName == e.SummaryName {
+				sm.IsPanic = true
+				sm.SummaryResult = fmt.Sprintf("模块错误（%s 执行失败，详见报告模块错误）", e.Module)
+			}
+		}
 		summaries = append(summaries, sm)
 	}
 
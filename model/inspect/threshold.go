@@ -0,0 +1,140 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package inspect
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the metric threshold names of the inspect config metric_thresholds, the latency is in milliseconds and the ratio is in percent
+const (
+	MetricPdRegionHeartbeatHandleLatency = "pd_region_heartbeat_handle_latency_ms"
+	MetricPdHandleRequestDuration        = "pd_handle_request_duration_ms"
+	MetricPdWalFsyncDuration             = "pd_wal_fsync_duration_ms"
+	MetricTiDBCommitTokenWaitDuration    = "tidb_commit_token_wait_duration_ms"
+	MetricTiKVSchedulerDiscardRatio      = "tikv_scheduler_discard_ratio"
+	MetricTiKVDiskWriteLatency           = "tikv_disk_write_latency_ms"
+	MetricTiKVDiskReadLatency            = "tikv_disk_read_latency_ms"
+	MetricHostDiskWriteLatency           = "host_disk_write_latency_ms"
+	MetricHostDiskReadLatency            = "host_disk_read_latency_ms"
+)
+
+const (
+	MetricVerdictWarn     = "警告"
+	MetricVerdictCritical = "严重"
+)
+
+// MetricThreshold is the warn and critical level of the metric, the metric exceeding the warn level is abnormal
+type MetricThreshold struct {
+	Warn     float64 `yaml:"warn" json:"warn"`
+	Critical float64 `yaml:"critical" json:"critical"`
+}
+
+// DefaultMetricThresholds returns the default metric thresholds, the warn level is the empirical value of the local ssd disk
+func DefaultMetricThresholds() map[string]*MetricThreshold {
+	return map[string]*MetricThreshold{
+		MetricPdRegionHeartbeatHandleLatency: {Warn: 30, Critical: 100},
+		MetricPdHandleRequestDuration:        {Warn: 30, Critical: 100},
+		MetricPdWalFsyncDuration:             {Warn: 15, Critical: 50},
+		MetricTiDBCommitTokenWaitDuration:    {Warn: 15, Critical: 50},
+		MetricTiKVSchedulerDiscardRatio:      {Warn: 0, Critical: 10},
+		MetricTiKVDiskWriteLatency:           {Warn: 15, Critical: 50},
+		MetricTiKVDiskReadLatency:            {Warn: 15, Critical: 50},
+		MetricHostDiskWriteLatency:           {Warn: 10, Critical: 30},
+		MetricHostDiskReadLatency:            {Warn: 10, Critical: 30},
+	}
+}
+
+func validateMetricThresholds(thresholds map[string]*MetricThreshold) error {
+	defaults := DefaultMetricThresholds()
+	for name, t := range thresholds {
+		if _, ok := defaults[name]; !ok {
+			var names []string
+			for n := range defaults {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown metric threshold [%s], options: %s", name, strings.Join(names, " / "))
+		}
+		if t == nil {
+			return fmt.Errorf("the metric threshold [%s] warn and critical cannot be empty", name)
+		}
+		if t.Warn < 0 || t.Critical < 0 {
+			return fmt.Errorf("the metric threshold [%s] warn [%v] and critical [%v] cannot be negative", name, t.Warn, t.Critical)
+		}
+		if t.Critical < t.Warn {
+			return fmt.Errorf("the metric threshold [%s] critical [%v] must be greater than or equal to warn [%v]", name, t.Critical, t.Warn)
+		}
+	}
+	return nil
+}
+
+// metricThreshold returns the threshold of the metric configured by the cluster, the default threshold is used if not configured
+func (i *InspectConfig) metricThreshold(name string) *MetricThreshold {
+	if t, ok := i.MetricThresholds[name]; ok && t != nil {
+		return t
+	}
+	return DefaultMetricThresholds()[name]
+}
+
+// verdict returns the verdict level of the metric value, the empty level means the value does not exceed the warn level
+func (t *MetricThreshold) verdict(v float64) string {
+	switch {
+	case v > t.Critical:
+		return MetricVerdictCritical
+	case v > t.Warn:
+		return MetricVerdictWarn
+	default:
+		return ""
+	}
+}
+
+// level returns the threshold value of the verdict level
+func (t *MetricThreshold) level(verdict string) float64 {
+	if verdict == MetricVerdictCritical {
+		return t.Critical
+	}
+	return t.Warn
+}
+
+// describe returns the threshold used by the verdict shown in the report, e.g. pd_wal_fsync_duration_ms: warn 15ms / critical 50ms
+func (t *MetricThreshold) describe(name string) string {
+	unit := metricThresholdUnit(name)
+	return fmt.Sprintf("%s: warn %s%s / critical %s%s", name, formatThresholdValue(t.Warn), unit, formatThresholdValue(t.Critical), unit)
+}
+
+// comment returns the verdict and the exceeded threshold level shown in the report, e.g. 严重：超过 critical 阈值 50ms
+func (t *MetricThreshold) comment(name, verdict string) string {
+	levelName := "warn"
+	if verdict == MetricVerdictCritical {
+		levelName = "critical"
+	}
+	return fmt.Sprintf("%s：超过 %s 阈值 %s%s", verdict, levelName, formatThresholdValue(t.level(verdict)), metricThresholdUnit(name))
+}
+
+func metricThresholdUnit(name string) string {
+	if strings.HasSuffix(name, "_ms") {
+		return "ms"
+	}
+	return "%"
+}
+
+func formatThresholdValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -116,6 +116,7 @@ WHERE
 						if _, err := db.ExecContext(gCtx, fmt.Sprintf("kill tidb %s", instS[2])); err != nil {
 							return err
 						}
+						db.RecordObject(fmt.Sprintf("session %s:%s id %s", instS[0], instS[1], instS[2]))
 						// -1 operation
 						sessionCounts.Add(^uint64(0))
 						logger.Info(fmt.Sprintf("killed sql digests session on [%s:%s] with id [%s] finished in %fs, session counts [%d]", instS[0], instS[1], instS[2], time.Since(stime).Seconds(), sessionCounts.Load()))
@@ -231,6 +232,7 @@ WHERE
 						if _, err := db.ExecContext(gCtx, fmt.Sprintf("kill tidb %s", instS[2])); err != nil {
 							return err
 						}
+						db.RecordObject(fmt.Sprintf("session %s:%s id %s", instS[0], instS[1], instS[2]))
 						// -1 operation
 						sessionCounts.Add(^uint64(0))
 						logger.Info(fmt.Sprintf("killed username sql digests session on [%s:%s] with id [%s] finished in %fs, session counts [%d]", instS[0], instS[1], instS[2], time.Since(stime).Seconds(), sessionCounts.Load()))
@@ -0,0 +1,456 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/secret"
+	"github.com/wentaojin/tidba/utils/stringutil"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MetadataBundleVersion is the bundle format version written by the current binary
+	MetadataBundleVersion = 1
+
+	BundleFormatJSON = "json"
+	BundleFormatYAML = "yaml"
+
+	BundleSecretRedact  = "redact"
+	BundleSecretEncrypt = "encrypt"
+	BundleSecretPlain   = "plain"
+
+	BundleConflictSkip      = "skip"
+	BundleConflictOverwrite = "overwrite"
+	BundleConflictRename    = "rename"
+)
+
+// MetadataBundle is the portable metadata format used to move clusters between tiup control machines.
+// The bundle is decoupled from the metadata table structure, so the table evolution does not break old bundles.
+type MetadataBundle struct {
+	Version    int              `yaml:"version" json:"version"`
+	ExportedAt string           `yaml:"exported_at" json:"exported_at"`
+	Secret     string           `yaml:"secret" json:"secret"`
+	Clusters   []*BundleCluster `yaml:"clusters" json:"clusters"`
+}
+
+type BundleCluster struct {
+	ClusterName        string               `yaml:"cluster_name" json:"cluster_name"`
+	DbUser             string               `yaml:"db_user" json:"db_user"`
+	DbPassword         string               `yaml:"db_password" json:"db_password"`
+	DbPasswordProvider string               `yaml:"db_password_provider" json:"db_password_provider"`
+	DbHost             string               `yaml:"db_host" json:"db_host"`
+	DbPort             uint64               `yaml:"db_port" json:"db_port"`
+	DbEndpoints        string               `yaml:"db_endpoints,omitempty" json:"db_endpoints,omitempty"`
+	DbCharset          string               `yaml:"db_charset" json:"db_charset"`
+	ConnParams         string               `yaml:"conn_params" json:"conn_params"`
+	TlsCaCert          string               `yaml:"tls_ca_cert,omitempty" json:"tls_ca_cert,omitempty"`
+	TlsClientCert      string               `yaml:"tls_client_cert,omitempty" json:"tls_client_cert,omitempty"`
+	TlsClientKey       string               `yaml:"tls_client_key,omitempty" json:"tls_client_key,omitempty"`
+	TlsServerName      string               `yaml:"tls_server_name,omitempty" json:"tls_server_name,omitempty"`
+	TlsSkipVerify      bool                 `yaml:"tls_skip_verify,omitempty" json:"tls_skip_verify,omitempty"`
+	Path               string               `yaml:"path" json:"path"`
+	PrivateKey         string               `yaml:"private_key" json:"private_key"`
+	Groups             string               `yaml:"groups,omitempty" json:"groups,omitempty"`
+	SqlPolicy          string               `yaml:"sql_policy,omitempty" json:"sql_policy,omitempty"`
+	Comment            string               `yaml:"comment" json:"comment"`
+	Inspect            *BundleInspect       `yaml:"inspect,omitempty" json:"inspect,omitempty"`
+	ResourceGroup      *BundleResourceGroup `yaml:"resource_group,omitempty" json:"resource_group,omitempty"`
+	SqlBindings        []*BundleSqlBinding  `yaml:"sql_bindings,omitempty" json:"sql_bindings,omitempty"`
+}
+
+type BundleInspect struct {
+	InspectConfig string `yaml:"inspect_config" json:"inspect_config"`
+	Comment       string `yaml:"comment" json:"comment"`
+}
+
+type BundleResourceGroup struct {
+	ResourceGroupName string `yaml:"resource_group_name" json:"resource_group_name"`
+	Comment           string `yaml:"comment" json:"comment"`
+}
+
+type BundleSqlBinding struct {
+	SchemaName   string `yaml:"schema_name" json:"schema_name"`
+	SqlDigest    string `yaml:"sql_digest" json:"sql_digest"`
+	DigestText   string `yaml:"digest_text" json:"digest_text"`
+	OptimizeText string `yaml:"optimize_text" json:"optimize_text"`
+	Comment      string `yaml:"comment" json:"comment"`
+}
+
+// Marshal encodes the bundle with the json or yaml format
+func (b *MetadataBundle) Marshal(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case BundleFormatJSON:
+		return json.MarshalIndent(b, "", "  ")
+	case BundleFormatYAML:
+		return yaml.Marshal(b)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format [%s], options: json / yaml", format)
+	}
+}
+
+// UnmarshalMetadataBundle decodes the bundle, yaml is a superset of json so both formats are accepted
+func UnmarshalMetadataBundle(content []byte) (*MetadataBundle, error) {
+	var b *MetadataBundle
+	if err := yaml.Unmarshal(content, &b); err != nil {
+		return nil, fmt.Errorf("invalid metadata bundle: %v", err)
+	}
+	if b == nil || b.Version == 0 {
+		return nil, fmt.Errorf("invalid metadata bundle: the bundle version not found")
+	}
+	if b.Version > MetadataBundleVersion {
+		return nil, fmt.Errorf("the metadata bundle version [%d] is newer than the supported version [%d], please upgrade tidba", b.Version, MetadataBundleVersion)
+	}
+	switch b.Secret {
+	case BundleSecretRedact, BundleSecretEncrypt, BundleSecretPlain:
+	default:
+		return nil, fmt.Errorf("invalid metadata bundle: unsupported secret mode [%s]", b.Secret)
+	}
+	return b, nil
+}
+
+// MetadataExport collects the selected clusters and their inspect, resource group and sql binding records into a bundle.
+// The cluster password is decrypted by the master key and then redacted, encrypted with the bundle key or kept in plaintext.
+func MetadataExport(ctx context.Context, clusterNames []string, secretMode string, bundleKeyFn func() ([]byte, error)) (*MetadataBundle, error) {
+	switch secretMode {
+	case BundleSecretRedact, BundleSecretEncrypt, BundleSecretPlain:
+	default:
+		return nil, fmt.Errorf("unsupported secret mode [%s], options: redact / encrypt / plain", secretMode)
+	}
+
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	meta := db.(*sqlite.Database)
+
+	clusters, err := meta.ListCluster(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusterNames) > 0 {
+		var (
+			filters []*sqlite.Cluster
+			founds  []string
+		)
+		for _, c := range clusters {
+			if stringutil.IsContainString(c.ClusterName, clusterNames) {
+				filters = append(filters, c)
+				founds = append(founds, c.ClusterName)
+			}
+		}
+		if notFounds := stringutil.CompareSliceString(clusterNames, founds); len(notFounds) > 0 {
+			return nil, fmt.Errorf("the cluster_name [%s] not found, please use command [meta list] to check whether the cluster exists", strings.Join(notFounds, ","))
+		}
+		clusters = filters
+	}
+
+	var bundleKey []byte
+	if secretMode == BundleSecretEncrypt {
+		bundleKey, err = bundleKeyFn()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bundle := &MetadataBundle{
+		Version:    MetadataBundleVersion,
+		ExportedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Secret:     secretMode,
+	}
+	for _, c := range clusters {
+		var passwd string
+		if c.DbPassword != "" && secretMode != BundleSecretRedact {
+			plaintext, err := database.DecryptClusterPassword(c)
+			if err != nil {
+				return nil, err
+			}
+			passwd = plaintext
+			if secretMode == BundleSecretEncrypt {
+				passwd, err = secret.Encrypt(bundleKey, plaintext)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		bc := &BundleCluster{
+			ClusterName:        c.ClusterName,
+			DbUser:             c.DbUser,
+			DbPassword:         passwd,
+			DbPasswordProvider: c.DbPasswordProvider,
+			DbHost:             c.DbHost,
+			DbPort:             c.DbPort,
+			DbEndpoints:        c.DbEndpoints,
+			DbCharset:          c.DbCharset,
+			ConnParams:         c.ConnParams,
+			TlsCaCert:          c.TlsCaCert,
+			TlsClientCert:      c.TlsClientCert,
+			TlsClientKey:       c.TlsClientKey,
+			TlsServerName:      c.TlsServerName,
+			TlsSkipVerify:      c.TlsSkipVerify,
+			Path:               c.Path,
+			PrivateKey:         c.PrivateKey,
+			Groups:             c.Groups,
+			SqlPolicy:          c.SqlPolicy,
+		}
+		if c.Entity != nil {
+			bc.Comment = c.Comment
+		}
+
+		insp, err := meta.GetInspect(ctx, c.ClusterName)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(insp, &sqlite.Inspect{}) {
+			bc.Inspect = &BundleInspect{InspectConfig: insp.InspectConfig}
+			if insp.Entity != nil {
+				bc.Inspect.Comment = insp.Comment
+			}
+		}
+
+		rg, err := meta.GetResourceGroup(ctx, c.ClusterName)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(rg, &sqlite.ResourceGroup{}) {
+			bc.ResourceGroup = &BundleResourceGroup{ResourceGroupName: rg.ResourceGroupName}
+			if rg.Entity != nil {
+				bc.ResourceGroup.Comment = rg.Comment
+			}
+		}
+
+		binds, err := meta.FindSqlBinding(ctx, c.ClusterName)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range binds {
+			bs := &BundleSqlBinding{
+				SchemaName:   s.SchemaName,
+				SqlDigest:    s.SqlDigest,
+				DigestText:   s.DigestText,
+				OptimizeText: s.OptimizeText,
+			}
+			if s.Entity != nil {
+				bs.Comment = s.Comment
+			}
+			bc.SqlBindings = append(bc.SqlBindings, bs)
+		}
+		bundle.Clusters = append(bundle.Clusters, bc)
+	}
+	return bundle, nil
+}
+
+type ImportResult struct {
+	ClusterName string
+	ImportName  string
+	Action      string
+	Message     string
+}
+
+// MetadataImport writes the bundle clusters into the metadata database, the existed clusters are handled by the conflict policy:
+//
+//	skip: keep the existed cluster and its records
+//	overwrite: replace the existed cluster and its records, the redacted password keeps the existed password
+//	rename: import the cluster with the rename suffix appended, the tiup cluster directory of the renamed cluster is required
+func MetadataImport(ctx context.Context, bundle *MetadataBundle, clusterNames []string, conflict string, renameSuffix string, bundleKeyFn func() ([]byte, error)) ([]*ImportResult, error) {
+	switch conflict {
+	case BundleConflictSkip, BundleConflictOverwrite, BundleConflictRename:
+	default:
+		return nil, fmt.Errorf("unsupported conflict policy [%s], options: skip / overwrite / rename", conflict)
+	}
+	if conflict == BundleConflictRename && renameSuffix == "" {
+		return nil, fmt.Errorf("the rename suffix cannot be empty when the conflict policy is rename")
+	}
+
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	meta := db.(*sqlite.Database)
+
+	var bundleKey []byte
+	if bundle.Secret == BundleSecretEncrypt {
+		bundleKey, err = bundleKeyFn()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []*ImportResult
+	for _, bc := range bundle.Clusters {
+		if len(clusterNames) > 0 && !stringutil.IsContainString(bc.ClusterName, clusterNames) {
+			continue
+		}
+		r := &ImportResult{ClusterName: bc.ClusterName, ImportName: bc.ClusterName}
+
+		exist, err := meta.GetCluster(ctx, bc.ClusterName)
+		if err != nil {
+			return results, err
+		}
+		isExisted := !reflect.DeepEqual(exist, &sqlite.Cluster{})
+		if isExisted {
+			switch conflict {
+			case BundleConflictSkip:
+				r.Action = "skipped"
+				r.Message = "the cluster_name is existed"
+				results = append(results, r)
+				continue
+			case BundleConflictRename:
+				r.ImportName = bc.ClusterName + renameSuffix
+				renamed, err := meta.GetCluster(ctx, r.ImportName)
+				if err != nil {
+					return results, err
+				}
+				if !reflect.DeepEqual(renamed, &sqlite.Cluster{}) {
+					r.Action = "skipped"
+					r.Message = fmt.Sprintf("the renamed cluster_name [%s] is existed", r.ImportName)
+					results = append(results, r)
+					continue
+				}
+				isExisted = false
+			}
+		}
+
+		// the cluster name is the directory name of the tiup cluster, the topology of the renamed cluster is found only if
+		// the tiup cluster directory of the renamed cluster exists, e.g. the tiup cluster is renamed by [tiup cluster rename]
+		clusterPath, privateKey := bc.Path, bc.PrivateKey
+		if r.ImportName != bc.ClusterName {
+			clusterPath, privateKey = remapClusterPath(bc.Path, bc.PrivateKey, r.ImportName)
+			if info, err := os.Stat(clusterPath); err != nil || !info.IsDir() {
+				r.Action = "skipped"
+				r.Message = fmt.Sprintf("the renamed cluster_name [%s] tiup cluster directory [%s] not found, please run [tiup cluster rename %s %s] in advance", r.ImportName, clusterPath, bc.ClusterName, r.ImportName)
+				results = append(results, r)
+				continue
+			}
+		}
+
+		var passwd string
+		switch {
+		case bc.DbPassword == "" && isExisted:
+			// redacted password, keep the existed password when overwriting
+			passwd = exist.DbPassword
+		case bc.DbPassword == "":
+		case bundle.Secret == BundleSecretEncrypt:
+			plaintext, err := secret.Decrypt(bundleKey, bc.DbPassword)
+			if err != nil {
+				return results, fmt.Errorf("the cluster_name [%s] bundle password %v", bc.ClusterName, err)
+			}
+			if passwd, err = database.EncryptClusterPassword(plaintext); err != nil {
+				return results, err
+			}
+		default:
+			if passwd, err = database.EncryptClusterPassword(bc.DbPassword); err != nil {
+				return results, err
+			}
+		}
+
+		c := &sqlite.Cluster{
+			ClusterName:        r.ImportName,
+			DbUser:             bc.DbUser,
+			DbPassword:         passwd,
+			DbPasswordProvider: bc.DbPasswordProvider,
+			DbHost:             bc.DbHost,
+			DbPort:             bc.DbPort,
+			DbEndpoints:        bc.DbEndpoints,
+			DbCharset:          bc.DbCharset,
+			ConnParams:         bc.ConnParams,
+			TlsCaCert:          bc.TlsCaCert,
+			TlsClientCert:      bc.TlsClientCert,
+			TlsClientKey:       bc.TlsClientKey,
+			TlsServerName:      bc.TlsServerName,
+			TlsSkipVerify:      bc.TlsSkipVerify,
+			Path:               clusterPath,
+			PrivateKey:         privateKey,
+			Groups:             bc.Groups,
+			SqlPolicy:          bc.SqlPolicy,
+			Entity: &sqlite.Entity{
+				Comment: bc.Comment,
+			},
+		}
+		var inspect *sqlite.Inspect
+		if bc.Inspect != nil {
+			inspect = &sqlite.Inspect{
+				ClusterName:   r.ImportName,
+				InspectConfig: bc.Inspect.InspectConfig,
+				Entity: &sqlite.Entity{
+					Comment: bc.Inspect.Comment,
+				},
+			}
+		}
+		var rg *sqlite.ResourceGroup
+		if bc.ResourceGroup != nil {
+			rg = &sqlite.ResourceGroup{
+				ClusterName:       r.ImportName,
+				ResourceGroupName: bc.ResourceGroup.ResourceGroupName,
+				Entity: &sqlite.Entity{
+					Comment: bc.ResourceGroup.Comment,
+				},
+			}
+		}
+		var bindings []*sqlite.SqlBinding
+		for _, s := range bc.SqlBindings {
+			bindings = append(bindings, &sqlite.SqlBinding{
+				ClusterName:  r.ImportName,
+				SchemaName:   s.SchemaName,
+				SqlDigest:    s.SqlDigest,
+				DigestText:   s.DigestText,
+				OptimizeText: s.OptimizeText,
+				Entity: &sqlite.Entity{
+					Comment: s.Comment,
+				},
+			})
+		}
+		// the cluster and its records are written in one transaction, the overwritten cluster does not keep the stale records
+		if err = meta.ImportCluster(ctx, c, inspect, rg, bindings); err != nil {
+			return results, fmt.Errorf("import the cluster_name [%s] failed: %v", r.ImportName, err)
+		}
+
+		switch {
+		case isExisted:
+			r.Action = "overwritten"
+		case r.ImportName != bc.ClusterName:
+			r.Action = "renamed"
+		default:
+			r.Action = "created"
+		}
+		if bc.DbPassword == "" && bc.DbPasswordProvider == "" && passwd == "" {
+			r.Message = fmt.Sprintf("the password is redacted, please run [meta update -c %s] to configure the password", r.ImportName)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// remapClusterPath returns the tiup cluster directory of the renamed cluster, which is the sibling directory named by the
+// renamed cluster, the private key under the origin cluster directory is remapped as well
+func remapClusterPath(path, privateKey, clusterName string) (string, string) {
+	origin := filepath.Clean(path)
+	remapped := filepath.Join(filepath.Dir(origin), clusterName)
+	if rel, err := filepath.Rel(origin, filepath.Clean(privateKey)); err == nil && !strings.HasPrefix(rel, "..") {
+		privateKey = filepath.Join(remapped, rel)
+	}
+	return remapped, privateKey
+}
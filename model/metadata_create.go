@@ -24,6 +24,8 @@ import (
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/database/sqlite"
 	"github.com/wentaojin/tidba/utils/cluster/operator"
+	"github.com/wentaojin/tidba/utils/credential"
+	"github.com/wentaojin/tidba/utils/secret"
 
 	"github.com/go-playground/validator/v10"
 
@@ -37,13 +39,29 @@ type Cluster struct {
 	ClusterName string `json:"clusterName" validate:"required"`
 	DbUser      string `json:"dbUser" validate:"required"`
 	DbPassword  string `json:"dbPassword"`
+	// The password source outside tidba, options: env:{name} / file:{path} / exec:{command}, cannot be configured with dbPassword at the same time
+	DbPasswordProvider string `json:"dbPasswordProvider"`
 	// If the host and port are not filled in, the meta.yaml will be automatically obtained according to the location of the metadata file and the host and port of the tidb-server will be randomly obtained.
 	// If you are using load balancing software or directly connecting to the tidb-server, it is recommended to fill in the corresponding host and port
-	DbHost     string `json:"dbHost"`
-	DbPort     uint64 `json:"dbPort"`
-	DbCharset  string `json:"dbCharset" validate:"required"`
-	ConnParams string `json:"connParams"`
-	Comment    string `json:"comment"`
+	DbHost string `json:"dbHost"`
+	DbPort uint64 `json:"dbPort"`
+	// The ordered failover tidb endpoints {host}:{port} separated by comma, tried in order when the dbHost:dbPort is unavailable,
+	// or tiup which means using all tidb instances of the tiup cluster topology
+	DbEndpoints string `json:"dbEndpoints"`
+	DbCharset   string `json:"dbCharset" validate:"required"`
+	ConnParams  string `json:"connParams"`
+	// The tls client connection, the certificate file paths are located on the tidba host. If they are not filled in
+	// and the tls is enabled in the tiup cluster topology, the tiup cluster certificates are used by default
+	TlsCaCert     string `json:"tlsCaCert"`
+	TlsClientCert string `json:"tlsClientCert"`
+	TlsClientKey  string `json:"tlsClientKey"`
+	TlsServerName string `json:"tlsServerName"`
+	TlsSkipVerify bool   `json:"tlsSkipVerify"`
+	// The cluster group names separated by comma, used to select the clusters by the flag --group
+	Groups string `json:"groups"`
+	// The statement classes read / write / ddl / admin separated by comma allowed in the interactive query mode, read only by default
+	SqlPolicy string `json:"sqlPolicy"`
+	Comment   string `json:"comment"`
 }
 
 func (c *Cluster) String() string {
@@ -198,6 +216,18 @@ func MetadataCreate(ctx context.Context, content string) (string, error) {
 	if err := validate.Struct(data); err != nil {
 		return "", fmt.Errorf("validation failed: %w", err)
 	}
+	if err := validateClusterPasswordProvider(ctx, data.DbPassword, data.DbPasswordProvider); err != nil {
+		return "", err
+	}
+	if err := database.ValidateClusterEndpoints(data.DbEndpoints); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
+	if err := database.ValidateClusterTLS(data.TlsCaCert, data.TlsClientCert, data.TlsClientKey); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
+	if err := database.ValidateClusterSqlPolicy(data.SqlPolicy); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
 
 	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
 	if err != nil {
@@ -239,20 +269,37 @@ func MetadataCreate(ctx context.Context, content string) (string, error) {
 			port = insts[0].Port
 		}
 
-		newData, err := db.(*sqlite.Database).CreateCluster(ctx, &sqlite.Cluster{
-			ClusterName: data.ClusterName,
-			DbUser:      data.DbUser,
-			DbPassword:  data.DbPassword,
-			DbHost:      host,
-			DbPort:      port,
-			DbCharset:   data.DbCharset,
-			ConnParams:  data.ConnParams,
-			Path:        metaC.Path,
-			PrivateKey:  metaC.PrivateKey,
+		passwd, err := database.EncryptClusterPassword(data.DbPassword)
+		if err != nil {
+			return "", err
+		}
+
+		newCluster := &sqlite.Cluster{
+			ClusterName:        data.ClusterName,
+			DbUser:             data.DbUser,
+			DbPassword:         passwd,
+			DbPasswordProvider: data.DbPasswordProvider,
+			DbHost:             host,
+			DbPort:             port,
+			DbEndpoints:        data.DbEndpoints,
+			DbCharset:          data.DbCharset,
+			ConnParams:         data.ConnParams,
+			TlsCaCert:          data.TlsCaCert,
+			TlsClientCert:      data.TlsClientCert,
+			TlsClientKey:       data.TlsClientKey,
+			TlsServerName:      data.TlsServerName,
+			TlsSkipVerify:      data.TlsSkipVerify,
+			Path:               metaC.Path,
+			PrivateKey:         metaC.PrivateKey,
+			Groups:             data.Groups,
+			SqlPolicy:          data.SqlPolicy,
 			Entity: &sqlite.Entity{
 				Comment: data.Comment,
 			},
-		})
+		}
+		DefaultClusterTopologyTLS(newCluster, topo.ClusterMeta)
+
+		newData, err := db.(*sqlite.Database).CreateCluster(ctx, newCluster)
 		if err != nil {
 			return "", err
 		}
@@ -260,3 +307,27 @@ func MetadataCreate(ctx context.Context, content string) (string, error) {
 	}
 	return content, fmt.Errorf("the cluster name [%s] is repeated", c.ClusterName)
 }
+
+// DefaultClusterTopologyTLS uses the tiup cluster certificates when the tls is enabled in the tiup cluster topology and the cluster tls is not configured
+func DefaultClusterTopologyTLS(c *sqlite.Cluster, meta *operator.ClusterMeta) {
+	if c.TlsEnabled() || meta == nil || !meta.TlsEnable {
+		return
+	}
+	c.TlsCaCert = meta.TlsCaCert
+	c.TlsClientCert = meta.TlsClientCert
+	c.TlsClientKey = meta.TlsClientKey
+}
+
+// validateClusterPasswordProvider verifies the password provider can be resolved, the provider and the literal password are mutually exclusive
+func validateClusterPasswordProvider(ctx context.Context, password, provider string) error {
+	if provider == "" {
+		return nil
+	}
+	if password != "" && password != secret.MaskedValue {
+		return fmt.Errorf("validation failed: the dbPassword and dbPasswordProvider cannot be configured at the same time")
+	}
+	if _, err := credential.Resolve(ctx, provider); err != nil {
+		return fmt.Errorf("validation failed: %v", err)
+	}
+	return nil
+}
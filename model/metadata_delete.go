@@ -54,7 +54,10 @@ func NewClusterDeleteModel(clusterName string) clusterDeleteModel {
 }
 
 func (m clusterDeleteModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitDelData(m.ctx, m.clusterName), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m clusterDeleteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -81,10 +84,7 @@ func (m clusterDeleteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	default:
 		m.mode = BubblesModeDeleting
 		m.spinner, cmd = m.spinner.Update(msg)
-		return m, tea.Batch(
-			cmd,
-			submitDelData(m.ctx, m.clusterName), // submit delete data
-		)
+		return m, cmd
 	}
 }
 
@@ -107,7 +107,7 @@ func (m clusterDeleteModel) View() string {
 		for _, c := range m.delMsg {
 			t.AppendRow(table.Row{
 				c.ClusterName,
-				fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.DbPassword, c.DbHost, c.DbPort),
+				fmt.Sprintf("%s[%s]@%s:%d", c.DbUser, c.MaskedPassword(), c.DbHost, c.DbPort),
 				c.Path,
 				c.PrivateKey})
 		}
@@ -0,0 +1,58 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+// MetadataSelectClusters returns the union of the clusters in the group and the specified cluster names, ordered by the metadata.
+// The specified cluster names not found in the metadata are kept at the end, so that they are reported as failed instead of ignored
+func MetadataSelectClusters(ctx context.Context, group string, clusterNames []string) ([]*sqlite.Cluster, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	clusters, err := db.(*sqlite.Database).ListCluster(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		selected []*sqlite.Cluster
+		existed  []string
+	)
+	for _, c := range clusters {
+		existed = append(existed, c.ClusterName)
+		if (group != "" && stringutil.IsContainString(group, c.GroupNames())) || stringutil.IsContainString(c.ClusterName, clusterNames) {
+			selected = append(selected, c)
+		}
+	}
+	for _, name := range clusterNames {
+		if !stringutil.IsContainString(name, existed) {
+			selected = append(selected, &sqlite.Cluster{ClusterName: name})
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("the cluster group [%s] does not contain any cluster, please run [meta update] to configure the cluster groups", group)
+	}
+	return selected, nil
+}
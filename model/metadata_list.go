@@ -58,7 +58,10 @@ func NewClusterListModel(clusterName string, page, pageSize uint64) ClusterListM
 }
 
 func (m ClusterListModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(
+		m.spinner.Tick,
+		submitListData(m.ctx, m.clusterName, m.page, m.pageSize), // submit the data when the model starts, without waiting for the terminal message
+	)
 }
 
 func (m ClusterListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -85,10 +88,7 @@ func (m ClusterListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	default:
 		m.mode = BubblesModeQuering
 		m.spinner, cmd = m.spinner.Update(msg)
-		return m, tea.Batch(
-			cmd,
-			submitListData(m.ctx, m.clusterName, m.page, m.pageSize), // submit list data
-		)
+		return m, cmd
 	}
 }
 
@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/secret"
+)
+
+type RekeyResult struct {
+	ClusterName string
+	Action      string
+}
+
+// MetadataRekey re-encrypts all cluster passwords with the new master key.
+// The legacy plaintext records are encrypted directly, which is the migration path of the old metadata database.
+func MetadataRekey(ctx context.Context, newKeyFn func() ([]byte, error)) ([]*RekeyResult, error) {
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	meta := db.(*sqlite.Database)
+
+	clusters, err := meta.ListCluster(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// the old master key must be resolved before the new key is generated,
+	// otherwise the newly generated key file may be loaded as the old key
+	var oldKey []byte
+	for _, c := range clusters {
+		if secret.IsEncrypted(c.DbPassword) {
+			oldKey, err = secret.DefaultKeyring.MasterKey()
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	newKey, err := newKeyFn()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = meta.RotateClusterPassword(ctx, func(password string) (string, error) {
+		if password == "" {
+			return password, nil
+		}
+		plaintext, err := secret.Decrypt(oldKey, password)
+		if err != nil {
+			return "", err
+		}
+		return secret.Encrypt(newKey, plaintext)
+	}); err != nil {
+		return nil, err
+	}
+
+	var results []*RekeyResult
+	for _, c := range clusters {
+		r := &RekeyResult{ClusterName: c.ClusterName}
+		switch {
+		case c.DbPassword == "":
+			r.Action = "skipped (empty password)"
+		case secret.IsEncrypted(c.DbPassword):
+			r.Action = "rekeyed"
+		default:
+			r.Action = "encrypted (migrated from plaintext)"
+		}
+		results = append(results, r)
+	}
+
+	secret.DefaultKeyring.SetMasterKey(newKey)
+	return results, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/cluster/operator"
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+const (
+	SyncActionCreated   = "created"
+	SyncActionUpdated   = "updated"
+	SyncActionUnchanged = "unchanged"
+	SyncActionMissing   = "missing"
+	SyncActionPruned    = "pruned"
+	SyncActionFailed    = "failed"
+)
+
+type SyncResult struct {
+	ClusterName string
+	Action      string
+	Database    string
+	Message     string
+}
+
+// SyncCredential returns the database user and password of the newly discovered cluster
+type SyncCredential func(clusterName string) (dbUser string, dbPassword string, err error)
+
+// MetadataSync discovers all tiup clusters and fills the cluster metadata, the name, path and private key come from
+// the tiup cluster list and the database address comes from the first tidb-server of the cluster topology.
+// Only the database user and password of the newly discovered cluster are asked, the metadata clusters that disappeared from tiup are reported or pruned.
+func MetadataSync(ctx context.Context, clusterNames []string, prune bool, credentialFn SyncCredential) ([]*SyncResult, error) {
+	if _, err := operator.IsExistedTiUPComponent(); err != nil {
+		return nil, err
+	}
+	deployed, err := operator.GetDeployedClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
+	}
+	meta := db.(*sqlite.Database)
+
+	metaClusters, err := meta.ListCluster(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	existed := make(map[string]*sqlite.Cluster)
+	for _, c := range metaClusters {
+		existed[c.ClusterName] = c
+	}
+
+	var (
+		results   []*SyncResult
+		tiupNames []string
+	)
+	for _, d := range deployed.Clusters {
+		tiupNames = append(tiupNames, d.Name)
+		if len(clusterNames) > 0 && !stringutil.IsContainString(d.Name, clusterNames) {
+			continue
+		}
+
+		if c, ok := existed[d.Name]; ok {
+			r := &SyncResult{ClusterName: d.Name, Database: fmt.Sprintf("%s@%s:%d", c.DbUser, c.DbHost, c.DbPort)}
+			if c.Path == d.Path && c.PrivateKey == d.PrivateKey {
+				r.Action = SyncActionUnchanged
+			} else {
+				if err := meta.UpdateCluster(ctx, d.Name, map[string]interface{}{
+					"path":        d.Path,
+					"private_key": d.PrivateKey,
+				}); err != nil {
+					return results, err
+				}
+				r.Action = SyncActionUpdated
+				r.Message = "the path and private key are refreshed from tiup"
+			}
+			results = append(results, r)
+			continue
+		}
+
+		topo, err := operator.GetDeployedClusterTopology(d.Name)
+		if err != nil {
+			results = append(results, &SyncResult{ClusterName: d.Name, Action: SyncActionFailed, Message: err.Error()})
+			continue
+		}
+		insts, err := topo.GetClusterTopologyComponentInstances(operator.ComponentNameTiDB, operator.ComponentNameUbiSQL)
+		if err != nil {
+			results = append(results, &SyncResult{ClusterName: d.Name, Action: SyncActionFailed, Message: err.Error()})
+			continue
+		}
+
+		dbUser, dbPassword, err := credentialFn(d.Name)
+		if err != nil {
+			return results, err
+		}
+		passwd, err := database.EncryptClusterPassword(dbPassword)
+		if err != nil {
+			return results, err
+		}
+
+		newCluster := &sqlite.Cluster{
+			ClusterName: d.Name,
+			DbUser:      dbUser,
+			DbPassword:  passwd,
+			DbHost:      insts[0].Host,
+			DbPort:      insts[0].Port,
+			DbCharset:   "utf8mb4",
+			Path:        d.Path,
+			PrivateKey:  d.PrivateKey,
+			Entity: &sqlite.Entity{
+				Comment: "synchronized from tiup",
+			},
+		}
+		DefaultClusterTopologyTLS(newCluster, topo.ClusterMeta)
+
+		if _, err := meta.CreateCluster(ctx, newCluster); err != nil {
+			return results, err
+		}
+		results = append(results, &SyncResult{
+			ClusterName: d.Name,
+			Action:      SyncActionCreated,
+			Database:    fmt.Sprintf("%s@%s:%d", dbUser, insts[0].Host, insts[0].Port),
+		})
+	}
+
+	for _, c := range metaClusters {
+		if stringutil.IsContainString(c.ClusterName, tiupNames) {
+			continue
+		}
+		if len(clusterNames) > 0 && !stringutil.IsContainString(c.ClusterName, clusterNames) {
+			continue
+		}
+		r := &SyncResult{
+			ClusterName: c.ClusterName,
+			Action:      SyncActionMissing,
+			Database:    fmt.Sprintf("%s@%s:%d", c.DbUser, c.DbHost, c.DbPort),
+			Message:     "the cluster disappeared from tiup, please run [meta delete] or [meta sync --prune] to remove it",
+		}
+		if prune {
+			if _, err := meta.DeleteCluster(ctx, c.ClusterName); err != nil {
+				return results, err
+			}
+			r.Action = SyncActionPruned
+			r.Message = "the cluster disappeared from tiup"
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
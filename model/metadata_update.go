@@ -24,6 +24,7 @@ import (
 
 	"github.com/wentaojin/tidba/database"
 	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/utils/secret"
 
 	"github.com/go-playground/validator/v10"
 
@@ -37,29 +38,61 @@ type NewCluster struct {
 	ClusterName string `json:"clusterName" validate:"required"`
 	DbUser      string `json:"dbUser" validate:"required"`
 	DbPassword  string `json:"dbPassword"`
+	// The password source outside tidba, options: env:{name} / file:{path} / exec:{command}, cannot be configured with dbPassword at the same time
+	DbPasswordProvider string `json:"dbPasswordProvider"`
 	// If the host and port are not filled in, the meta.yaml will be automatically obtained according to the location of the metadata file and the host and port of the tidb-server will be randomly obtained.
 	// If you are using load balancing software or directly connecting to the tidb-server, it is recommended to fill in the corresponding host and port
-	DbHost     string `json:"dbHost"`
-	DbPort     uint64 `json:"dbPort"`
-	DbCharset  string `json:"dbCharset" validate:"required"`
-	ConnParams string `json:"connParams"`
-	Path       string `json:"path" validate:"required"`
-	PrivateKey string `json:"privateKey" validate:"required"`
-	Comment    string `json:"comment"`
+	DbHost string `json:"dbHost"`
+	DbPort uint64 `json:"dbPort"`
+	// The ordered failover tidb endpoints {host}:{port} separated by comma, tried in order when the dbHost:dbPort is unavailable,
+	// or tiup which means using all tidb instances of the tiup cluster topology
+	DbEndpoints string `json:"dbEndpoints"`
+	DbCharset   string `json:"dbCharset" validate:"required"`
+	ConnParams  string `json:"connParams"`
+	// The tls client connection, the certificate file paths are located on the tidba host. If they are not filled in
+	// and the tls is enabled in the tiup cluster topology, the tiup cluster certificates are used by default
+	TlsCaCert     string `json:"tlsCaCert"`
+	TlsClientCert string `json:"tlsClientCert"`
+	TlsClientKey  string `json:"tlsClientKey"`
+	TlsServerName string `json:"tlsServerName"`
+	TlsSkipVerify bool   `json:"tlsSkipVerify"`
+	Path          string `json:"path" validate:"required"`
+	PrivateKey    string `json:"privateKey" validate:"required"`
+	// The cluster group names separated by comma, used to select the clusters by the flag --group
+	Groups string `json:"groups"`
+	// The statement classes read / write / ddl / admin separated by comma allowed in the interactive query mode, read only by default
+	SqlPolicy string `json:"sqlPolicy"`
+	Comment   string `json:"comment"`
 }
 
 type ModifyCluster struct {
 	DbUser     string `json:"dbUser" validate:"required"`
 	DbPassword string `json:"dbPassword"`
+	// The password source outside tidba, options: env:{name} / file:{path} / exec:{command}, cannot be configured with dbPassword at the same time
+	DbPasswordProvider string `json:"dbPasswordProvider"`
 	// If the host and port are not filled in, the meta.yaml will be automatically obtained according to the location of the metadata file and the host and port of the tidb-server will be randomly obtained.
 	// If you are using load balancing software or directly connecting to the tidb-server, it is recommended to fill in the corresponding host and port
-	DbHost     string `json:"dbHost"`
-	DbPort     uint64 `json:"dbPort"`
-	DbCharset  string `json:"dbCharset" validate:"required"`
-	ConnParams string `json:"connParams"`
-	Path       string `json:"path" validate:"required"`
-	PrivateKey string `json:"privateKey" validate:"required"`
-	Comment    string `json:"comment"`
+	DbHost string `json:"dbHost"`
+	DbPort uint64 `json:"dbPort"`
+	// The ordered failover tidb endpoints {host}:{port} separated by comma, tried in order when the dbHost:dbPort is unavailable,
+	// or tiup which means using all tidb instances of the tiup cluster topology
+	DbEndpoints string `json:"dbEndpoints"`
+	DbCharset   string `json:"dbCharset" validate:"required"`
+	ConnParams  string `json:"connParams"`
+	// The tls client connection, the certificate file paths are located on the tidba host. If they are not filled in
+	// and the tls is enabled in the tiup cluster topology, the tiup cluster certificates are used by default
+	TlsCaCert     string `json:"tlsCaCert"`
+	TlsClientCert string `json:"tlsClientCert"`
+	TlsClientKey  string `json:"tlsClientKey"`
+	TlsServerName string `json:"tlsServerName"`
+	TlsSkipVerify bool   `json:"tlsSkipVerify"`
+	Path          string `json:"path" validate:"required"`
+	PrivateKey    string `json:"privateKey" validate:"required"`
+	// The cluster group names separated by comma, used to select the clusters by the flag --group
+	Groups string `json:"groups"`
+	// The statement classes read / write / ddl / admin separated by comma allowed in the interactive query mode, read only by default
+	SqlPolicy string `json:"sqlPolicy"`
+	Comment   string `json:"comment"`
 }
 
 func (c *ModifyCluster) String() string {
@@ -165,15 +198,24 @@ func (m clusterUpdateModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.mode = BubblesModeEditing
 			c := &ModifyCluster{
-				DbUser:     msg.datas[0].DbUser,
-				DbPassword: msg.datas[0].DbPassword,
-				DbHost:     msg.datas[0].DbHost,
-				DbPort:     msg.datas[0].DbPort,
-				DbCharset:  msg.datas[0].DbCharset,
-				ConnParams: msg.datas[0].ConnParams,
-				Path:       msg.datas[0].Path,
-				PrivateKey: msg.datas[0].PrivateKey,
-				Comment:    msg.datas[0].Comment,
+				DbUser:             msg.datas[0].DbUser,
+				DbPassword:         secret.Mask(msg.datas[0].DbPassword),
+				DbPasswordProvider: msg.datas[0].DbPasswordProvider,
+				DbHost:             msg.datas[0].DbHost,
+				DbPort:             msg.datas[0].DbPort,
+				DbEndpoints:        msg.datas[0].DbEndpoints,
+				DbCharset:          msg.datas[0].DbCharset,
+				ConnParams:         msg.datas[0].ConnParams,
+				TlsCaCert:          msg.datas[0].TlsCaCert,
+				TlsClientCert:      msg.datas[0].TlsClientCert,
+				TlsClientKey:       msg.datas[0].TlsClientKey,
+				TlsServerName:      msg.datas[0].TlsServerName,
+				TlsSkipVerify:      msg.datas[0].TlsSkipVerify,
+				Path:               msg.datas[0].Path,
+				PrivateKey:         msg.datas[0].PrivateKey,
+				Groups:             msg.datas[0].Groups,
+				SqlPolicy:          msg.datas[0].SqlPolicy,
+				Comment:            msg.datas[0].Comment,
 			}
 			m.textarea.SetValue(c.String()) // setted origin template
 			return m, nil
@@ -286,7 +328,25 @@ func MetadataUpdate(ctx context.Context, clusterName string, content string) (st
 
 	data.ClusterName = clusterName
 
-	if data.Path == "" || data.PrivateKey == "" {
+	if err := validateClusterPasswordProvider(ctx, data.DbPassword, data.DbPasswordProvider); err != nil {
+		return "", err
+	}
+	if err := database.ValidateClusterEndpoints(data.DbEndpoints); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
+	if err := database.ValidateClusterTLS(data.TlsCaCert, data.TlsClientCert, data.TlsClientKey); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
+	if err := database.ValidateClusterSqlPolicy(data.SqlPolicy); err != nil {
+		return "", fmt.Errorf("validation failed: %v", err)
+	}
+	// the password provider takes precedence, the stored password is no longer needed
+	if data.DbPasswordProvider != "" {
+		data.DbPassword = ""
+	}
+
+	// the masked password means the password is not modified, keep the stored ciphertext
+	if data.Path == "" || data.PrivateKey == "" || data.DbPassword == secret.MaskedValue {
 		var (
 			err error
 		)
@@ -300,11 +360,15 @@ func MetadataUpdate(ctx context.Context, clusterName string, content string) (st
 		if err != nil {
 			return "", err
 		}
-		if !reflect.DeepEqual(c, &sqlite.Cluster{}) {
+		if reflect.DeepEqual(c, &sqlite.Cluster{}) {
+			return "", fmt.Errorf("the cluster_name [%s] not found, please use command [meta list] to check whether the cluster [%s] exists. if not, you can create it by using command [meta create] create", clusterName, clusterName)
+		}
+		if data.Path == "" || data.PrivateKey == "" {
 			data.Path = c.Path
 			data.PrivateKey = c.PrivateKey
-		} else {
-			return "", fmt.Errorf("the cluster_name [%s] not found, please use command [meta list] to check whether the cluster [%s] exists. if not, you can create it by using command [meta create] create", clusterName, clusterName)
+		}
+		if data.DbPassword == secret.MaskedValue {
+			data.DbPassword = c.DbPassword
 		}
 	}
 
@@ -317,20 +381,34 @@ func MetadataUpdate(ctx context.Context, clusterName string, content string) (st
 		return "", fmt.Errorf("make sure that the cluster name is consistent with the cluster name directory name of the .tiup metadata directory")
 	}
 
+	passwd, err := database.EncryptClusterPassword(data.DbPassword)
+	if err != nil {
+		return "", err
+	}
+
 	db, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
 	if err != nil {
 		return "", fmt.Errorf("invalid cluster [%s] database connector: %v", database.DefaultSqliteClusterName, err)
 	}
 	newData, err := db.(*sqlite.Database).CreateCluster(ctx, &sqlite.Cluster{
-		ClusterName: data.ClusterName,
-		DbUser:      data.DbUser,
-		DbPassword:  data.DbPassword,
-		DbHost:      data.DbHost,
-		DbPort:      data.DbPort,
-		DbCharset:   data.DbCharset,
-		ConnParams:  data.ConnParams,
-		Path:        data.Path,
-		PrivateKey:  data.PrivateKey,
+		ClusterName:        data.ClusterName,
+		DbUser:             data.DbUser,
+		DbPassword:         passwd,
+		DbPasswordProvider: data.DbPasswordProvider,
+		DbHost:             data.DbHost,
+		DbPort:             data.DbPort,
+		DbEndpoints:        data.DbEndpoints,
+		DbCharset:          data.DbCharset,
+		ConnParams:         data.ConnParams,
+		TlsCaCert:          data.TlsCaCert,
+		TlsClientCert:      data.TlsClientCert,
+		TlsClientKey:       data.TlsClientKey,
+		TlsServerName:      data.TlsServerName,
+		TlsSkipVerify:      data.TlsSkipVerify,
+		Path:               data.Path,
+		PrivateKey:         data.PrivateKey,
+		Groups:             data.Groups,
+		SqlPolicy:          data.SqlPolicy,
 		Entity: &sqlite.Entity{
 			Comment: data.Comment,
 		},
@@ -0,0 +1,463 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/xuri/excelize/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputFormatTable = "table"
+	OutputFormatJSON  = "json"
+	OutputFormatYAML  = "yaml"
+	OutputFormatCSV   = "csv"
+	OutputFormatXLSX  = "xlsx"
+)
+
+// OutputFormats is the supported output formats of the command results
+var OutputFormats = []string{OutputFormatTable, OutputFormatJSON, OutputFormatYAML, OutputFormatCSV, OutputFormatXLSX}
+
+// Output is the formatter layer of the command results. The table format prints each result as the terminal table once it
+// is written, the json / yaml / csv / xlsx formats collect the results and render them as one document by Flush, so that
+// the output can be consumed by the scripts. The output is written to the file instead of the terminal if the file is set
+type Output struct {
+	format      string
+	file        string
+	command     string
+	clusterName string
+	buffered    bool
+	watch       bool
+	buf         bytes.Buffer
+	results     []*outputResult
+}
+
+// outputResult is one result of the command, the tabular result has the columns and the rows, and the structured
+// result, e.g. the sql plan detail, is the value
+type outputResult struct {
+	name    string
+	columns []string
+	rows    [][]interface{}
+	value   interface{}
+}
+
+// outputRow keeps the column order of the row in the json and yaml format
+type outputRow struct {
+	columns []string
+	values  []interface{}
+}
+
+func NewOutput(format, file, command, clusterName string) (*Output, error) {
+	if format == "" {
+		format = OutputFormatTable
+	}
+	format = strings.ToLower(format)
+	valid := false
+	for _, f := range OutputFormats {
+		if f == format {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("the flag --output-format [%s] is invalid, options: %s", format, strings.Join(OutputFormats, " / "))
+	}
+	if format == OutputFormatXLSX && file == "" {
+		return nil, fmt.Errorf("the flag --output xlsx requires the flag --output-file {file}, the xlsx document cannot be written to the terminal")
+	}
+	return &Output{format: format, file: file, command: command, clusterName: clusterName}, nil
+}
+
+// Fork creates the output of the same format collecting the results of the cluster executed by the fan-out execution, the
+// table format is written to the buffer instead of the terminal, and the results are merged by the caller
+func (o *Output) Fork(clusterName string) *Output {
+	return &Output{format: o.format, command: o.command, clusterName: clusterName, buffered: true}
+}
+
+// ForkWatch creates the json output collecting the results of the cluster executed by the watch sample, the results are
+// converted by WatchResults and compared with the previous sample
+func (o *Output) ForkWatch(clusterName string) *Output {
+	return &Output{format: OutputFormatJSON, command: o.command, clusterName: clusterName, buffered: true, watch: true}
+}
+
+// Buffered returns the table format text written to the forked output
+func (o *Output) Buffered() string {
+	return strings.TrimSpace(o.buf.String())
+}
+
+// Document returns the collected results as the document rendered by the json and yaml format
+func (o *Output) Document() map[string]interface{} {
+	return o.document()
+}
+
+// WatchResults converts the collected results into the watch results, the cells are the values of the json document
+func (o *Output) WatchResults() ([]*WatchResult, error) {
+	b, err := json.Marshal(o.document())
+	if err != nil {
+		return nil, err
+	}
+	return ParseWatchResults(b)
+}
+
+// IsTable returns whether the results are printed as the terminal table
+func (o *Output) IsTable() bool {
+	return o.format == OutputFormatTable
+}
+
+// IsTerminal returns whether the results are printed as the table on the terminal, the comments explaining the results
+// are only printed on the terminal
+func (o *Output) IsTerminal() bool {
+	return o.IsTable() && o.file == "" && !o.buffered
+}
+
+// Table writes the tabular result, the row values exceeding the columns are ignored and the nil value is NULL
+func (o *Output) Table(name string, columns []string, rows [][]interface{}) error {
+	var newRows [][]interface{}
+	for _, row := range rows {
+		if len(row) > len(columns) {
+			row = row[:len(columns)]
+		}
+		newRows = append(newRows, row)
+	}
+	if !o.IsTable() {
+		o.results = append(o.results, &outputResult{name: name, columns: columns, rows: newRows})
+		return nil
+	}
+
+	t := table.NewWriter()
+	var header table.Row
+	for _, c := range columns {
+		header = append(header, c)
+	}
+	t.AppendHeader(header)
+	t.AppendSeparator()
+	for _, row := range newRows {
+		var newRow table.Row
+		for _, v := range row {
+			if v == nil {
+				v = "NULL"
+			}
+			newRow = append(newRow, v)
+		}
+		t.AppendRow(newRow)
+	}
+	_, err := fmt.Fprintf(o.writer(), "%s:\n%s\n\n", name, t.Render())
+	return err
+}
+
+// Rows writes the tabular result queried by the database, the NULLABLE value is NULL
+func (o *Output) Rows(name string, columns []string, results []map[string]string) error {
+	var rows [][]interface{}
+	for _, res := range results {
+		var row []interface{}
+		for _, c := range columns {
+			if v := res[c]; v == "NULLABLE" {
+				row = append(row, nil)
+			} else {
+				row = append(row, v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return o.Table(name, columns, rows)
+}
+
+// Value collects the structured result in the json / yaml / csv / xlsx format, the table format prints the result
+// in the readable text by Printf instead, so the value is ignored
+func (o *Output) Value(name string, v interface{}) {
+	if o.IsTable() {
+		return
+	}
+	o.results = append(o.results, &outputResult{name: name, value: v})
+}
+
+// Printf writes the comment or the readable text of the result in the table format, it is ignored in the other formats
+func (o *Output) Printf(format string, a ...interface{}) {
+	if o.IsTable() {
+		fmt.Fprintf(o.writer(), format, a...)
+	}
+}
+
+// Println writes the comment or the readable text of the result in the table format, it is ignored in the other formats
+func (o *Output) Println(a ...interface{}) {
+	if o.IsTable() {
+		fmt.Fprintln(o.writer(), a...)
+	}
+}
+
+// Notice writes the message that is not the result, e.g. the result not found, it is written to the stderr unless the
+// results are printed on the terminal, so that the document written to the stdout or the file can be consumed by the scripts.
+// The message of the watch sample is discarded, the terminal is refreshed by the sample results
+func (o *Output) Notice(format string, a ...interface{}) {
+	if o.watch {
+		return
+	}
+	if o.IsTerminal() {
+		fmt.Printf(format+"\n", a...)
+		return
+	}
+	if o.buffered && o.IsTable() {
+		fmt.Fprintf(&o.buf, format+"\n", a...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+// Flush renders the collected results in the format, and writes the document to the file or the terminal
+func (o *Output) Flush() error {
+	switch o.format {
+	case OutputFormatTable:
+	case OutputFormatJSON:
+		b, err := json.MarshalIndent(o.document(), "", "  ")
+		if err != nil {
+			return err
+		}
+		o.buf.Write(b)
+		o.buf.WriteString("\n")
+	case OutputFormatYAML:
+		enc := yaml.NewEncoder(&o.buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(o.document()); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	case OutputFormatCSV:
+		if err := o.writeCSV(&o.buf); err != nil {
+			return err
+		}
+	case OutputFormatXLSX:
+		return o.writeXLSX()
+	}
+
+	if o.file == "" {
+		if o.IsTable() {
+			return nil
+		}
+		_, err := os.Stdout.Write(o.buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(o.file, o.buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write the output file [%s] failed: %v", o.file, err)
+	}
+	fmt.Fprintf(os.Stderr, "the command results are written to the file [%s]\n", o.file)
+	return nil
+}
+
+// writer returns the writer of the table format, the table is buffered and written by Flush if the file is set, and the
+// forked output is always buffered
+func (o *Output) writer() io.Writer {
+	if o.file != "" || o.buffered {
+		return &o.buf
+	}
+	return os.Stdout
+}
+
+func (o *Output) document() map[string]interface{} {
+	results := []map[string]interface{}{}
+	for _, r := range o.results {
+		if r.columns == nil {
+			results = append(results, map[string]interface{}{"name": r.name, "value": r.value})
+			continue
+		}
+		rows := []*outputRow{}
+		for _, row := range r.rows {
+			rows = append(rows, &outputRow{columns: r.columns, values: row})
+		}
+		results = append(results, map[string]interface{}{"name": r.name, "columns": r.columns, "rows": rows})
+	}
+	doc := map[string]interface{}{"command": o.command, "results": results}
+	if o.clusterName != "" {
+		doc["cluster_name"] = o.clusterName
+	}
+	return doc
+}
+
+// records returns the header and the records of the result in the csv and xlsx format, the structured value is one
+// record per element of the string slice, otherwise it is one json encoded record
+func (r *outputResult) records() ([]string, [][]string, error) {
+	if r.columns == nil {
+		if vs, ok := r.value.([]string); ok {
+			var records [][]string
+			for _, v := range vs {
+				records = append(records, []string{v})
+			}
+			return []string{"value"}, records, nil
+		}
+		b, err := json.Marshal(r.value)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []string{"value"}, [][]string{{string(b)}}, nil
+	}
+	var records [][]string
+	for _, row := range r.rows {
+		record := make([]string, 0, len(row))
+		for _, v := range row {
+			if v == nil {
+				record = append(record, "NULL")
+			} else {
+				record = append(record, fmt.Sprintf("%v", v))
+			}
+		}
+		records = append(records, record)
+	}
+	return r.columns, records, nil
+}
+
+// writeCSV writes the results separated by the empty line, each result starts with the header
+func (o *Output) writeCSV(w io.Writer) error {
+	for i, r := range o.results {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		header, records, err := r.records()
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeXLSX writes each result to the sheet named by the result name
+func (o *Output) writeXLSX() error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheets := make(map[string]bool)
+	for i, r := range o.results {
+		sheetName := xlsxSheetName(r.name, i, sheets)
+		if _, err := f.NewSheet(sheetName); err != nil {
+			return err
+		}
+		header, records, err := r.records()
+		if err != nil {
+			return err
+		}
+		for j, record := range append([][]string{header}, records...) {
+			cell, err := excelize.CoordinatesToCellName(1, j+1)
+			if err != nil {
+				return err
+			}
+			values := make([]interface{}, 0, len(record))
+			for _, v := range record {
+				values = append(values, v)
+			}
+			if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+				return err
+			}
+		}
+	}
+	// the empty document keeps the default sheet
+	if len(o.results) > 0 {
+		if err := f.DeleteSheet("Sheet1"); err != nil {
+			return err
+		}
+		f.SetActiveSheet(0)
+	}
+	if err := f.SaveAs(o.file); err != nil {
+		return fmt.Errorf("write the output file [%s] failed: %v", o.file, err)
+	}
+	fmt.Fprintf(os.Stderr, "the command results are written to the file [%s]\n", o.file)
+	return nil
+}
+
+// xlsxSheetName returns the unique sheet name, the sheet name is limited to 31 characters without : \ / ? * [ ]
+func xlsxSheetName(name string, index int, sheets map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || strings.EqualFold(name, "Sheet1") {
+		name = fmt.Sprintf("result_%d", index+1)
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	for base, i := name, 2; sheets[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		runes := []rune(base)
+		if len(runes)+len(suffix) > 31 {
+			runes = runes[:31-len(suffix)]
+		}
+		name = string(runes) + suffix
+	}
+	sheets[strings.ToLower(name)] = true
+	return name
+}
+
+func (r *outputRow) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, c := range r.columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if i < len(r.values) {
+			v = r.values[i]
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(val)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+func (r *outputRow) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i, c := range r.columns {
+		var v interface{}
+		if i < len(r.values) {
+			v = r.values[i]
+		}
+		val := &yaml.Node{}
+		if err := val.Encode(v); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: c}, val)
+	}
+	return node, nil
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package region
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -113,12 +114,12 @@ type Response struct {
 	RegionInfo []string
 }
 
-func getClusterRegions(topo *operator.ClusterTopology, pdAddr string) (*Region, error) {
+func getClusterRegions(ctx context.Context, topo *operator.ClusterTopology, pdAddr string) (*Region, error) {
 	var region *Region
 
 	regionAPI := fmt.Sprintf("%s/pd/api/v1/regions", pdAddr)
 
-	resp, err := request.Request(request.DefaultRequestMethodGet, regionAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
+	resp, err := request.Request(ctx, request.DefaultRequestMethodGet, regionAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
 	if err != nil {
 		return region, err
 	}
@@ -129,10 +130,10 @@ func getClusterRegions(topo *operator.ClusterTopology, pdAddr string) (*Region,
 	return region, nil
 }
 
-func getClusterConfigReplica(topo *operator.ClusterTopology, pdAddr string) (*ConfigReplica, error) {
+func getClusterConfigReplica(ctx context.Context, topo *operator.ClusterTopology, pdAddr string) (*ConfigReplica, error) {
 	var cfgReplica *ConfigReplica
 	cfgReplicaAPI := fmt.Sprintf("%s/pd/api/v1/config/replicate", pdAddr)
-	resp, err := request.Request(request.DefaultRequestMethodGet, cfgReplicaAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
+	resp, err := request.Request(ctx, request.DefaultRequestMethodGet, cfgReplicaAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
 	if err != nil {
 		return cfgReplica, err
 	}
@@ -142,11 +143,11 @@ func getClusterConfigReplica(topo *operator.ClusterTopology, pdAddr string) (*Co
 	return cfgReplica, nil
 }
 
-func getClusterSingleRegion(topo *operator.ClusterTopology, pdAddr string, regionID string) (*SingleRegion, error) {
+func getClusterSingleRegion(ctx context.Context, topo *operator.ClusterTopology, pdAddr string, regionID string) (*SingleRegion, error) {
 	var region *SingleRegion
 
 	regionAPI := fmt.Sprintf("%s/pd/api/v1/region/id/%s", pdAddr, regionID)
-	resp, err := request.Request(request.DefaultRequestMethodGet, regionAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
+	resp, err := request.Request(ctx, request.DefaultRequestMethodGet, regionAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
 	if err != nil {
 		return region, err
 	}
@@ -156,11 +157,11 @@ func getClusterSingleRegion(topo *operator.ClusterTopology, pdAddr string, regio
 	return region, nil
 }
 
-func getClusterStores(topo *operator.ClusterTopology, pdAddr string) (*Store, error) {
+func getClusterStores(ctx context.Context, topo *operator.ClusterTopology, pdAddr string) (*Store, error) {
 	var store *Store
 
 	storeAPI := fmt.Sprintf("%s/pd/api/v1/stores", pdAddr)
-	resp, err := request.Request(request.DefaultRequestMethodGet, storeAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
+	resp, err := request.Request(ctx, request.DefaultRequestMethodGet, storeAPI, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
 	if err != nil {
 		return store, err
 	}
@@ -43,7 +43,7 @@ func GenerateHotspotRegionQuerySql(clusterName, database string, storeAddrs []st
 		if err != nil {
 			return nil, err
 		}
-		allStores, err := getClusterStores(topo, fmt.Sprintf("%s:%d", pdInsts[0].Host, pdInsts[0].Port))
+		allStores, err := getClusterStores(context.Background(), topo, fmt.Sprintf("%s:%d", pdInsts[0].Host, pdInsts[0].Port))
 		if err != nil {
 			return nil, err
 		}
@@ -134,7 +134,7 @@ func GenerateLeaderDistributedQuerySql(clusterName, database string, storeAddrs
 		if err != nil {
 			return nil, err
 		}
-		allStores, err := getClusterStores(topo, fmt.Sprintf("%s:%d", pdInsts[0].Host, pdInsts[0].Port))
+		allStores, err := getClusterStores(context.Background(), topo, fmt.Sprintf("%s:%d", pdInsts[0].Host, pdInsts[0].Port))
 		if err != nil {
 			return nil, err
 		}
@@ -258,21 +258,21 @@ func QueryMarjorDownRegionPeers(ctx context.Context, clusterName string, db *mys
 		pdAddr = fmt.Sprintf("%s:%d", pdInsts[0].Host, pdInsts[0].Port)
 	}
 
-	regions, err := getClusterRegions(topo, pdAddr)
+	regions, err := getClusterRegions(ctx, topo, pdAddr)
 	if err != nil {
 		return nil, err
 	}
 	logger.Info(fmt.Sprintf("[Region] query cluster regions info in finished %fs", time.Since(queryTime).Seconds()))
 
 	queryTime = time.Now()
-	cfgReplica, err := getClusterConfigReplica(topo, pdAddr)
+	cfgReplica, err := getClusterConfigReplica(ctx, topo, pdAddr)
 	if err != nil {
 		return nil, err
 	}
 	logger.Info(fmt.Sprintf("[Region] query cluster config replica info in finished %fs", time.Since(queryTime).Seconds()))
 
 	queryTime = time.Now()
-	allStores, err := getClusterStores(topo, pdAddr)
+	allStores, err := getClusterStores(ctx, topo, pdAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -449,7 +449,7 @@ func QueryRegionIDInformation(ctx context.Context, clusterName string, db *mysql
 
 	for _, r := range regionID {
 		var singleRegion *SingleRegion
-		singleRegion, err = getClusterSingleRegion(topo, pdAddr, r)
+		singleRegion, err = getClusterSingleRegion(ctx, topo, pdAddr, r)
 		if err != nil {
 			return regions, err
 		}
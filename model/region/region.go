@@ -80,7 +80,7 @@ func NewRegionQueryModel(clusterName string,
 
 func (m RegionQueryModel) Init() tea.Cmd {
 	logger.Info("Start Work...")
-	return m.spinner.Tick
+	return tea.Batch(m.spinner.Tick, m.submit())
 }
 
 func (m RegionQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -106,31 +106,24 @@ func (m RegionQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		switch m.command {
-		case "HOTSPOT":
-			return m, tea.Batch(
-				cmd,
-				submitHotspotData(m.ctx, m.clusterName, m.database, m.stores, m.tables, m.indexes, m.hottype, m.top), // submit list data
-			)
-		case "LEADER":
-			return m, tea.Batch(
-				cmd,
-				submitLeaderData(m.ctx, m.clusterName, m.database, m.stores, m.tables, m.indexes), // submit list data
-			)
-		case "REPLICA":
-			return m, tea.Batch(
-				cmd,
-				submitDownMajorReplicaData(m.ctx, m.clusterName, m.stores, m.regionType, m.pdAddr, m.concurrency), // submit list data
-			)
-		case "QUERY":
-			return m, tea.Batch(
-				cmd,
-				submitRegionData(m.ctx, m.clusterName, m.regionIds, m.pdAddr, m.concurrency), // submit list data
-			)
-		default:
-			return m, tea.Quit
-		}
+		return m, cmd
+	}
+}
 
+// submit returns the query of the command submitted when the model starts, the query does not wait for the first terminal
+// message so that the model also runs without the terminal, e.g. the fan-out and the watch execution
+func (m RegionQueryModel) submit() tea.Cmd {
+	switch m.command {
+	case "HOTSPOT":
+		return submitHotspotData(m.ctx, m.clusterName, m.database, m.stores, m.tables, m.indexes, m.hottype, m.top)
+	case "LEADER":
+		return submitLeaderData(m.ctx, m.clusterName, m.database, m.stores, m.tables, m.indexes)
+	case "REPLICA":
+		return submitDownMajorReplicaData(m.ctx, m.clusterName, m.stores, m.regionType, m.pdAddr, m.concurrency)
+	case "QUERY":
+		return submitRegionData(m.ctx, m.clusterName, m.regionIds, m.pdAddr, m.concurrency)
+	default:
+		return tea.Quit
 	}
 }
 
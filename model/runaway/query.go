@@ -136,6 +136,7 @@ func TopsqlRunaway(ctx context.Context, clusterName string, resourceGroup, sqlDi
 			if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE RESOURCE GROUP IF NOT EXISTS %s RU_PER_SEC = %d PRIORITY=%s", rcName, ruPerSec, priority)); err != nil {
 				return nil, nil, err
 			}
+			db.RecordObject(fmt.Sprintf("resource group %s", rcName))
 		}
 
 		if resourceGroup != "" {
@@ -190,5 +191,10 @@ rw.RESOURCE_GROUP_NAME = rg.NAME`)
 	if err != nil {
 		return nil, nil, err
 	}
+	for _, r := range res {
+		if (sqlDigest != "" && r["WATCH_TEXT"] == sqlDigest) || (sqlText != "" && r["WATCH_TEXT"] == sqlText) {
+			db.RecordObject(fmt.Sprintf("watch %s", r["ID"]))
+		}
+	}
 	return cols, res, nil
 }
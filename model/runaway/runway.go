@@ -77,7 +77,7 @@ func NewSqlRunawayModel(clusterName string,
 }
 
 func (m SqlRunawayModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(m.spinner.Tick, m.submit())
 }
 
 func (m SqlRunawayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -106,25 +106,22 @@ func (m SqlRunawayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		switch m.command {
-		case "CREATE":
-			return m, tea.Batch(
-				cmd,
-				submitRunawayCreateData(m.ctx, m.clusterName, m.resourceGroup, m.sqlDigest, m.priority, m.sqlText, m.action, m.ruPerSec), // submit list data
-			)
-		case "QUERY":
-			return m, tea.Batch(
-				cmd,
-				submitRunawayQueryData(m.ctx, m.clusterName), // submit list data
-			)
-		case "DELETE":
-			return m, tea.Batch(
-				cmd,
-				submitRunawayDeleteData(m.ctx, m.clusterName, m.watchIDs), // submit list data
-			)
-		default:
-			return m, tea.Quit
-		}
+		return m, cmd
+	}
+}
+
+// submit returns the query of the command submitted when the model starts, the query does not wait for the first terminal
+// message so that the model also runs without the terminal, e.g. the fan-out and the watch execution
+func (m SqlRunawayModel) submit() tea.Cmd {
+	switch m.command {
+	case "CREATE":
+		return submitRunawayCreateData(m.ctx, m.clusterName, m.resourceGroup, m.sqlDigest, m.priority, m.sqlText, m.action, m.ruPerSec)
+	case "QUERY":
+		return submitRunawayQueryData(m.ctx, m.clusterName)
+	case "DELETE":
+		return submitRunawayDeleteData(m.ctx, m.clusterName, m.watchIDs)
+	default:
+		return tea.Quit
 	}
 }
 
@@ -288,11 +285,13 @@ func submitRunawayDeleteData(ctx context.Context, clusterName string, ids []int)
 			if _, err := db.ExecContext(ctx, fmt.Sprintf(`QUERY WATCH REMOVE %d`, id)); err != nil {
 				return listRunawayMsg{err: err}
 			}
+			db.RecordObject(fmt.Sprintf("watch %d", id))
 		}
 		if rc.ResourceGroupName != "" {
 			if _, err := db.ExecContext(ctx, fmt.Sprintf(`DROP RESOURCE GROUP %s`, rc.ResourceGroupName)); err != nil {
 				return listRunawayMsg{err: err}
 			}
+			db.RecordObject(fmt.Sprintf("resource group %s", rc.ResourceGroupName))
 			if _, err := meta.DeleteResourceGroup(ctx, clusterName); err != nil {
 				return listRunawayMsg{err: err}
 			}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package snippet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+const (
+	ParamTypeString = "string"
+	ParamTypeInt    = "int"
+	ParamTypeFloat  = "float"
+	ParamTypeBool   = "bool"
+	ParamTypeIdent  = "ident"
+)
+
+// ParamTypes are the placeholder value types, the string value is quoted as the sql string literal and the ident value
+// is quoted as the identifier, so that the value cannot change the structure of the sql statement
+var ParamTypes = []string{ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeBool, ParamTypeIdent}
+
+var (
+	paramNameRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholderRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Param defines the named placeholder ${name} of the snippet, the param without the default value is required
+type Param struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Default *string `json:"default,omitempty"`
+}
+
+// ParseParam parses the param definition in the format of {name}[:{type}][={default}], the type is string by default
+func ParseParam(spec string) (*Param, error) {
+	p := &Param{Type: ParamTypeString}
+	def := ""
+	if idx := strings.Index(spec, "="); idx >= 0 {
+		def = spec[idx+1:]
+		p.Default = &def
+		spec = spec[:idx]
+	}
+	if idx := strings.Index(spec, ":"); idx >= 0 {
+		p.Type = strings.ToLower(strings.TrimSpace(spec[idx+1:]))
+		spec = spec[:idx]
+	}
+	p.Name = strings.TrimSpace(spec)
+
+	if !paramNameRegexp.MatchString(p.Name) {
+		return nil, fmt.Errorf("the param [%s] name is invalid, the name consists of the letters, digits and underscores and does not start with the digit", p.Name)
+	}
+	if !stringutil.IsContainString(p.Type, ParamTypes) {
+		return nil, fmt.Errorf("the param [%s] type [%s] is invalid, options: %s", p.Name, p.Type, strings.Join(ParamTypes, " / "))
+	}
+	if p.Default != nil {
+		if _, err := p.Render(def); err != nil {
+			return nil, fmt.Errorf("the param [%s] default value is invalid: %v", p.Name, err)
+		}
+	}
+	return p, nil
+}
+
+// String returns the param definition in the format accepted by ParseParam
+func (p *Param) String() string {
+	if p.Default == nil {
+		return fmt.Sprintf("%s:%s", p.Name, p.Type)
+	}
+	return fmt.Sprintf("%s:%s=%s", p.Name, p.Type, *p.Default)
+}
+
+// Render validates the value by the param type and returns the sql text replacing the placeholder
+func (p *Param) Render(value string) (string, error) {
+	switch p.Type {
+	case ParamTypeInt:
+		v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("the value [%s] of the param [%s] is not the integer", value, p.Name)
+		}
+		return strconv.FormatInt(v, 10), nil
+	case ParamTypeFloat:
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", fmt.Errorf("the value [%s] of the param [%s] is not the number", value, p.Name)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case ParamTypeBool:
+		v, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("the value [%s] of the param [%s] is not the boolean, options: true / false / 1 / 0", value, p.Name)
+		}
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case ParamTypeIdent:
+		if value == "" {
+			return "", fmt.Errorf("the value of the param [%s] is not the identifier, the identifier cannot be empty", p.Name)
+		}
+		return "`" + strings.ReplaceAll(value, "`", "``") + "`", nil
+	default:
+		r := strings.NewReplacer(`\`, `\\`, `'`, `''`)
+		return "'" + r.Replace(value) + "'", nil
+	}
+}
+
+// placeholders returns the distinct placeholder names of the content in the order of appearance
+func placeholders(content string) []string {
+	var names []string
+	for _, m := range placeholderRegexp.FindAllStringSubmatch(content, -1) {
+		if !stringutil.IsContainString(m[1], names) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
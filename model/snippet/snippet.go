@@ -0,0 +1,279 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package snippet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wentaojin/tidba/database"
+	"github.com/wentaojin/tidba/database/sqlite"
+	"github.com/wentaojin/tidba/model/inspect"
+)
+
+// CreateSnippet saves the snippet, each placeholder ${name} of the content must be defined by the params and each param must be used
+func CreateSnippet(ctx context.Context, name, description, content string, paramSpecs []string) (*sqlite.Snippet, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("the snippet name cannot be empty, required flag(s) --name {snippetName} not set")
+	}
+	for _, prefix := range builtinSnippetPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return nil, fmt.Errorf("the snippet name prefix [%s] is reserved for the builtin snippets, please use another name", prefix)
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("the snippet [%s] sql content cannot be empty", name)
+	}
+
+	var params []*Param
+	for _, spec := range paramSpecs {
+		p, err := ParseParam(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, exist := range params {
+			if exist.Name == p.Name {
+				return nil, fmt.Errorf("the param [%s] is defined repeatedly", p.Name)
+			}
+		}
+		params = append(params, p)
+	}
+	if err := validateParams(content, params); err != nil {
+		return nil, fmt.Errorf("the snippet [%s] %v", name, err)
+	}
+
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := seedBuiltinSnippets(ctx, metaDB.(*sqlite.Database)); err != nil {
+		return nil, err
+	}
+	exist, err := metaDB.(*sqlite.Database).GetSnippet(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if exist.ID != 0 {
+		return nil, fmt.Errorf("the snippet [%s] already exists, please delete it by [snippet delete --name %s] in advance", name, name)
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return metaDB.(*sqlite.Database).CreateSnippet(ctx, &sqlite.Snippet{
+		SnippetName: name,
+		Description: description,
+		Content:     strings.TrimSpace(content),
+		Params:      string(paramsJSON),
+	})
+}
+
+// ListSnippet returns the builtin snippets and the snippets saved by the user in the order of the name
+func ListSnippet(ctx context.Context) ([]*sqlite.Snippet, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := seedBuiltinSnippets(ctx, metaDB.(*sqlite.Database)); err != nil {
+		return nil, err
+	}
+	return metaDB.(*sqlite.Database).ListSnippet(ctx)
+}
+
+func GetSnippet(ctx context.Context, name string) (*sqlite.Snippet, error) {
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := seedBuiltinSnippets(ctx, metaDB.(*sqlite.Database)); err != nil {
+		return nil, err
+	}
+	s, err := metaDB.(*sqlite.Database).GetSnippet(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if s.ID == 0 {
+		return nil, fmt.Errorf("the snippet [%s] not found, please run [snippet list] to query the snippets", name)
+	}
+	return s, nil
+}
+
+// DeleteSnippet deletes the snippet saved by the user, the builtin snippet is seeded again and cannot be deleted
+func DeleteSnippet(ctx context.Context, name string) error {
+	s, err := GetSnippet(ctx, name)
+	if err != nil {
+		return err
+	}
+	if s.Builtin {
+		return fmt.Errorf("the snippet [%s] is the builtin snippet and cannot be deleted", name)
+	}
+	metaDB, err := database.Connector.GetDatabase(database.DefaultSqliteClusterName)
+	if err != nil {
+		return err
+	}
+	return metaDB.(*sqlite.Database).DeleteSnippet(ctx, name)
+}
+
+// Params returns the placeholder definitions of the snippet
+func Params(s *sqlite.Snippet) ([]*Param, error) {
+	var params []*Param
+	if s.Params == "" {
+		return params, nil
+	}
+	if err := json.Unmarshal([]byte(s.Params), &params); err != nil {
+		return nil, fmt.Errorf("the snippet [%s] params [%s] unmarshal failed: %v", s.SnippetName, s.Params, err)
+	}
+	return params, nil
+}
+
+// ParseArgs parses the snippet arguments in the format of {key}={value}
+func ParseArgs(args []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, arg := range args {
+		idx := strings.Index(arg, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("the snippet argument [%s] is invalid, the format is {key}={value}", arg)
+		}
+		values[arg[:idx]] = arg[idx+1:]
+	}
+	return values, nil
+}
+
+// Render replaces the placeholders of the snippet content by the values, the default value is used for the param without
+// the value, and the value is validated and quoted by the param type
+func Render(s *sqlite.Snippet, values map[string]string) (string, error) {
+	params, err := Params(s)
+	if err != nil {
+		return "", err
+	}
+	defined := make(map[string]*Param)
+	for _, p := range params {
+		defined[p.Name] = p
+	}
+	for key := range values {
+		if _, ok := defined[key]; !ok {
+			return "", fmt.Errorf("the snippet [%s] has no param [%s], params: [%s]", s.SnippetName, key, paramsString(params))
+		}
+	}
+
+	rendered := make(map[string]string)
+	for _, p := range params {
+		v, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return "", fmt.Errorf("the snippet [%s] param [%s] is required, e.g. %s={value}", s.SnippetName, p.Name, p.Name)
+			}
+			v = *p.Default
+		}
+		sqlText, err := p.Render(v)
+		if err != nil {
+			return "", err
+		}
+		rendered[p.Name] = sqlText
+	}
+	return placeholderRegexp.ReplaceAllStringFunc(s.Content, func(m string) string {
+		return rendered[placeholderRegexp.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+func validateParams(content string, params []*Param) error {
+	names := placeholders(content)
+	for _, n := range names {
+		found := false
+		for _, p := range params {
+			if p.Name == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("placeholder [${%s}] is not defined, please define it by the flag --param %s[:{type}][={default}]", n, n)
+		}
+	}
+	for _, p := range params {
+		found := false
+		for _, n := range names {
+			if p.Name == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("param [%s] is not used by any placeholder [${%s}] of the sql content", p.Name, p.Name)
+		}
+	}
+	return nil
+}
+
+func paramsString(params []*Param) string {
+	var ps []string
+	for _, p := range params {
+		ps = append(ps, p.String())
+	}
+	return strings.Join(ps, ", ")
+}
+
+// ParamsString returns the placeholder definitions of the snippet separated by comma
+func ParamsString(s *sqlite.Snippet) string {
+	params, err := Params(s)
+	if err != nil {
+		return s.Params
+	}
+	return paramsString(params)
+}
+
+// builtinSnippetsRevision is the revision of the builtin snippets, increase it when the builtin snippets are changed, e.g. the
+// development best practices or the statistics inspection queries are changed, so that the new tidba seeds them again once
+const builtinSnippetsRevision = 1
+
+// builtinSnippetPrefixes are the name prefixes of the builtin snippets, the snippet created by the user cannot use them
+var builtinSnippetPrefixes = []string{"practice-", "statistics-"}
+
+// seedBuiltinSnippets saves the diagnostic queries of the development best practices and the statistics inspection as the builtin
+// snippets, the builtin snippets are seeded only if the seeded revision is older than the revision of the current tidba
+func seedBuiltinSnippets(ctx context.Context, metaDB *sqlite.Database) error {
+	revision, err := metaDB.GetBuiltinRevision(ctx, sqlite.BuiltinSnippets)
+	if err != nil {
+		return err
+	}
+	if revision >= builtinSnippetsRevision {
+		return nil
+	}
+
+	var snippets []*sqlite.Snippet
+	for _, p := range inspect.DefaultDevBestPracticesInspItems() {
+		snippets = append(snippets, &sqlite.Snippet{
+			SnippetName: fmt.Sprintf("practice-%02d", p.CheckSeq),
+			Description: fmt.Sprintf("[%s] %s: %s", p.CheckCategory, p.CheckItem, p.BestPracticeDesc),
+			Content:     p.CheckSql,
+			Params:      "[]",
+			Builtin:     true,
+		})
+	}
+	for _, s := range inspect.DefaultInspDatabaseStatisticsItems() {
+		snippets = append(snippets, &sqlite.Snippet{
+			SnippetName: fmt.Sprintf("statistics-%02d", s.CheckSeq),
+			Description: fmt.Sprintf("%s: %s", s.CheckItem, s.CheckStandard),
+			Content:     s.CheckSql,
+			Params:      "[]",
+			Builtin:     true,
+		})
+	}
+	return metaDB.SaveBuiltinSnippets(ctx, builtinSnippetsRevision, snippets)
+}
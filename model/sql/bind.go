@@ -69,6 +69,7 @@ func SqlQueryCreateBind(ctx context.Context, clusterName string, nearly int, sta
 				return err
 			}
 		}
+		db.RecordObject(fmt.Sprintf("binding %s.%s", schemaName, r["sql_digest"]))
 		if _, err := meta.CreateSqlBinding(ctx, &sqlite.SqlBinding{
 			ClusterName:  clusterName,
 			SchemaName:   schemaName,
@@ -80,7 +80,7 @@ func NewSqlQueryModel(clusterName string, nearly int,
 }
 
 func (m SqlQueryModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(m.spinner.Tick, m.submit())
 }
 
 func (m SqlQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -109,25 +109,22 @@ func (m SqlQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		switch m.command {
-		case "DISPLAY":
-			return m, tea.Batch(
-				cmd,
-				submitSqlData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.startTime, m.endTime, m.sqlDigest, m.trend), // submit list data
-			)
-		case "QUERY":
-			return m, tea.Batch(
-				cmd,
-				submitBindQueryData(m.ctx, m.clusterName, m.schemaName, m.sqlDigest), // submit list data
-			)
-		case "DELETE":
-			return m, tea.Batch(
-				cmd,
-				submitBindDeleteData(m.ctx, m.clusterName, m.schemaName, m.sqlDigest), // submit list data
-			)
-		default:
-			return m, tea.Quit
-		}
+		return m, cmd
+	}
+}
+
+// submit returns the query of the command submitted when the model starts, the query does not wait for the first terminal
+// message so that the model also runs without the terminal, e.g. the fan-out and the watch execution
+func (m SqlQueryModel) submit() tea.Cmd {
+	switch m.command {
+	case "DISPLAY":
+		return submitSqlData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.startTime, m.endTime, m.sqlDigest, m.trend)
+	case "QUERY":
+		return submitBindQueryData(m.ctx, m.clusterName, m.schemaName, m.sqlDigest)
+	case "DELETE":
+		return submitBindDeleteData(m.ctx, m.clusterName, m.schemaName, m.sqlDigest)
+	default:
+		return tea.Quit
 	}
 }
 
@@ -300,6 +297,7 @@ func submitBindDeleteData(ctx context.Context, clusterName string, schemaName, s
 					return listRespMsg{err: err}
 				}
 			}
+			db.RecordObject(fmt.Sprintf("binding %s.%s", b.SchemaName, b.SqlDigest))
 			if _, err := meta.DeleteSqlBinding(ctx, clusterName, b.SchemaName, b.SqlDigest); err != nil {
 				return listRespMsg{err: err}
 			}
@@ -361,7 +361,7 @@ func GenerateTosqlCpuTimeByComponentServer(ctx context.Context, db *mysql.Databa
 				func() error {
 					url := generateTopsqlRequestAPI(ngAddr, startSecs, endSecs, addr, component, top)
 
-					resp, err := request.Request(request.DefaultRequestMethodGet, url, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
+					resp, err := request.Request(ctx, request.DefaultRequestMethodGet, url, nil, topo.ClusterMeta.TlsCaCert, topo.ClusterMeta.TlsClientCert, topo.ClusterMeta.TlsClientKey)
 					if err != nil {
 						return err
 					}
@@ -89,7 +89,7 @@ func NewTopsqlQueryModel(clusterName string, nearly int,
 }
 
 func (m TopsqlQueryModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(m.spinner.Tick, m.submit())
 }
 
 func (m TopsqlQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -118,40 +118,28 @@ func (m TopsqlQueryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	default:
 		m.spinner, cmd = m.spinner.Update(msg)
-		switch m.command {
-		case "ELAPSED":
-			return m, tea.Batch(
-				cmd,
-				submitElapsedData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top), // submit list data
-			)
-		case "EXECUTIONS":
-			return m, tea.Batch(
-				cmd,
-				submitExecutionsData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top), // submit list data
-			)
-		case "PLANS":
-			return m, tea.Batch(
-				cmd,
-				submitPlansData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top), // submit list data
-			)
-		case "CPU":
-			return m, tea.Batch(
-				cmd,
-				submitCpuData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.component, m.concurrency, m.enableSqlDisplay, m.enableConnDisplay, m.instances), // submit list data
-			)
-		case "DIAGNOSIS":
-			return m, tea.Batch(
-				cmd,
-				submitDiagData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.concurrency, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay), // submit list data
-			)
-		case "MEMORY":
-			return m, tea.Batch(
-				cmd,
-				submitMemoryData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay), // submit list data
-			)
-		default:
-			return m, tea.Quit
-		}
+		return m, cmd
+	}
+}
+
+// submit returns the query of the command submitted when the model starts, the query does not wait for the first terminal
+// message so that the model also runs without the terminal, e.g. the fan-out and the watch execution
+func (m TopsqlQueryModel) submit() tea.Cmd {
+	switch m.command {
+	case "ELAPSED":
+		return submitElapsedData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top)
+	case "EXECUTIONS":
+		return submitExecutionsData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top)
+	case "PLANS":
+		return submitPlansData(m.ctx, m.clusterName, m.nearly, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay, m.startTime, m.endTime, m.top)
+	case "CPU":
+		return submitCpuData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.component, m.concurrency, m.enableSqlDisplay, m.enableConnDisplay, m.instances)
+	case "DIAGNOSIS":
+		return submitDiagData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.concurrency, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay)
+	case "MEMORY":
+		return submitMemoryData(m.ctx, m.clusterName, m.nearly, m.startTime, m.endTime, m.top, m.enableHistory, m.enableSqlDisplay, m.enableConnDisplay)
+	default:
+		return tea.Quit
 	}
 }
 
@@ -0,0 +1,255 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// WatchResult is the tabular result of one watch sample, the cells are compared with the previous sample
+type WatchResult struct {
+	Name    string     `json:"name"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// WatchSample is the record of the sample log written by the watch mode, one json object per line
+type WatchSample struct {
+	Sample  int            `json:"sample"`
+	Time    time.Time      `json:"time"`
+	Elapsed float64        `json:"elapsed"`
+	Error   string         `json:"error,omitempty"`
+	Results []*WatchResult `json:"results"`
+}
+
+// NewWatchResult converts the query result into the watch result, the NULLABLE value is NULL
+func NewWatchResult(name string, columns []string, results []map[string]string) *WatchResult {
+	r := &WatchResult{Name: name, Columns: columns}
+	for _, res := range results {
+		row := make([]string, 0, len(columns))
+		for _, c := range columns {
+			if v := res[c]; v == "NULLABLE" {
+				row = append(row, "NULL")
+			} else {
+				row = append(row, v)
+			}
+		}
+		r.Rows = append(r.Rows, row)
+	}
+	return r
+}
+
+// ParseWatchResults decodes the json document written by the json output format into the watch results, the structured
+// value is one row per element of the array, and the nested document of the fan-out execution is flattened
+func ParseWatchResults(data []byte) ([]*WatchResult, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode the command json output failed: %v", err)
+	}
+	return watchDocumentResults("", doc), nil
+}
+
+func watchDocumentResults(prefix string, doc map[string]interface{}) []*WatchResult {
+	var results []*WatchResult
+	items, _ := doc["results"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("%v", m["name"])
+		if prefix != "" {
+			name = prefix + " / " + name
+		}
+
+		if columns, ok := m["columns"].([]interface{}); ok {
+			r := &WatchResult{Name: name}
+			for _, c := range columns {
+				r.Columns = append(r.Columns, fmt.Sprintf("%v", c))
+			}
+			rows, _ := m["rows"].([]interface{})
+			for _, row := range rows {
+				values, _ := row.(map[string]interface{})
+				record := make([]string, 0, len(r.Columns))
+				for _, c := range r.Columns {
+					record = append(record, watchValue(values[c]))
+				}
+				r.Rows = append(r.Rows, record)
+			}
+			results = append(results, r)
+			continue
+		}
+
+		switch v := m["value"].(type) {
+		case map[string]interface{}:
+			if _, ok := v["results"]; ok {
+				results = append(results, watchDocumentResults(name, v)...)
+				continue
+			}
+			results = append(results, &WatchResult{Name: name, Columns: []string{"value"}, Rows: [][]string{{watchValue(v)}}})
+		case []interface{}:
+			r := &WatchResult{Name: name, Columns: []string{"value"}}
+			for _, e := range v {
+				r.Rows = append(r.Rows, []string{watchValue(e)})
+			}
+			results = append(results, r)
+		default:
+			results = append(results, &WatchResult{Name: name, Columns: []string{"value"}, Rows: [][]string{{watchValue(v)}}})
+		}
+	}
+	return results
+}
+
+func watchValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return fmt.Sprintf("%v", val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(b)
+	}
+}
+
+// WriteWatchResults renders the watch results as the tables, the cells changed since the previous sample and the new rows
+// are highlighted if highlight is set. The rows are matched with the previous sample by the key column, which is the first
+// digest column or else the first column, the rows with the same key are matched in order, and the cells by the column name
+func WriteWatchResults(w io.Writer, results, previous []*WatchResult, highlight bool) error {
+	prev := make(map[string]*WatchResult)
+	for _, r := range previous {
+		prev[r.Name] = r
+	}
+	changed := text.Colors{text.FgHiYellow, text.Bold}
+
+	for _, r := range results {
+		t := table.NewWriter()
+		var header table.Row
+		for _, c := range r.Columns {
+			header = append(header, c)
+		}
+		t.AppendHeader(header)
+		t.AppendSeparator()
+
+		var (
+			p        = prev[r.Name]
+			prevRows map[string][]string
+			prevCols map[string]int
+			keys     []string
+		)
+		if p != nil {
+			prevRows = watchRowsByKey(p)
+			prevCols = make(map[string]int)
+			for j, c := range p.Columns {
+				prevCols[c] = j
+			}
+			keys = watchRowKeys(r)
+		}
+		for i, row := range r.Rows {
+			var (
+				newRow  table.Row
+				prevRow []string
+			)
+			if p != nil {
+				prevRow = prevRows[keys[i]]
+			}
+			for j, v := range row {
+				if highlight && p != nil && !watchCellEqual(prevRow, prevCols, r.Columns[j], v) {
+					newRow = append(newRow, changed.Sprint(v))
+				} else {
+					newRow = append(newRow, v)
+				}
+			}
+			t.AppendRow(newRow)
+		}
+		t.SetCaption("%d rows", len(r.Rows))
+
+		var err error
+		if r.Name == "" {
+			_, err = fmt.Fprintf(w, "%s\n\n", t.Render())
+		} else {
+			_, err = fmt.Fprintf(w, "%s:\n%s\n\n", r.Name, t.Render())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchRowKeyColumn returns the index of the column identifying the row, the digest column identifies the statement
+// in the topsql and sql results, otherwise the first column is the instance, the name or the id
+func watchRowKeyColumn(columns []string) int {
+	for i, c := range columns {
+		if strings.Contains(strings.ToLower(c), "digest") {
+			return i
+		}
+	}
+	return 0
+}
+
+// watchRowKeys returns the key of each row, the rows with the same key column value are numbered in order
+func watchRowKeys(r *WatchResult) []string {
+	col := watchRowKeyColumn(r.Columns)
+	seen := make(map[string]int)
+	keys := make([]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		var v string
+		if col < len(row) {
+			v = row[col]
+		}
+		keys = append(keys, fmt.Sprintf("%s\x00%d", v, seen[v]))
+		seen[v]++
+	}
+	return keys
+}
+
+func watchRowsByKey(r *WatchResult) map[string][]string {
+	rows := make(map[string][]string, len(r.Rows))
+	for i, key := range watchRowKeys(r) {
+		rows[key] = r.Rows[i]
+	}
+	return rows
+}
+
+// watchCellEqual reports whether the cell of the previous row has the same value, the new row and the new column are changed
+func watchCellEqual(prevRow []string, prevCols map[string]int, column, value string) bool {
+	if prevRow == nil {
+		return false
+	}
+	j, ok := prevCols[column]
+	if !ok || j >= len(prevRow) {
+		return false
+	}
+	return prevRow[j] == value
+}
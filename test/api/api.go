@@ -16,6 +16,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -30,7 +31,7 @@ func main() {
 
 	apis := api("10.2.103.77", "9090", `tidb_server_plan_cache_instance_memory_usage{}`)
 
-	maxResp, err := request.Request(request.DefaultRequestMethodGet, apis, nil, "", "", "")
+	maxResp, err := request.Request(context.Background(), request.DefaultRequestMethodGet, apis, nil, "", "", "")
 	if err != nil {
 		panic(err)
 	}
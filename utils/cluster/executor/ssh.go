@@ -89,7 +89,29 @@ func (e *EasySSHExecutor) Execute(ctx context.Context, cmd string, sudo bool, ex
 		execTimeout = append(execTimeout, time.Duration(DefaultExecuteTimeout)*time.Second)
 	}
 
-	stdout, stderr, done, err := e.Config.Run(cmd, execTimeout...)
+	// the easyssh run does not accept the context, the command is abandoned when the context is done, e.g. the inspection
+	// module timeout, the abandoned command is still bounded by the execute timeout and the session is closed by the easyssh
+	type runResult struct {
+		stdout, stderr string
+		done           bool
+		err            error
+	}
+	resultC := make(chan runResult, 1)
+	go func() {
+		stdout, stderr, done, err := e.Config.Run(cmd, execTimeout...)
+		resultC <- runResult{stdout: stdout, stderr: stderr, done: done, err: err}
+	}()
+	var stdout, stderr string
+	var done bool
+	var err error
+	select {
+	case r := <-resultC:
+		stdout, stderr, done, err = r.stdout, r.stderr, r.done, r.err
+	case <-ctx.Done():
+		return nil, nil, ErrSSHExecuteFailed.
+			Wrap(ctx.Err(), "Execute command over SSH cancelled for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
 	if err != nil {
 		sshErr := ErrSSHExecuteFailed.
 			Wrap(err, "Failed to execute command over SSH for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
@@ -54,6 +54,22 @@ type Cluster struct {
 }
 
 func GetDeployedClusterList(clusterName string) (*Cluster, error) {
+	cl, err := GetDeployedClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cl.Clusters {
+		if c.Name == clusterName {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the cluster_name [%v] display not found, please double verify [tiup cluster display %s]", clusterName, clusterName)
+}
+
+// GetDeployedClusters returns all clusters managed by the tiup of the current user
+func GetDeployedClusters() (*ClusterList, error) {
 	cmd := "tiup cluster list --format json"
 	command := exec.Command("/bin/bash", "-c", cmd)
 
@@ -71,14 +87,7 @@ func GetDeployedClusterList(clusterName string) (*Cluster, error) {
 	if err := json.Unmarshal(stdout.Bytes(), &cl); err != nil {
 		return nil, err
 	}
-
-	for _, c := range cl.Clusters {
-		if c.Name == clusterName {
-			return c, nil
-		}
-	}
-
-	return nil, fmt.Errorf("the cluster_name [%v] display not found, please double verify [tiup cluster display %s]", clusterName, clusterName)
+	return cl, nil
 }
 
 type ClusterLabel struct {
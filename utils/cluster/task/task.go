@@ -76,6 +76,10 @@ func isDisplayTask(t Task) bool {
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx context.Context) error {
 	for _, t := range s.inner {
+		// the rest tasks are not started once the context is done
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !isDisplayTask(t) {
 			if !s.hideDetailDisplay {
 				ctx.Value(printer.ContextKeyLogger).(*printer.Logger).
@@ -125,6 +129,17 @@ func (pt *Parallel) Execute(ctx context.Context) error {
 	for _, t := range pt.inner {
 		wg.Add(1)
 		workerPool <- struct{}{}
+		// the rest tasks are not started once the context is done
+		if err := ctx.Err(); err != nil {
+			<-workerPool
+			wg.Done()
+			mu.Lock()
+			if firstError == nil {
+				firstError = err
+			}
+			mu.Unlock()
+			break
+		}
 
 		// the checkpoint part of context can't be shared between goroutines
 		// since it's used to trace the stack, so we must create a new layer
@@ -153,6 +168,9 @@ func (pt *Parallel) Execute(ctx context.Context) error {
 		}(ctx, t)
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if pt.ignoreError {
 		return nil
 	}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package credential
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	ProviderTypeEnv  = "env"
+	ProviderTypeFile = "file"
+	ProviderTypeExec = "exec"
+
+	// DefaultExecTimeout limits the external command which prints the password
+	DefaultExecTimeout = 30 * time.Second
+)
+
+// AllowExec enables the exec provider, the provider command is stored in the metadata database, which may be shared by the
+// tidba of multiple hosts or imported from the metadata bundle, so the command is only executed by the local shell when the
+// user opts in explicitly
+var AllowExec bool
+
+// Provider resolves the database password from a source outside tidba,
+// the password is resolved every time the database connection is created, so rotated passwords take effect immediately
+type Provider interface {
+	// String returns the provider spec, used to name the provider in errors
+	String() string
+	Resolve(ctx context.Context) (string, error)
+}
+
+// Parse parses the provider spec, the format is {type}:{value}, for example:
+//
+//	env:TIDB_PASSWORD
+//	file:~/.secrets/tidb.pass
+//	exec:vault kv get -field=password secret/tidb
+func Parse(spec string) (Provider, error) {
+	typ, val, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || strings.TrimSpace(val) == "" {
+		return nil, fmt.Errorf("invalid password provider [%s], the format is {env|file|exec}:{value}", spec)
+	}
+	val = strings.TrimSpace(val)
+	switch strings.ToLower(typ) {
+	case ProviderTypeEnv:
+		return &EnvProvider{Name: val}, nil
+	case ProviderTypeFile:
+		return &FileProvider{Path: val}, nil
+	case ProviderTypeExec:
+		return &ExecProvider{Command: val, Timeout: DefaultExecTimeout}, nil
+	default:
+		return nil, fmt.Errorf("invalid password provider [%s], unsupported provider type [%s], options: env / file / exec", spec, typ)
+	}
+}
+
+// Resolve parses the provider spec and resolves the password, the error always names the provider
+func Resolve(ctx context.Context, spec string) (string, error) {
+	p, err := Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	passwd, err := p.Resolve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("the password provider [%s] resolve failed: %v", p.String(), err)
+	}
+	return passwd, nil
+}
+
+type EnvProvider struct {
+	Name string
+}
+
+func (p *EnvProvider) String() string {
+	return fmt.Sprintf("%s:%s", ProviderTypeEnv, p.Name)
+}
+
+func (p *EnvProvider) Resolve(ctx context.Context) (string, error) {
+	val, ok := os.LookupEnv(p.Name)
+	if !ok {
+		return "", fmt.Errorf("the environment variable [%s] is not set", p.Name)
+	}
+	if val == "" {
+		return "", fmt.Errorf("the environment variable [%s] is empty", p.Name)
+	}
+	return val, nil
+}
+
+type FileProvider struct {
+	Path string
+}
+
+func (p *FileProvider) String() string {
+	return fmt.Sprintf("%s:%s", ProviderTypeFile, p.Path)
+}
+
+func (p *FileProvider) Resolve(ctx context.Context) (string, error) {
+	path, err := homedir.Expand(p.Path)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	val := strings.TrimRight(string(b), "\r\n")
+	if val == "" {
+		return "", fmt.Errorf("the file [%s] content is empty", path)
+	}
+	return val, nil
+}
+
+type ExecProvider struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (p *ExecProvider) String() string {
+	return fmt.Sprintf("%s:%s", ProviderTypeExec, p.Command)
+}
+
+func (p *ExecProvider) Resolve(ctx context.Context) (string, error) {
+	if !AllowExec {
+		return "", fmt.Errorf("the exec password provider is disabled, the command stored in the metadata database is executed by the local shell, please run tidba with the flag --allow-exec-credentials to enable it")
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, "/bin/bash", "-c", p.Command)
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	if err := command.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("shell command exec timeout after %v", p.Timeout)
+		}
+		return "", fmt.Errorf("shell command exec failed, error: [%v] output: [%v]", err, strings.TrimSpace(stderr.String()))
+	}
+	val := strings.TrimRight(stdout.String(), "\r\n")
+	if val == "" {
+		return "", fmt.Errorf("shell command stdout is empty")
+	}
+	return val, nil
+}
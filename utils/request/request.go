@@ -17,6 +17,7 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -30,13 +31,14 @@ const (
 	DefaultRequestMethodPost = "POST"
 )
 
-func Request(method, url string, body []byte, cacertPath, certPath, keyPath string) ([]byte, error) {
+// Request sends the http request and returns the response body, the request is cancelled when the context is done
+func Request(ctx context.Context, method, url string, body []byte, cacertPath, certPath, keyPath string) ([]byte, error) {
 	client, err := createHTTPClient(cacertPath, certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
 
-	respBody, err := doRequest(client, method, url, body)
+	respBody, err := doRequest(ctx, client, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +106,7 @@ func loadClientCert(certPath, keyPath string) (tls.Certificate, error) {
 }
 
 // doRequest performs the actual HTTP request.
-func doRequest(client *http.Client, method, url string, body []byte) ([]byte, error) {
+func doRequest(ctx context.Context, client *http.Client, method, url string, body []byte) ([]byte, error) {
 	var reqBody io.Reader
 	if body != nil {
 		reqBody = bytes.NewBuffer(body)
@@ -118,7 +120,7 @@ func doRequest(client *http.Client, method, url string, body []byte) ([]byte, er
 		url = fmt.Sprintf("http://%s", url)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("create HTTP request [%s] failed: %v", url, err)
 	}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"github.com/wentaojin/tidba/utils/stringutil"
+)
+
+const (
+	// EnvMasterKey is the environment variable holding the master key passphrase
+	EnvMasterKey = "TIDBA_MASTER_KEY"
+	// DefaultKeyFileName is the key file name looked up in the metadata directory
+	DefaultKeyFileName = "tidba.key"
+)
+
+// Keyring resolves and caches the master key used to encrypt cluster credentials.
+// The lookup order is environment variable, key file and then the passphrase prompt.
+type Keyring struct {
+	mutex   sync.Mutex
+	keyFile string
+	key     []byte
+}
+
+var DefaultKeyring = &Keyring{}
+
+func (k *Keyring) SetKeyFile(keyFile string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.keyFile != keyFile {
+		k.keyFile = keyFile
+		k.key = nil
+	}
+}
+
+// SetMasterKey overrides the resolved master key, used after the key is rotated
+func (k *Keyring) SetMasterKey(key []byte) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.key = key
+}
+
+func (k *Keyring) MasterKey() ([]byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if len(k.key) > 0 {
+		return k.key, nil
+	}
+
+	if val := os.Getenv(EnvMasterKey); val != "" {
+		k.key = []byte(val)
+		return k.key, nil
+	}
+
+	if k.keyFile != "" && stringutil.IsPathExist(k.keyFile) {
+		key, err := ReadKeyFile(k.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.key = key
+		return k.key, nil
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("the master key is not configured, please set the environment variable [%s] or the flag [--master-key-file]", EnvMasterKey)
+	}
+	passphrase := stringutil.PromptForPassword("Please input the tidba master key passphrase: ")
+	if passphrase == "" {
+		return nil, fmt.Errorf("the master key passphrase cannot be empty")
+	}
+	k.key = []byte(passphrase)
+	return k.key, nil
+}
+
+// ReadKeyFile reads the master key from the key file, surrounding whitespace is ignored
+func ReadKeyFile(keyFile string) ([]byte, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read the master key file [%s] failed: %v", keyFile, err)
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return nil, fmt.Errorf("the master key file [%s] content cannot be empty", keyFile)
+	}
+	return []byte(key), nil
+}
+
+// GenerateKeyFile writes a random master key into the key file, only readable by the current user
+func GenerateKeyFile(keyFile string) ([]byte, error) {
+	b := make([]byte, keySize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate the master key failed: %v", err)
+	}
+	key := hex.EncodeToString(b)
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("write the master key file [%s] failed: %v", keyFile, err)
+	}
+	return []byte(key), nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EncryptedPrefix marks a value stored in the metadata database as ciphertext,
+	// values without the prefix are treated as legacy plaintext rows
+	EncryptedPrefix = "enc:v1:"
+	// MaskedValue is the placeholder printed instead of secrets
+	MaskedValue = "******"
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// IsEncrypted returns whether the value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Mask returns the placeholder for non-empty secrets
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return MaskedValue
+}
+
+// Encrypt seals the plaintext with AES-256-GCM, the data key is derived from the master key and a random salt by scrypt
+func Encrypt(masterKey []byte, plaintext string) (string, error) {
+	if len(masterKey) == 0 {
+		return "", fmt.Errorf("the master key cannot be empty")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate encrypt salt failed: %v", err)
+	}
+	gcm, err := newGCM(masterKey, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate encrypt nonce failed: %v", err)
+	}
+
+	var b []byte
+	b = append(b, salt...)
+	b = append(b, nonce...)
+	b = gcm.Seal(b, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Decrypt opens the value sealed by Encrypt, legacy plaintext values are returned as is
+func Decrypt(masterKey []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if len(masterKey) == 0 {
+		return "", fmt.Errorf("the master key cannot be empty")
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value failed: %v", err)
+	}
+	if len(b) < saltSize {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+	gcm, err := newGCM(masterKey, b[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	b = b[saltSize:]
+	if len(b) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value failed, the master key may be incorrect: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(masterKey, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(masterKey, salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("derive encrypt key failed: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}